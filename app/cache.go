@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// OfflineMode browses the cached data without calling Azure DevOps
+var OfflineMode bool
+
+// cacheEntry is the content of a cache file
+type cacheEntry[T any] struct {
+	SavedAt time.Time `json:"savedAt"`
+	Value   T         `json:"value"`
+}
+
+var unsafeCacheNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// GetCacheDir returns the cache directory of the organization and project,
+// under $XDG_CACHE_HOME/lazyaz or the cache directory of the platform
+func GetCacheDir() string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	scope := unsafeCacheNameChars.ReplaceAllString(_organization+"_"+_project, "_")
+	return filepath.Join(base, "lazyaz", scope)
+}
+
+// cacheName makes a file name of the parts, e.g. cacheName("workitems", "me") is "workitems-me"
+func cacheName(kind string, key string) string {
+	return kind + "-" + unsafeCacheNameChars.ReplaceAllString(key, "_")
+}
+
+// cacheNameOf names the cache of a value with many fields, such as a filter, by its hash
+func cacheNameOf(kind string, value any) string {
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%+v", value)
+	return kind + "-" + strconv.FormatUint(hash.Sum64(), 16)
+}
+
+// SaveCache writes the value to the cache file of the name
+func SaveCache(name string, value any) {
+	path := filepath.Join(GetCacheDir(), name+".json")
+	content, err := json.Marshal(cacheEntry[any]{SavedAt: time.Now(), Value: value})
+	if err != nil {
+		log.Printf("Error encoding cache %s: %v", name, err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("Error creating cache directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		log.Printf("Error writing cache %s: %v", name, err)
+	}
+}
+
+// LoadCache reads the value of the cache file of the name and when it was saved.
+// Returns false when there is no cache.
+func LoadCache[T any](name string) (T, time.Time, bool) {
+	var entry cacheEntry[T]
+	content, err := os.ReadFile(filepath.Join(GetCacheDir(), name+".json"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading cache %s: %v", name, err)
+		}
+		return entry.Value, time.Time{}, false
+	}
+	if err := json.Unmarshal(content, &entry); err != nil {
+		log.Printf("Error parsing cache %s: %v", name, err)
+		return entry.Value, time.Time{}, false
+	}
+	return entry.Value, entry.SavedAt, true
+}
+
+// Items cached by ID, such as details, are dropped when older than cacheItemMaxAge,
+// the oldest ones also being dropped beyond maxCacheItems per kind
+const (
+	cacheItemMaxAge = 30 * 24 * time.Hour
+	maxCacheItems   = 1000
+)
+
+// prunedCacheItems records the kinds of items pruned by this process, which is done once on the first save
+var prunedCacheItems sync.Map
+
+// cacheItemName names the cache file of the item of the ID, in the directory of the kind
+func cacheItemName(name string, id int) string {
+	return filepath.Join(name, strconv.Itoa(id))
+}
+
+// SaveCacheItem writes the item of the ID to its own cache file in the directory of the name
+func SaveCacheItem[T any](name string, id int, item T) {
+	SaveCache(cacheItemName(name, id), item)
+	if _, pruned := prunedCacheItems.LoadOrStore(name, true); !pruned {
+		go pruneCacheItems(name)
+	}
+}
+
+// LoadCacheItem reads the item of the ID from the cache directory of the name, ignoring expired items
+func LoadCacheItem[T any](name string, id int) (T, bool) {
+	item, savedAt, ok := LoadCache[T](cacheItemName(name, id))
+	if ok && time.Since(savedAt) > cacheItemMaxAge {
+		var zero T
+		return zero, false
+	}
+	return item, ok
+}
+
+// pruneCacheItems removes the expired items of the cache directory of the name, and the oldest
+// items beyond maxCacheItems
+func pruneCacheItems(name string) {
+	dir := filepath.Join(GetCacheDir(), name)
+	// Items used to be kept together in one file
+	os.Remove(dir + ".json")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading cache %s: %v", name, err)
+		}
+		return
+	}
+	type cachedFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []cachedFile
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if time.Since(info.ModTime()) > cacheItemMaxAge {
+			os.Remove(path)
+			continue
+		}
+		files = append(files, cachedFile{path, info.ModTime()})
+	}
+	if len(files) <= maxCacheItems {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	for _, file := range files[maxCacheItems:] {
+		os.Remove(file.path)
+	}
+}
+
+// fetchCached fetches and caches the value. When fetching fails, the cached value is returned
+// along with when it was saved, the time being zero for fresh values.
+func fetchCached[T any](name string, fetch func() (T, error)) (T, time.Time, error) {
+	value, err := fetch()
+	if err == nil {
+		SaveCache(name, value)
+		return value, time.Time{}, nil
+	}
+	if cached, savedAt, ok := LoadCache[T](name); ok {
+		log.Printf("Using cached %s from %s: %v", name, savedAt, err)
+		return cached, savedAt, nil
+	}
+	return value, time.Time{}, err
+}
+
+// AnnounceCached tells the user that the data shown is from the cache
+func AnnounceCached(what string, savedAt time.Time) {
+	if OfflineMode {
+		Announce(fmt.Sprintf("📦 Offline, %s cached %s", what, humanize.Time(savedAt)), -1)
+		return
+	}
+	Announce(fmt.Sprintf("📦 Showing %s cached %s", what, humanize.Time(savedAt)), -1)
+}
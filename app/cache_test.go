@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCacheItems(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	prunedCacheItems.Store("details", true)
+
+	SaveCacheItem("details", 1, "first")
+	SaveCacheItem("details", 2, "second")
+	SaveCacheItem("details", 1, "first again")
+
+	tests := []struct {
+		id       int
+		expected string
+		found    bool
+	}{
+		{1, "first again", true},
+		{2, "second", true},
+		{3, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.id), func(t *testing.T) {
+			item, ok := LoadCacheItem[string]("details", tt.id)
+			if ok != tt.found || item != tt.expected {
+				t.Errorf("Expected %q, %v, got %q, %v", tt.expected, tt.found, item, ok)
+			}
+		})
+	}
+}
+
+func TestPruneCacheItems(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := filepath.Join(GetCacheDir(), "details")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := dir + ".json"
+	if err := os.WriteFile(legacy, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The newest items are kept, the expired one and the oldest beyond the maximum are removed
+	now := time.Now()
+	ages := map[string]time.Duration{"expired": cacheItemMaxAge + time.Hour}
+	for i := range maxCacheItems + 1 {
+		ages[strconv.Itoa(i)] = time.Duration(i) * time.Minute
+	}
+	for name, age := range ages {
+		path := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruneCacheItems("details")
+
+	tests := []struct {
+		name   string
+		exists bool
+	}{
+		{"0", true},
+		{strconv.Itoa(maxCacheItems - 1), true},
+		{strconv.Itoa(maxCacheItems), false},
+		{"expired", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := os.Stat(filepath.Join(dir, tt.name+".json"))
+			if exists := err == nil; exists != tt.exists {
+				t.Errorf("Expected %s to exist: %v, got %v", tt.name, tt.exists, exists)
+			}
+		})
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("Expected the file of the items saved together to be removed, got %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != maxCacheItems {
+		t.Errorf("Expected %d items, got %d", maxCacheItems, len(entries))
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+)
+
+// Fields of the table and CSV outputs when no fields are given
+var (
+	defaultWorkItemFields    = []string{"Id", "Work Item Type", "State", "Assigned To", "Title"}
+	defaultPullRequestFields = []string{"ID", "Status", "Author", "Repository", "Title"}
+	defaultPipelineRunFields = []string{"id", "definitionName", "buildNumber", "status", "result", "sourceBranch", "requestedFor"}
+	defaultPipelineDefFields = []string{"id", "name", "path", "status"}
+)
+
+const headlessCommandsUsageText = `Usage:
+  lazyaz workitems list [--filter me|was-ever-me|all] [--all]
+  lazyaz prs list [--filter mine|assigned-to-me|all|active|completed|abandoned] [--status <status>] [--top <n>] [--all]
+  lazyaz pipelines list
+  lazyaz pipelines runs [--pipeline <id>] [--preset <name>] [--branch <ref>] [--reason <reason>] [--result <result>]
+                        [--status <status>] [--requested-for <user>] [--mine] [--top <n>] [--all]
+
+Common flags:
+  --output table|json|csv|yaml   Output format, defaults to table
+  --fields <a,b,c>               Fields to output, e.g. --fields id,title,state
+`
+
+// IsHeadlessCommand reports whether the arguments run a command without the terminal UI
+func IsHeadlessCommand(args []string) bool {
+	return len(args) > 0 && slices.Contains([]string{"workitems", "prs", "pipelines"}, args[0])
+}
+
+// outputFlags are the flags shared by the headless commands
+type outputFlags struct {
+	Output string
+	Fields string
+}
+
+func (o *outputFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.Output, "output", "table", "Output format: "+strings.Join(OutputFormats, ", "))
+	flags.StringVar(&o.Fields, "fields", "", "Comma-separated fields to output")
+}
+
+func (o *outputFlags) validate() error {
+	if !slices.Contains(OutputFormats, o.Output) {
+		return fmt.Errorf("unknown output format %q, available formats: %s", o.Output, strings.Join(OutputFormats, ", "))
+	}
+	return nil
+}
+
+func (o *outputFlags) fields() []string {
+	if o.Fields == "" {
+		return nil
+	}
+	return strings.Split(o.Fields, ",")
+}
+
+// newCommandFlags creates the flags of a command along with the connection flags,
+// printing the usage of the headless commands on errors
+func newCommandFlags(name string, opts *Options) *flag.FlagSet {
+	flags := flag.NewFlagSet("lazyaz "+name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), headlessCommandsUsageText)
+		fmt.Fprintln(flags.Output(), "\nFlags:")
+		flags.PrintDefaults()
+	}
+	opts.registerConnectionFlags(flags)
+	return flags
+}
+
+// fetchAllPages follows the continuation tokens until the last page
+func fetchAllPages[T any](fetch func(continuationToken string) (azuredevops.Page[T], error)) ([]T, error) {
+	var items []T
+	token := ""
+	for {
+		page, err := fetch(token)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if !page.HasMore() {
+			return items, nil
+		}
+		token = page.ContinuationToken
+	}
+}
+
+// fetchPages fetches the first page, or all of them
+func fetchPages[T any](all bool, fetch func(continuationToken string) (azuredevops.Page[T], error)) ([]T, error) {
+	if all {
+		return fetchAllPages(fetch)
+	}
+	page, err := fetch("")
+	return page.Items, err
+}
+
+// RunHeadlessCommand runs a command without the terminal UI, writing its output to w
+func RunHeadlessCommand(args []string, w io.Writer, opts *Options) error {
+	if len(args) < 2 {
+		fmt.Fprint(os.Stderr, headlessCommandsUsageText)
+		return fmt.Errorf("missing subcommand")
+	}
+	command := args[0] + " " + args[1]
+	switch command {
+	case "workitems list":
+		return listWorkItems(args[2:], w, opts)
+	case "prs list":
+		return listPullRequests(args[2:], w, opts)
+	case "pipelines list":
+		return listPipelineDefinitions(args[2:], w, opts)
+	case "pipelines runs":
+		return listPipelineRuns(args[2:], w, opts)
+	default:
+		fmt.Fprint(os.Stderr, headlessCommandsUsageText)
+		return fmt.Errorf("unknown command: %s", command)
+	}
+}
+
+func listWorkItems(args []string, w io.Writer, opts *Options) error {
+	flags := newCommandFlags("workitems list", opts)
+	var output outputFlags
+	output.register(flags)
+	filter := flags.String("filter", "me", "Work items filter: me, was-ever-me or all")
+	all := flags.Bool("all", false, "Fetch every page instead of the latest 90 days")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := output.validate(); err != nil {
+		return err
+	}
+	if !slices.Contains(workItemFilters, *filter) {
+		return fmt.Errorf("unknown filter %q, available filters: %s", *filter, strings.Join(workItemFilters, ", "))
+	}
+	if err := connect(opts); err != nil {
+		return err
+	}
+
+	workItems, err := fetchPages(*all, func(continuationToken string) (azuredevops.Page[azuredevops.WorkItem], error) {
+		return client.GetWorkItemsPage(*filter, continuationToken)
+	})
+	if err != nil {
+		return err
+	}
+	return WriteRecords(w, workItems, output.Output, output.fields(), defaultWorkItemFields)
+}
+
+func listPullRequests(args []string, w io.Writer, opts *Options) error {
+	flags := newCommandFlags("prs list", opts)
+	var output outputFlags
+	output.register(flags)
+	filter := flags.String("filter", "active", "Pull requests filter: mine, assigned-to-me, all, active, completed or abandoned")
+	status := flags.String("status", "", "Status of the pull requests: "+strings.Join(azuredevops.PRStatuses, ", "))
+	top := flags.Int("top", azuredevops.DefaultPRsTop, "Number of pull requests per page")
+	all := flags.Bool("all", false, "Fetch every page")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := output.validate(); err != nil {
+		return err
+	}
+	if !slices.Contains(pullRequestFilters, *filter) {
+		return fmt.Errorf("unknown filter %q, available filters: %s", *filter, strings.Join(pullRequestFilters, ", "))
+	}
+	if err := connect(opts); err != nil {
+		return err
+	}
+
+	var userMail string
+	if *filter == "mine" || *filter == "assigned-to-me" {
+		if activeUser == nil {
+			return fmt.Errorf("the %s filter needs the user profile: %v", *filter, userProfileErr)
+		}
+		userMail = activeUser.Mail
+	}
+	prsFilter := pullRequestsFilterFor(*filter, userMail)
+	if *status != "" {
+		prsFilter.Status = *status
+	}
+	prsFilter.Top = *top
+
+	prs, err := fetchPages(*all, func(continuationToken string) (azuredevops.Page[azuredevops.PullRequestDetails], error) {
+		return client.GetPRsPage(prsFilter, continuationToken)
+	})
+	if err != nil {
+		return err
+	}
+	return WriteRecords(w, prs, output.Output, output.fields(), defaultPullRequestFields)
+}
+
+func listPipelineDefinitions(args []string, w io.Writer, opts *Options) error {
+	flags := newCommandFlags("pipelines list", opts)
+	var output outputFlags
+	output.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := output.validate(); err != nil {
+		return err
+	}
+	if err := connect(opts); err != nil {
+		return err
+	}
+
+	definitions, err := client.GetPipelineDefinitions()
+	if err != nil {
+		return err
+	}
+	return WriteRecords(w, definitions, output.Output, output.fields(), defaultPipelineDefFields)
+}
+
+func listPipelineRuns(args []string, w io.Writer, opts *Options) error {
+	flags := newCommandFlags("pipelines runs", opts)
+	var output outputFlags
+	output.register(flags)
+	presetName := flags.String("preset", "", "Filter preset of the configuration")
+	var preset PipelineRunsPreset
+	flags.IntVar(&preset.PipelineID, "pipeline", 0, "ID of the pipeline")
+	flags.StringVar(&preset.Branch, "branch", "", "Source branch, e.g. refs/heads/main")
+	flags.StringVar(&preset.Reason, "reason", "", "Reason: "+strings.Join(azuredevops.PipelineRunReasons(), ", "))
+	flags.StringVar(&preset.Result, "result", "", "Result: "+strings.Join(azuredevops.PipelineRunResults(), ", "))
+	flags.StringVar(&preset.Status, "status", "", "Status: "+strings.Join(azuredevops.PipelineRunStatuses(), ", "))
+	flags.StringVar(&preset.RequestedFor, "requested-for", "", "User the runs were requested for")
+	flags.BoolVar(&preset.MyRuns, "mine", false, "Only my runs")
+	flags.IntVar(&preset.Top, "top", 0, "Number of runs per page, defaults to the configured top")
+	all := flags.Bool("all", false, "Fetch every page")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := output.validate(); err != nil {
+		return err
+	}
+	if err := connect(opts); err != nil {
+		return err
+	}
+
+	// Flags given on the command line override the preset
+	if *presetName != "" {
+		presets := LoadPipelineRunsPresets(AppSettings)
+		base, ok := presets[*presetName]
+		if !ok {
+			return fmt.Errorf("unknown preset %q", *presetName)
+		}
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "pipeline":
+				base.PipelineID = preset.PipelineID
+			case "branch":
+				base.Branch = preset.Branch
+			case "reason":
+				base.Reason = preset.Reason
+			case "result":
+				base.Result = preset.Result
+			case "status":
+				base.Status = preset.Status
+			case "requested-for":
+				base.RequestedFor = preset.RequestedFor
+			case "mine":
+				base.MyRuns = preset.MyRuns
+			case "top":
+				base.Top = preset.Top
+			}
+		})
+		preset = base
+	}
+	filter, err := preset.ToFilter(activeUser)
+	if err != nil {
+		if userProfileErr != nil {
+			return fmt.Errorf("--mine: %v: %v", err, userProfileErr)
+		}
+		return fmt.Errorf("--mine: %v", err)
+	}
+	filter = withConfiguredTop(filter)
+
+	runs, err := fetchPages(*all, func(continuationToken string) (azuredevops.Page[azuredevops.PipelineRun], error) {
+		return client.GetPipelineRunsPage(filter, continuationToken)
+	})
+	if err != nil {
+		return err
+	}
+	return WriteRecords(w, runs, output.Output, output.fields(), defaultPipelineRunFields)
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+)
+
+const completionUsageText = `Usage:
+  lazyaz completion bash|zsh|fish|powershell
+
+Prints the completion script of the shell. To load the completions:
+  bash        source <(lazyaz completion bash), e.g. in ~/.bashrc
+  zsh         lazyaz completion zsh > "${fpath[1]}/_lazyaz", or source <(lazyaz completion zsh) in ~/.zshrc
+  fish        lazyaz completion fish > ~/.config/fish/completions/lazyaz.fish
+  powershell  lazyaz completion powershell | Out-String | Invoke-Expression, e.g. in $PROFILE
+
+Work item, pull request and pipeline run IDs and pipelines are completed from the cache of the
+application, or fetched once when there is none.
+`
+
+// How long the completion waits for Azure DevOps when nothing is cached
+const completionTimeout = 5 * time.Second
+
+// Subcommands of the commands, "" being lazyaz itself, along with their descriptions
+var completionSubcommands = map[string][][2]string{
+	"": {
+		{"open", "Start the application on the details of an item"},
+		{"show", "Print the details of an item"},
+		{"export", "Render an item with a template"},
+		{"workitems", "List work items"},
+		{"prs", "List pull requests"},
+		{"pipelines", "List pipelines or pipeline runs"},
+		{"doctor", "Check the installation and the configuration"},
+		{"version", "Print the version"},
+		{"completion", "Print the completion script of a shell"},
+	},
+	"workitems": {{"list", "List work items"}},
+	"prs":       {{"list", "List pull requests"}},
+	"pipelines": {{"list", "List pipelines"}, {"runs", "List pipeline runs"}},
+}
+
+// Flags of the commands besides the connection flags, see the headless commands
+var completionCommandFlags = map[string][]string{
+	"":               {"page", "filter"},
+	"open":           {},
+	"show":           {},
+	"export":         {"template", "to", "file", "list"},
+	"doctor":         {},
+	"workitems list": {"output", "fields", "filter", "all"},
+	"prs list":       {"output", "fields", "filter", "status", "top", "all"},
+	"pipelines list": {"output", "fields"},
+	"pipelines runs": {"output", "fields", "preset", "pipeline", "branch", "reason", "result", "status", "requested-for", "mine", "top", "all"},
+}
+
+// Flags of the commands without a value
+var completionBoolFlags = []string{"all", "mine", "list"}
+
+// Kinds of items of show, open and export
+var completionItemKinds = [][2]string{
+	{"wi", "Work item"},
+	{"pr", "Pull request"},
+	{"run", "Pipeline run"},
+}
+
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// RunCompletion prints the completion script of the shell
+func RunCompletion(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, completionUsageText)
+		return fmt.Errorf("expected a shell: %s", strings.Join(completionShells, ", "))
+	}
+	switch args[0] {
+	case "bash":
+		io.WriteString(w, bashCompletionScript)
+	case "zsh":
+		io.WriteString(w, zshCompletionScript)
+	case "fish":
+		io.WriteString(w, fishCompletionScript)
+	case "powershell":
+		io.WriteString(w, powershellCompletionScript)
+	default:
+		fmt.Fprint(os.Stderr, completionUsageText)
+		return fmt.Errorf("unknown shell %q, available shells: %s", args[0], strings.Join(completionShells, ", "))
+	}
+	return nil
+}
+
+// completionRequest is the command line being completed
+type completionRequest struct {
+	command    string
+	positional []string
+	flags      map[string]string
+	opts       Options
+	// Flag waiting for its value, if any
+	pending string
+}
+
+// parseCompletionWords walks the words before the one being completed, telling the command,
+// its positional arguments and the values of the flags
+func parseCompletionWords(words []string) *completionRequest {
+	req := &completionRequest{flags: map[string]string{}}
+	connectionFlags := flag.NewFlagSet("lazyaz", flag.ContinueOnError)
+	req.opts.registerConnectionFlags(connectionFlags)
+	for _, word := range words {
+		if req.pending != "" {
+			if word == "=" {
+				// Bash splits --flag=value at the equal sign
+				continue
+			}
+			req.setFlag(connectionFlags, req.pending, word)
+			req.pending = ""
+			continue
+		}
+		if word == "--" || word == "-" || !strings.HasPrefix(word, "-") {
+			if slices.ContainsFunc(completionSubcommands[req.command], func(sub [2]string) bool { return sub[0] == word }) && len(req.positional) == 0 {
+				req.command = strings.TrimSpace(req.command + " " + word)
+			} else {
+				req.positional = append(req.positional, word)
+			}
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		if hasValue || isCompletionBoolFlag(connectionFlags, name) {
+			req.setFlag(connectionFlags, name, value)
+		} else {
+			req.pending = name
+		}
+	}
+	return req
+}
+
+func (r *completionRequest) setFlag(connectionFlags *flag.FlagSet, name string, value string) {
+	r.flags[name] = value
+	if connectionFlags.Lookup(name) != nil {
+		if err := connectionFlags.Set(name, value); err != nil && value == "" {
+			// Boolean flags without a value
+			connectionFlags.Set(name, "true")
+		}
+	}
+}
+
+// isCompletionBoolFlag reports whether the flag has no value, e.g. --all or --offline
+func isCompletionBoolFlag(connectionFlags *flag.FlagSet, name string) bool {
+	if f := connectionFlags.Lookup(name); f != nil {
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		return ok && boolFlag.IsBoolFlag()
+	}
+	return slices.Contains(completionBoolFlags, name)
+}
+
+// RunComplete prints the candidates of the last word, one per line with an optional description
+// after a tab. The words are the command line after lazyaz. Nothing is printed to fall back to files.
+func RunComplete(words []string, w io.Writer) error {
+	// Logs would garble the command line
+	log.SetOutput(io.Discard)
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	azuredevops.SetLogger(logger)
+
+	current := ""
+	if len(words) > 0 {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	req := parseCompletionWords(words)
+
+	var candidates [][2]string
+	prefix := ""
+	switch {
+	case req.pending != "":
+		candidates, prefix = req.flagValues(req.pending, current)
+	case strings.HasPrefix(current, "-") && strings.Contains(current, "="):
+		name, value, _ := strings.Cut(strings.TrimLeft(current, "-"), "=")
+		candidates, prefix = req.flagValues(name, value)
+		prefix = current[:len(current)-len(value)] + prefix
+	case strings.HasPrefix(current, "-"):
+		candidates = req.flagNames()
+	default:
+		candidates = req.arguments()
+	}
+
+	for _, candidate := range candidates {
+		value := prefix + candidate[0]
+		if !strings.HasPrefix(value, current) {
+			continue
+		}
+		if candidate[1] != "" {
+			fmt.Fprintf(w, "%s\t%s\n", value, candidate[1])
+		} else {
+			fmt.Fprintln(w, value)
+		}
+	}
+	return nil
+}
+
+// flagNames are the flags of the command
+func (r *completionRequest) flagNames() [][2]string {
+	names, ok := completionCommandFlags[r.command]
+	if !ok {
+		return nil
+	}
+	names = slices.Clone(names)
+	connectionFlags := flag.NewFlagSet("lazyaz", flag.ContinueOnError)
+	new(Options).registerConnectionFlags(connectionFlags)
+	connectionFlags.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	candidates := make([][2]string, 0, len(names))
+	for _, name := range names {
+		candidates = append(candidates, [2]string{"--" + name, ""})
+	}
+	return candidates
+}
+
+// arguments are the subcommands of the command, or its positional arguments
+func (r *completionRequest) arguments() [][2]string {
+	if subcommands, ok := completionSubcommands[r.command]; ok && len(r.positional) == 0 {
+		return subcommands
+	}
+	switch r.command {
+	case "completion":
+		if len(r.positional) == 0 {
+			return valuesOf(completionShells)
+		}
+	case "show", "open", "export":
+		switch len(r.positional) {
+		case 0:
+			if r.command == "open" {
+				// An ID alone is a work item
+				return append(slices.Clone(completionItemKinds), r.itemIDs(azuredevops.ItemWorkItem)...)
+			}
+			return completionItemKinds
+		case 1:
+			kind, err := azuredevops.ParseItemKind(r.positional[0])
+			if err != nil {
+				return nil
+			}
+			return r.itemIDs(kind)
+		}
+	}
+	return nil
+}
+
+// flagValues are the values of the flag. A list of fields is completed after its last comma, given as the prefix.
+func (r *completionRequest) flagValues(name string, value string) (candidates [][2]string, prefix string) {
+	switch name {
+	case "page":
+		return valuesOf(startupPages), ""
+	case "filter":
+		page := r.command
+		if page == "" {
+			page = startupPages[0]
+			if value, ok := r.flags["page"]; ok {
+				page = value
+			}
+		}
+		switch page {
+		case "workitems", "workitems list":
+			return valuesOf(workItemFilters), ""
+		case "prs", "prs list":
+			return valuesOf(pullRequestFilters), ""
+		case "pipelines":
+			return r.presetNames(), ""
+		}
+	case "output":
+		return valuesOf(OutputFormats), ""
+	case "fields":
+		if comma := strings.LastIndex(value, ","); comma >= 0 {
+			prefix = value[:comma+1]
+		}
+		switch r.command {
+		case "workitems list":
+			return valuesOf(recordFields[azuredevops.WorkItem]()), prefix
+		case "prs list":
+			return valuesOf(recordFields[azuredevops.PullRequestDetails]()), prefix
+		case "pipelines list":
+			return valuesOf(recordFields[azuredevops.Pipeline]()), prefix
+		case "pipelines runs":
+			return valuesOf(recordFields[azuredevops.PipelineRun]()), prefix
+		}
+	case "status":
+		if r.command == "prs list" {
+			return valuesOf(azuredevops.PRStatuses), ""
+		}
+		return valuesOf(azuredevops.PipelineRunStatuses()), ""
+	case "reason":
+		return valuesOf(azuredevops.PipelineRunReasons()), ""
+	case "result":
+		return valuesOf(azuredevops.PipelineRunResults()), ""
+	case "profile":
+		return r.profileNames(), ""
+	case "preset":
+		return r.presetNames(), ""
+	case "pipeline":
+		return r.pipelines(), ""
+	case "to":
+		return valuesOf(TemplateOutputs), ""
+	case "template":
+		return r.templateNames(), ""
+	}
+	return nil, ""
+}
+
+func valuesOf(values []string) [][2]string {
+	candidates := make([][2]string, 0, len(values))
+	for _, value := range values {
+		candidates = append(candidates, [2]string{value, ""})
+	}
+	return candidates
+}
+
+// appConfig loads lazyaz.toml of the --config flag, if any
+func (r *completionRequest) appConfig() *AppConfig {
+	config, _, err := loadAppConfig(r.opts.ConfigPath)
+	if err != nil || config == nil {
+		return &AppConfig{}
+	}
+	return config
+}
+
+// templateNames are the templates of the kind of item given to export
+func (r *completionRequest) templateNames() [][2]string {
+	if len(r.positional) == 0 {
+		return nil
+	}
+	kind, err := azuredevops.ParseItemKind(r.positional[0])
+	if err != nil {
+		return nil
+	}
+	config, path, err := loadAppConfig(r.opts.ConfigPath)
+	if err != nil {
+		return nil
+	}
+	ConfigPath = path
+	templates, err := LoadTemplates(exportExtension(config).TemplatesDir(), kind)
+	if err != nil {
+		return nil
+	}
+	var candidates [][2]string
+	for _, t := range templates {
+		candidates = append(candidates, [2]string{t.Name, t.Description})
+	}
+	return candidates
+}
+
+func (r *completionRequest) profileNames() [][2]string {
+	var candidates [][2]string
+	for name, profile := range r.appConfig().Profiles {
+		candidates = append(candidates, [2]string{name, strings.TrimSpace(profile.Organization + " " + profile.Project)})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i][0] < candidates[j][0] })
+	return candidates
+}
+
+func (r *completionRequest) presetNames() [][2]string {
+	presets := LoadPipelineRunsPresets(r.appConfig())
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return valuesOf(names)
+}
+
+// connect points the cache to the organization and project of the command line, without calling the Azure CLI.
+// Returns false when the organization is unknown.
+func (r *completionRequest) connect() bool {
+	OfflineMode = r.opts.Offline || os.Getenv("LAZYAZ_OFFLINE") == "1"
+	layers, err := r.opts.configLayers(r.appConfig())
+	if err != nil {
+		return false
+	}
+	config, err := azuredevops.MergeConfigLayers(azuredevops.ConfigLayers(layers...)...)
+	if err != nil {
+		return false
+	}
+	_organization = config.Organization
+	_project = config.Project
+	client = azuredevops.NewClient(config)
+	azuredevops.SetOffline(OfflineMode)
+	return true
+}
+
+// cachedOrFetched returns the cached items of the names, or fetches them once when none is cached.
+// Fetching is given up after the completion timeout.
+func cachedOrFetched[T any](names []string, fetch func() ([]T, error)) []T {
+	var items []T
+	for _, name := range names {
+		cached, _, ok := LoadCache[azuredevops.Page[T]](name)
+		if ok {
+			items = append(items, cached.Items...)
+		}
+	}
+	if len(items) > 0 || OfflineMode || fetch == nil {
+		return items
+	}
+	done := make(chan []T, 1)
+	go func() {
+		fetched, err := fetch()
+		if err != nil {
+			fetched = nil
+		}
+		done <- fetched
+	}()
+	select {
+	case fetched := <-done:
+		return fetched
+	case <-time.After(completionTimeout):
+		return nil
+	}
+}
+
+// itemIDs are the IDs of the cached items of the kind, described by their titles
+func (r *completionRequest) itemIDs(kind string) [][2]string {
+	if !r.connect() {
+		return nil
+	}
+	var candidates [][2]string
+	seen := map[int]bool{}
+	add := func(id int, description string) {
+		if !seen[id] {
+			seen[id] = true
+			candidates = append(candidates, [2]string{strconv.Itoa(id), description})
+		}
+	}
+	switch kind {
+	case azuredevops.ItemWorkItem:
+		names := make([]string, 0, len(workItemFilters))
+		for _, filter := range workItemFilters {
+			names = append(names, cacheName("workitems", filter))
+		}
+		workItems := cachedOrFetched(names, func() ([]azuredevops.WorkItem, error) {
+			page, _, err := fetchWorkItems(workItemFilters[0])
+			return page.Items, err
+		})
+		for _, workItem := range workItems {
+			add(workItem.ID, workItem.Title)
+		}
+	case azuredevops.ItemPullRequest:
+		names := make([]string, 0, len(pullRequestFilters))
+		for _, filter := range pullRequestFilters {
+			names = append(names, cacheName("pullrequests", filter))
+		}
+		prs := cachedOrFetched(names, func() ([]azuredevops.PullRequestDetails, error) {
+			page, _, err := fetchCached(cacheName("pullrequests", "active"), func() (azuredevops.Page[azuredevops.PullRequestDetails], error) {
+				return client.GetPRsPage(pullRequestsFilterFor("active", ""), "")
+			})
+			return page.Items, err
+		})
+		for _, pr := range prs {
+			add(pr.ID, pr.Title)
+		}
+	case azuredevops.ItemPipelineRun:
+		// Runs are cached by the hash of their filter
+		paths, _ := filepath.Glob(filepath.Join(GetCacheDir(), "pipelineruns-*.json"))
+		names := make([]string, 0, len(paths))
+		for _, path := range paths {
+			names = append(names, strings.TrimSuffix(filepath.Base(path), ".json"))
+		}
+		runs := cachedOrFetched(names, func() ([]azuredevops.PipelineRun, error) {
+			page, _, err := fetchRunsFiltered(withConfiguredTop(azuredevops.PipelineRunsFilter{}))
+			return page.Items, err
+		})
+		for _, run := range runs {
+			add(run.ID, run.DefinitionName+" "+run.BuildNumber)
+		}
+	}
+	return candidates
+}
+
+// pipelines are the IDs of the pipelines, described by their names
+func (r *completionRequest) pipelines() [][2]string {
+	if !r.connect() {
+		return nil
+	}
+	pipelines, _, ok := LoadCache[[]azuredevops.Pipeline]("pipelines")
+	if !ok && !OfflineMode {
+		pipelines = cachedOrFetched(nil, func() ([]azuredevops.Pipeline, error) {
+			pipelines, _, err := fetchCached("pipelines", client.GetPipelineDefinitions)
+			return pipelines, err
+		})
+	}
+	candidates := make([][2]string, 0, len(pipelines))
+	for _, pipeline := range pipelines {
+		candidates = append(candidates, [2]string{strconv.Itoa(pipeline.ID), pipeline.Name})
+	}
+	return candidates
+}
+
+const bashCompletionScript = `# bash completion of lazyaz
+_lazyaz() {
+    local IFS=$'\n'
+    COMPREPLY=($(lazyaz __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null | cut -f1))
+}
+complete -o default -F _lazyaz lazyaz
+`
+
+const zshCompletionScript = `#compdef lazyaz
+# zsh completion of lazyaz
+_lazyaz() {
+    local -a lines candidates
+    local line
+    lines=("${(@f)$(lazyaz __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    for line in $lines; do
+        [[ -z $line ]] && continue
+        if [[ $line == *$'\t'* ]]; then
+            candidates+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+        else
+            candidates+=("${line//:/\\:}")
+        fi
+    done
+    if (( ${#candidates} == 0 )); then
+        _files
+        return
+    fi
+    _describe 'lazyaz' candidates
+}
+if [ "$funcstack[1]" = "_lazyaz" ]; then
+    _lazyaz "$@"
+else
+    compdef _lazyaz lazyaz
+fi
+`
+
+const fishCompletionScript = `# fish completion of lazyaz
+function __lazyaz_complete
+    set -l words (commandline -opc)[2..-1] (commandline -ct)
+    set -l candidates (lazyaz __complete $words 2>/dev/null)
+    if test (count $candidates) -eq 0
+        __fish_complete_path (commandline -ct)
+        return
+    end
+    printf '%s\n' $candidates
+end
+complete -c lazyaz -f -a '(__lazyaz_complete)'
+`
+
+const powershellCompletionScript = `# PowerShell completion of lazyaz
+Register-ArgumentCompleter -Native -CommandName lazyaz -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements |
+        Where-Object { $_.Extent.EndOffset -le $cursorPosition } |
+        Select-Object -Skip 1 |
+        ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') {
+        # Empty arguments are dropped before PowerShell 7.3
+        if ($PSVersionTable.PSVersion -lt [version]'7.3.0') { $words += '""' } else { $words += '' }
+    }
+    lazyaz __complete @words 2>$null | ForEach-Object {
+        $value, $description = $_ -split "` + "`" + `t", 2
+        if (-not $description) { $description = $value }
+        [System.Management.Automation.CompletionResult]::new($value, $value, 'ParameterValue', $description)
+    }
+}
+`
@@ -27,11 +27,33 @@ func GetHotkeyView() *tview.TextView {
 	fmt.Fprintln(w, "Q\tClose details panel")
 	fmt.Fprintln(w, " \tClose hotkeys")
 	fmt.Fprintln(w, "R\tRefresh")
+	fmt.Fprintln(w, "C\tCheckout PR source branch")
+	fmt.Fprintln(w, "Shift+C\tCheckout PR merge ref")
+	fmt.Fprintln(w, "F\tFilter pipeline runs")
+	fmt.Fprintln(w, "M\tToggle my pipeline runs")
+	fmt.Fprintln(w, "N\tRun pipeline")
+	fmt.Fprintln(w, "X\tCancel pipeline run")
+	fmt.Fprintln(w, "E\tRe-run pipeline run")
+	fmt.Fprintln(w, "T\tRetry failed jobs of pipeline run")
+	fmt.Fprintln(w, "L\tView pipeline run logs")
+	fmt.Fprintln(w, "G\tView pipeline run timeline")
+	fmt.Fprintln(w, "A\tBrowse pipeline run artifacts")
+	fmt.Fprintln(w, "P\tReview pending pipeline approvals")
+	fmt.Fprintln(w, "Shift+T\tView test results of pipeline run")
+	fmt.Fprintln(w, "Y\tView pipeline definition YAML")
+	fmt.Fprintln(w, "P (Definitions)\tPause or enable pipeline definition")
+	fmt.Fprintln(w, "W\tWatch work item, PR or pipeline run")
+	fmt.Fprintln(w, "Space\tSelect item for the extensions")
+	fmt.Fprintln(w, "CTRL+N\tView notifications and watched items")
 	fmt.Fprintln(w, "CTRL+K\tToggle hotkeys")
 	fmt.Fprintln(w, "ESC\tExit application")
 	w.Flush()
 
 	helpTextBuilder.WriteString(buf.String())
+	if extensionKeys := extensionHotkeys(); extensionKeys != "" {
+		helpTextBuilder.WriteString("\n[::b]Extensions[::-]\n\n")
+		helpTextBuilder.WriteString(extensionKeys)
+	}
 
 	return tview.NewTextView().
 		SetText(helpTextBuilder.String()).
@@ -39,36 +61,58 @@ func GetHotkeyView() *tview.TextView {
 		SetDynamicColors(true)
 }
 
-func attachExtensionToPanel[T any](extension ExtensionConfig, actionsPanel *tview.Flex, table *tview.Table, items *[]T) {
-	buttonWidth := len(extension.Name) + 6
-	button := tview.NewButton(extension.Name)
+// extensionHotkeys lists the keys of the extensions along with the pages they work on
+func extensionHotkeys() string {
+	if ExtRegistry == nil {
+		return ""
+	}
+	pageNames := map[string]string{"workitems": "Work Items", "pullrequests": "Pull Requests", "pipelines": "Pipelines"}
+	var keys []string
+	pages := map[string][]string{}
+	for _, domain := range []string{"workitems", "pullrequests", "pipelines"} {
+		for _, binding := range ExtRegistry.KeyBindings(domain) {
+			key := binding.Key.String() + "\t" + binding.Description
+			if _, ok := pages[key]; !ok {
+				keys = append(keys, key)
+			}
+			pages[key] = append(pages[key], pageNames[domain])
+		}
+	}
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s (%s)\n", tview.Escape(key), strings.Join(pages[key], ", "))
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// attachExtensionToPanel adds the button of the extension, running it against the selected items of the table,
+// or the highlighted one when none is selected
+func attachExtensionToPanel[T any](extension ExtensionConfig, actionsPanel *tview.Flex, table *tview.Table, items *[]T, id func(T) int, selection *ItemSelection) {
+	label := extension.Name
+	if binding, err := extension.KeyBinding(); extension.Key != "" && err == nil {
+		label += " (" + binding.String() + ")"
+	}
+	buttonWidth := len(label) + 6
+	button := tview.NewButton(label)
 	button.
 		SetSelectedFunc(func() {
-			// Get the selected item
+			// Get the selected items
 			row, _ := table.GetSelection()
-			idx := row - 1
-			if idx >= 0 && idx < len(*items) {
-				selectedItem := (*items)[idx]
-				// Get the extension ID from the registry
-				for id, ext := range ExtRegistry.Extensions {
-					if ext.Name == extension.Name {
-						// Call the extension's entry point with the selected item
-						_, err := extension.EntryPoint(id).(func(interface{}) (string, error))(selectedItem)
-						if err != nil {
-							button.SetBorderColor(tcell.ColorRed)
-							if err == ErrNoClipboard {
-								AnnounceError(fmt.Sprintf("❌ %s failed: no clipboard utility found (install xsel or xclip)", extension.Name))
-							} else {
-								AnnounceError(fmt.Sprintf("❌ %s failed", extension.Name))
-							}
-						} else {
-							button.SetBorderColor(tcell.ColorGreen)
-							Announce(fmt.Sprintf("✅ [green]%s done[white]", extension.Name), 0)
-						}
-						break
-					}
-				}
+			selected := selectedItems(*items, id, selection, row-1)
+			if len(selected) == 0 {
+				return
 			}
+			RunExtensionOnItems(extension, selected, button, func(err error) {
+				if err != nil {
+					button.SetBorderColor(tcell.ColorRed)
+				} else {
+					button.SetBorderColor(tcell.ColorGreen)
+				}
+				clearSelectedRows(table, *items, id, selection)
+			})
 		})
 	button.SetStyle(tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorBlack))
 	button.SetActivatedStyle(tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorWhite))
@@ -76,21 +120,55 @@ func attachExtensionToPanel[T any](extension ExtensionConfig, actionsPanel *tvie
 	actionsPanel.AddItem(nil, 1, 1, false)
 }
 
-func AttachWorkItemExtensions(actionsPanel *tview.Flex, table *tview.Table, workItems *[]azuredevops.WorkItem) {
+func AttachWorkItemExtensions(actionsPanel *tview.Flex, table *tview.Table, workItems *[]azuredevops.WorkItem, selection *ItemSelection) {
 	for _, extension := range ExtRegistry.GetFor("workitems") {
-		attachExtensionToPanel(extension, actionsPanel, table, workItems)
+		attachExtensionToPanel(extension, actionsPanel, table, workItems, workItemID, selection)
 	}
 }
 
-func AttachPullRequestExtensions(actionsPanel *tview.Flex, table *tview.Table, pullRequests *[]azuredevops.PullRequestDetails) {
+func AttachPullRequestExtensions(actionsPanel *tview.Flex, table *tview.Table, pullRequests *[]azuredevops.PullRequestDetails, selection *ItemSelection) {
 	for _, extension := range ExtRegistry.GetFor("pullrequests") {
-		attachExtensionToPanel(extension, actionsPanel, table, pullRequests)
+		attachExtensionToPanel(extension, actionsPanel, table, pullRequests, pullRequestID, selection)
 	}
 }
 
-func AttachPipelineRunExtensions(actionsPanel *tview.Flex, table *tview.Table, pipelines *[]azuredevops.PipelineRun) {
+func AttachPipelineRunExtensions(actionsPanel *tview.Flex, table *tview.Table, pipelines *[]azuredevops.PipelineRun, selection *ItemSelection) {
 	for _, extension := range ExtRegistry.GetFor("pipelines") {
-		attachExtensionToPanel(extension, actionsPanel, table, pipelines)
+		attachExtensionToPanel(extension, actionsPanel, table, pipelines, pipelineRunID, selection)
+	}
+}
+
+// HandleExtensionKey runs the extension bound to the key against the selected items of the domain's table.
+// Returns whether the key was handled. onDone is called on the UI thread once the extension finished.
+func HandleExtensionKey(domain string, event *tcell.EventKey, items []interface{}, focus tview.Primitive, onDone func()) bool {
+	if len(items) == 0 {
+		return false
+	}
+	for _, binding := range ExtRegistry.KeyBindings(domain) {
+		if binding.Key.Matches(event) {
+			RunKeyBinding(binding, items, focus, func(error) { onDone() })
+			return true
+		}
+	}
+	return false
+}
+
+// RunKeyBinding runs the extension of the key, or the function of its script, against the items
+func RunKeyBinding(binding ExtensionKeyBinding, items []interface{}, focus tview.Primitive, onDone func(err error)) {
+	switch {
+	case binding.Function == nil:
+		RunExtensionOnItems(binding.Extension, items, focus, onDone)
+	case len(items) == 1:
+		RunScriptExtension(binding.Extension, binding.Function, items[0], focus, onDone)
+	default:
+		// Scripts ask for confirmation themselves
+		runOnItems(binding.Extension, items, false, focus, onDone, func(domain interface{}) (ExtensionCommandResult, error) {
+			script, err := LoadScriptExtension(binding.Extension.ID, binding.Extension)
+			if err == nil {
+				_, err = script.Call(binding.Function, domain, focus)
+			}
+			return ExtensionCommandResult{}, err
+		})
 	}
 }
 
@@ -130,3 +208,53 @@ func Announce(message string, duration int) {
 func AnnounceError(message string) {
 	Announce(fmt.Sprintf("[red]%s[white]", message), 0)
 }
+
+// Overlays holds the main layout and any dialog shown on top of it
+var Overlays = tview.NewPages()
+
+const mainOverlayPage = "main"
+
+// ShowOverlay displays the primitive centered on top of the main layout
+func ShowOverlay(name string, primitive tview.Primitive, width, height int) {
+	centered := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(primitive, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+	Overlays.AddPage(name, centered, true, true)
+	app.SetFocus(primitive)
+}
+
+// CloseOverlay removes the dialog and gives focus back to the given primitive
+func CloseOverlay(name string, focus tview.Primitive) {
+	Overlays.RemovePage(name)
+	if focus != nil {
+		app.SetFocus(focus)
+	}
+}
+
+// HasOverlay reports whether a dialog is shown on top of the main layout
+func HasOverlay() bool {
+	name, _ := Overlays.GetFrontPage()
+	return name != "" && name != mainOverlayPage
+}
+
+const confirmOverlay = "confirm"
+
+// ShowConfirm asks the user to confirm an action before running onConfirm
+func ShowConfirm(message string, focus tview.Primitive, onConfirm func()) {
+	modal := tview.NewModal().
+		SetText(message).
+		AddButtons([]string{"Cancel", "Confirm"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			CloseOverlay(confirmOverlay, focus)
+			if buttonLabel == "Confirm" {
+				onConfirm()
+			}
+		})
+	Overlays.AddPage(confirmOverlay, modal, true, true)
+	app.SetFocus(modal)
+}
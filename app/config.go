@@ -6,32 +6,312 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
 )
 
 // AppConfig represents the application configuration
 type AppConfig struct {
-	WorkItems  WorkItemsConfig            `toml:"workitems"`
-	Extensions map[string]ExtensionConfig `toml:"extensions"`
+	Organization  string                     `toml:"organization"`
+	Project       string                     `toml:"project"`
+	Profiles      map[string]ProfileConfig   `toml:"profiles"`
+	WorkItems     WorkItemsConfig            `toml:"workitems"`
+	PullRequests  PullRequestsConfig         `toml:"pullrequests"`
+	Pipelines     PipelinesConfig            `toml:"pipelines"`
+	Refresh       RefreshConfig              `toml:"refresh"`
+	Notifications NotificationsConfig        `toml:"notifications"`
+	Extensions    map[string]ExtensionConfig `toml:"extensions"`
+}
+
+// ProfileConfig is a named organization and project, picked with --profile
+type ProfileConfig struct {
+	Organization string `toml:"organization"`
+	Project      string `toml:"project"`
+}
+
+// PullRequestsConfig represents the configuration for pull requests
+type PullRequestsConfig struct {
+	RefreshInterval int `toml:"refresh_interval"`
+}
+
+// PipelinesConfig represents the configuration for pipelines
+type PipelinesConfig struct {
+	Top             int                           `toml:"top"`
+	Presets         map[string]PipelineRunsPreset `toml:"presets"`
+	RefreshInterval int                           `toml:"refresh_interval"`
+}
+
+// RefreshConfig represents the configuration of the background refresh of the pages.
+// Intervals are in seconds, 0 disables the auto-refresh.
+type RefreshConfig struct {
+	Interval  int `toml:"interval"`
+	IdleAfter int `toml:"idle_after"`
+}
+
+// RefreshIntervalFor returns the auto-refresh interval of the page ("workitems", "pullrequests",
+// "pipelines" or "definitions"), falling back to the interval of all pages
+func (c *AppConfig) RefreshIntervalFor(page string) time.Duration {
+	if c == nil {
+		return 0
+	}
+	seconds := c.Refresh.Interval
+	var pageSeconds int
+	switch page {
+	case "workitems":
+		pageSeconds = c.WorkItems.RefreshInterval
+	case "pullrequests":
+		pageSeconds = c.PullRequests.RefreshInterval
+	case "pipelines", "definitions":
+		pageSeconds = c.Pipelines.RefreshInterval
+	}
+	if pageSeconds != 0 {
+		seconds = pageSeconds
+	}
+	return time.Duration(max(seconds, 0)) * time.Second
+}
+
+// IdleAfter returns how long without interaction the terminal is considered idle
+func (c *AppConfig) IdleAfter() time.Duration {
+	if c == nil || c.Refresh.IdleAfter <= 0 {
+		return defaultIdleAfter
+	}
+	return time.Duration(c.Refresh.IdleAfter) * time.Second
+}
+
+// NotificationsConfig represents the configuration of the notifications of watched items.
+// The interval is in seconds. Desktop notifications use notify-send, terminal notifications the OSC 9 escape sequence.
+type NotificationsConfig struct {
+	Interval int  `toml:"interval"`
+	Desktop  bool `toml:"desktop"`
+	Terminal bool `toml:"terminal"`
+}
+
+// WatchInterval returns how often the watched items are checked for changes
+func (c *AppConfig) WatchInterval() time.Duration {
+	if c == nil || c.Notifications.Interval <= 0 {
+		return defaultWatchInterval
+	}
+	return time.Duration(c.Notifications.Interval) * time.Second
+}
+
+// PipelineRunsPreset is a named set of pipeline run filters
+type PipelineRunsPreset struct {
+	PipelineID   int    `toml:"pipeline_id,omitempty"`
+	Branch       string `toml:"branch,omitempty"`
+	Reason       string `toml:"reason,omitempty"`
+	Result       string `toml:"result,omitempty"`
+	Status       string `toml:"status,omitempty"`
+	RequestedFor string `toml:"requested_for,omitempty"`
+	MyRuns       bool   `toml:"my_runs,omitempty"`
+	Top          int    `toml:"top,omitempty"`
+}
+
+// ToFilter converts the preset to the filter of the pipeline runs, "my runs" being the given user by mail,
+// or by ID for the profiles without a mail. Fails when "my runs" cannot be told, the runs of everyone being returned.
+func (p PipelineRunsPreset) ToFilter(user *azuredevops.UserProfile) (azuredevops.PipelineRunsFilter, error) {
+	filter := azuredevops.PipelineRunsFilter{
+		PipelineID:   p.PipelineID,
+		Branch:       p.Branch,
+		Reason:       p.Reason,
+		Result:       p.Result,
+		Status:       p.Status,
+		RequestedFor: p.RequestedFor,
+		Top:          p.Top,
+	}
+	if !p.MyRuns {
+		return filter, nil
+	}
+	switch {
+	case user == nil:
+		return filter, fmt.Errorf("my runs needs the user profile")
+	case user.Mail != "":
+		filter.RequestedFor = user.Mail
+	case user.ID != "":
+		filter.RequestedFor = user.ID
+	default:
+		return filter, fmt.Errorf("my runs needs the mail or the ID of the user profile")
+	}
+	return filter, nil
+}
+
+// Describe the filters of the preset that are set, e.g. "branch=main result=failed"
+func (p PipelineRunsPreset) String() string {
+	var parts []string
+	for _, field := range []struct{ name, value string }{
+		{"branch", p.Branch},
+		{"reason", p.Reason},
+		{"result", p.Result},
+		{"status", p.Status},
+		{"requested-for", p.RequestedFor},
+	} {
+		if field.value != "" && field.value != "all" {
+			parts = append(parts, field.name+"="+field.value)
+		}
+	}
+	if p.MyRuns {
+		parts = append(parts, "my runs")
+	}
+	if p.Top > 0 {
+		parts = append(parts, fmt.Sprintf("top=%d", p.Top))
+	}
+	return strings.Join(parts, " ")
+}
+
+// GetPipelineRunsPresetsPath returns the file where the presets saved from the application are kept
+func GetPipelineRunsPresetsPath() string {
+	return filepath.Join(filepath.Dir(GetDefaultConfigPath()), "pipeline_presets.toml")
+}
+
+// LoadPipelineRunsPresets returns the presets of the configuration along with the saved ones.
+// Saved presets take precedence over configured presets of the same name.
+func LoadPipelineRunsPresets(config *AppConfig) map[string]PipelineRunsPreset {
+	presets := map[string]PipelineRunsPreset{}
+	if config != nil {
+		for name, preset := range config.Pipelines.Presets {
+			presets[name] = preset
+		}
+	}
+	var saved map[string]PipelineRunsPreset
+	if _, err := toml.DecodeFile(GetPipelineRunsPresetsPath(), &saved); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error reading saved pipeline presets: %v", err)
+	}
+	for name, preset := range saved {
+		presets[name] = preset
+	}
+	return presets
+}
+
+// SavePipelineRunsPreset adds or replaces the preset in the saved presets file
+func SavePipelineRunsPreset(name string, preset PipelineRunsPreset) error {
+	path := GetPipelineRunsPresetsPath()
+	saved := map[string]PipelineRunsPreset{}
+	if _, err := toml.DecodeFile(path, &saved); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading saved pipeline presets: %v", err)
+	}
+	saved[name] = preset
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating config directory: %v", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error writing saved pipeline presets: %v", err)
+	}
+	defer file.Close()
+	if err := toml.NewEncoder(file).Encode(saved); err != nil {
+		return fmt.Errorf("error encoding saved pipeline presets: %v", err)
+	}
+	return nil
 }
 
 // WorkItemsConfig represents the configuration for work items
 type WorkItemsConfig struct {
-	Extensions []string `toml:"extensions"`
+	Extensions      []string `toml:"extensions"`
+	RefreshInterval int      `toml:"refresh_interval"`
+	PrefetchDetails bool     `toml:"prefetch_details"`
 }
 
+// Kinds of extensions: built into the application, running a command of the configuration, or a Starlark script
+const (
+	ExtensionKindBuiltin = "builtin"
+	ExtensionKindCommand = "command"
+	ExtensionKindScript  = "script"
+)
+
+// Where the output of a command extension is shown
+const (
+	ExtensionOutputStatus = "status"
+	ExtensionOutputPanel  = "panel"
+)
+
+// Time given to a command or script extension when the configuration has no timeout
+const defaultExtensionTimeout = 30 * time.Second
+
 // ExtensionConfig represents the configuration for an extension
 type ExtensionConfig struct {
+	ID                 string   `toml:"-"`
 	Name               string   `toml:"name"`
 	Description        string   `toml:"description"`
+	Kind               string   `toml:"kind"`
 	TemplatesDirectory string   `toml:"templates_directory"`
 	AppliesTo          []string `toml:"applies_to"`
-	CanInitialize      bool     `toml:"-"`
+	// Key running the extension from the tables of the pages it applies to, e.g. "o", "ctrl+o" or "f5"
+	Key string `toml:"key"`
+	// Command extensions run the command in the shell with the selected item, see RunCommand.
+	// The timeout is in seconds, the output is shown in the status bar or a result panel.
+	Command string `toml:"command"`
+	Timeout int    `toml:"timeout"`
+	Confirm bool   `toml:"confirm"`
+	Output  string `toml:"output"`
+	// Templates of export_to_template are read from templates_directory, ~/.lazyaz/templates by default, see LoadTemplates.
+	// They go to the output, "clipboard" by default, the file output writing output_file.
+	OutputFile string `toml:"output_file"`
+	// Script extensions run the Starlark script, relative to lazyaz.toml, see LoadScriptExtension.
+	// Scripts may only read and write files in the allowed directories, reach the allowed hosts
+	// ("*.example.com" allows the subdomains), and change items when allowed to.
+	Script        string   `toml:"script"`
+	AllowRead     []string `toml:"allow_read"`
+	AllowWrite    []string `toml:"allow_write"`
+	AllowNetwork  []string `toml:"allow_network"`
+	AllowUpdates  bool     `toml:"allow_updates"`
+	CanInitialize bool     `toml:"-"`
+}
+
+// KindOf returns the kind of the extension, a command or script extension when only the command or the script is set
+func (e ExtensionConfig) KindOf() string {
+	switch {
+	case e.Kind != "":
+		return e.Kind
+	case e.Script != "":
+		return ExtensionKindScript
+	case e.Command != "":
+		return ExtensionKindCommand
+	default:
+		return ExtensionKindBuiltin
+	}
+}
+
+// KeyBinding parses the key of the extension, which cannot be one of the keys of the pages it applies to
+func (e ExtensionConfig) KeyBinding() (keyBinding, error) {
+	binding, err := parseKeyBinding(e.Key)
+	if err != nil {
+		return binding, err
+	}
+	if page, conflict := binding.ConflictsWith(e.AppliesTo); conflict {
+		return binding, fmt.Errorf("key %q is already used on %s", e.Key, page)
+	}
+	return binding, nil
+}
+
+// TimeoutDuration returns how long a command or script extension may run
+func (e ExtensionConfig) TimeoutDuration() time.Duration {
+	if e.Timeout <= 0 {
+		return defaultExtensionTimeout
+	}
+	return time.Duration(e.Timeout) * time.Second
 }
 
 // EntryPoint returns the function corresponding to the extension ID
 func (e ExtensionConfig) EntryPoint(id string) interface{} {
+	if e.KindOf() == ExtensionKindCommand {
+		return func(domain interface{}) (string, error) {
+			result, err := e.RunCommand(id, domain)
+			return result.Stdout, err
+		}
+	}
+	if e.KindOf() == ExtensionKindScript {
+		return func(domain interface{}) (string, error) {
+			script, err := LoadScriptExtension(id, e)
+			if err != nil {
+				return "", err
+			}
+			_, err = script.Call(nil, domain, nil)
+			return "", err
+		}
+	}
+
 	// Convert from snake_case to CamelCase
 	parts := strings.Split(strings.Replace(id, ".", "_", -1), "_")
 	var camelCase string
@@ -56,9 +336,38 @@ func (e ExtensionConfig) EntryPoint(id string) interface{} {
 
 // Allow extension to know ahead if it can initialize. By default, it is true. Also returns reason if it cannot initialize.
 func (e ExtensionConfig) TryInitialize(id string) (bool, string) {
-	// If export_to_template, check if clipboard utility is installed
+	if e.Key != "" {
+		if _, err := e.KeyBinding(); err != nil {
+			return false, fmt.Sprintf("Invalid key: %v.", err)
+		}
+	}
+	switch e.KindOf() {
+	case ExtensionKindBuiltin:
+	case ExtensionKindCommand:
+		if strings.TrimSpace(e.Command) == "" {
+			return false, "The command is empty."
+		}
+		if e.Output != "" && e.Output != ExtensionOutputStatus && e.Output != ExtensionOutputPanel {
+			return false, fmt.Sprintf("Unknown output %q, use %q or %q.", e.Output, ExtensionOutputStatus, ExtensionOutputPanel)
+		}
+		return true, ""
+	case ExtensionKindScript:
+		if strings.TrimSpace(e.Script) == "" {
+			return false, "The script is empty."
+		}
+		if _, err := LoadScriptExtension(id, e); err != nil {
+			return false, fmt.Sprintf("Cannot load the script: %v", err)
+		}
+		return true, ""
+	default:
+		return false, fmt.Sprintf("Unknown kind %q.", e.Kind)
+	}
+	// If export_to_template, check the output, and if the clipboard utility is installed for the clipboard
 	if id == "export_to_template" {
-		if !CheckClipboardUtility() {
+		if e.Output != "" && !validTemplateOutput(e.Output) {
+			return false, fmt.Sprintf("Unknown output %q, use one of %s.", e.Output, strings.Join(TemplateOutputs, ", "))
+		}
+		if (e.Output == "" || e.Output == TemplateOutputClipboard) && !CheckClipboardUtility() {
 			return false, "Clipboard utility not found. Please install xsel or xclip."
 		}
 	}
@@ -126,6 +435,18 @@ applies_to = ["workitems", "pullrequests", "pipelines"]
 	return nil
 }
 
+// Path of the loaded configuration file, empty when none was found
+var ConfigPath string
+
+// loadAppConfig loads the configuration file of the path given on the command line, or finds it
+func loadAppConfig(path string) (*AppConfig, string, error) {
+	if path == "" {
+		return FindConfig()
+	}
+	config, err := LoadConfig(path)
+	return config, path, err
+}
+
 // FindConfig looks for the config file in the current directory and home directory
 func FindConfig() (*AppConfig, string, error) {
 	// First, try the current directory
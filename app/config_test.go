@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+)
+
+func TestPipelineRunsPresetToFilter(t *testing.T) {
+	tests := []struct {
+		name                 string
+		preset               PipelineRunsPreset
+		user                 *azuredevops.UserProfile
+		expectedRequestedFor string
+		expectedErr          bool
+	}{
+		{"not my runs", PipelineRunsPreset{RequestedFor: "someone@example.com"}, nil, "someone@example.com", false},
+		{"my runs by mail", PipelineRunsPreset{MyRuns: true}, &azuredevops.UserProfile{ID: "1234", Mail: "me@example.com"}, "me@example.com", false},
+		{"my runs over the requested for", PipelineRunsPreset{RequestedFor: "someone@example.com", MyRuns: true}, &azuredevops.UserProfile{Mail: "me@example.com"}, "me@example.com", false},
+		{"my runs by ID without a mail", PipelineRunsPreset{MyRuns: true}, &azuredevops.UserProfile{ID: "1234"}, "1234", false},
+		{"my runs without a user", PipelineRunsPreset{MyRuns: true}, nil, "", true},
+		{"my runs without a mail or an ID", PipelineRunsPreset{MyRuns: true}, &azuredevops.UserProfile{DisplayName: "Me"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := tt.preset.ToFilter(tt.user)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("Expected an error: %v, got %v", tt.expectedErr, err)
+			}
+			if !tt.expectedErr && filter.RequestedFor != tt.expectedRequestedFor {
+				t.Errorf("Expected the runs requested for %q, got %q", tt.expectedRequestedFor, filter.RequestedFor)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/dustin/go-humanize"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+const pipelineYAMLOverlay = "pipeline-yaml"
+
+// latestRunIcon is the result of the latest run of the pipeline as an icon
+func latestRunIcon(pipeline azuredevops.Pipeline) string {
+	if pipeline.LatestRunID == 0 {
+		return "[gray]-[-]"
+	}
+	if pipeline.LatestRunStatus != "completed" {
+		return _timelineStateIcons["inProgress"]
+	}
+	if icon, ok := _timelineResultIcons[pipeline.LatestRunResult]; ok {
+		return icon
+	}
+	return "[white]·[-]"
+}
+
+// pipelineDefinitionFingerprint changes whenever the pipeline is updated or has a new run
+func pipelineDefinitionFingerprint(pipeline azuredevops.Pipeline) string {
+	return fmt.Sprintf("%s|%s|%d|%s|%s", pipeline.Name, pipeline.Status, pipeline.LatestRunID, pipeline.LatestRunStatus, pipeline.LatestRunResult)
+}
+
+func pipelineDefinitionToDetailsData(pipeline azuredevops.Pipeline) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', tabwriter.TabIndent)
+
+	var keyColor = "[blue]"
+	var valueColor = "[white]"
+
+	fmt.Fprintf(w, "%sID%s\t%d\n", keyColor, valueColor, pipeline.ID)
+	fmt.Fprintf(w, "%sName%s\t%s\n", keyColor, valueColor, tview.Escape(pipeline.Name))
+	fmt.Fprintf(w, "%sFolder%s\t%s\n", keyColor, valueColor, tview.Escape(pipeline.Path))
+	if pipeline.IsEnabled() {
+		fmt.Fprintf(w, "%sQueue Status%s\t[green]Enabled[white]\n", keyColor, valueColor)
+	} else {
+		fmt.Fprintf(w, "%sQueue Status%s\t[orange]%s[white]\n", keyColor, valueColor, cases.Title(language.English).String(pipeline.Status))
+	}
+	fmt.Fprintf(w, "%sDefault Queue%s\t%s\n", keyColor, valueColor, pipeline.DefaultQueue)
+	fmt.Fprintf(w, "%sType%s\t%s\n", keyColor, valueColor, cases.Title(language.English).String(pipeline.PipelineType))
+	if isSameAsUser(pipeline.Author, activeUser) {
+		fmt.Fprintf(w, "%sAuthor%s\t[green]%s[white]\n", keyColor, valueColor, pipeline.Author)
+	} else {
+		fmt.Fprintf(w, "%sAuthor%s\t%s\n", keyColor, valueColor, pipeline.Author)
+	}
+	if pipeline.LatestRunID != 0 {
+		result := pipeline.LatestRunResult
+		if pipeline.LatestRunStatus != "completed" {
+			result = pipeline.LatestRunStatus
+		}
+		fmt.Fprintf(w, "%sLatest Run%s\t%s %s %s", keyColor, valueColor, latestRunIcon(pipeline), pipeline.LatestRunBuildNumber, cases.Title(language.English).String(result))
+		if !pipeline.LatestRunFinishTime.IsZero() {
+			fmt.Fprintf(w, " (%s)", humanize.Time(pipeline.LatestRunFinishTime))
+		}
+		fmt.Fprintln(w)
+	} else {
+		fmt.Fprintf(w, "%sLatest Run%s\t-\n", keyColor, valueColor)
+	}
+
+	w.Flush()
+	return buf.String()
+}
+
+// highlightYAML adds line numbers, and colors the comments and keys of the YAML content
+func highlightYAML(content string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	width := len(fmt.Sprint(len(lines)))
+	var highlighted strings.Builder
+	for i, line := range lines {
+		fmt.Fprintf(&highlighted, "[gray]%*d[-] ", width, i+1)
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "#"):
+			highlighted.WriteString("[gray]" + tview.Escape(line) + "[-]")
+		case strings.Contains(line, ":") && !strings.HasPrefix(strings.TrimPrefix(trimmed, "- "), "\""):
+			key, value, _ := strings.Cut(line, ":")
+			highlighted.WriteString("[blue]" + tview.Escape(key) + "[-]:" + tview.Escape(value))
+		default:
+			highlighted.WriteString(tview.Escape(line))
+		}
+		highlighted.WriteString("\n")
+	}
+	return highlighted.String()
+}
+
+// ShowPipelineYAML shows the YAML file of the pipeline definition from its repository
+func ShowPipelineYAML(pipelineID int, focus tview.Primitive) {
+	textView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(false).
+		SetText("[yellow]Loading...[white]")
+	textView.SetBorder(true).
+		SetTitle(" Pipeline YAML ")
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]↑/↓[white] scroll  [yellow]q[white] close")
+
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(textView, 0, 1, true).
+		AddItem(statusBar, 1, 0, false)
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+			CloseOverlay(pipelineYAMLOverlay, focus)
+			return nil
+		}
+		return event
+	})
+
+	Overlays.AddPage(pipelineYAMLOverlay, layout, true, true)
+	app.SetFocus(textView)
+
+	go func() {
+		var content string
+		definition, err := client.GetPipelineDefinition(pipelineID)
+		if err == nil {
+			content, err = client.GetPipelineDefinitionYAML(definition, "")
+		}
+		app.QueueUpdateDraw(func() {
+			if err != nil {
+				log.Printf("Error fetching pipeline YAML: %v", err)
+				textView.SetText(fmt.Sprintf("[red]%s[white]", tview.Escape(err.Error())))
+				return
+			}
+			textView.SetTitle(fmt.Sprintf(" %s: %s (%s) ", definition.Name, definition.YAMLFilename, strings.TrimPrefix(definition.DefaultBranch, "refs/heads/")))
+			textView.SetText(highlightYAML(content))
+			textView.ScrollToBeginning()
+		})
+	}()
+}
+
+func PipelineDefinitionsPage(nextSlide func()) (title string, content tview.Primitive) {
+	var definitions []azuredevops.Pipeline
+	isFetching := make(chan bool, 1)
+
+	root := tview.NewTreeNode("\\").
+		SetColor(tcell.ColorYellow)
+	tree := tview.NewTreeView().
+		SetRoot(root).
+		SetCurrentNode(root).
+		SetGraphicsColor(tcell.ColorGray)
+
+	detailsTextView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWordWrap(true)
+	detailsPanel := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(detailsTextView, 0, 1, false)
+	detailsPanel.SetBorder(true).
+		SetTitle(" Pipeline ")
+
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]Enter[white] expand/collapse  [yellow]y[white] view YAML  [yellow]n[white] run  [yellow]p[white] pause/enable  [yellow]r[white] refresh")
+
+	mainWindow := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(tree, 0, 1, true).
+			AddItem(detailsPanel, 0, 1, false), 0, 1, true).
+		AddItem(statusBar, 1, 0, false)
+
+	selectedPipeline := func() (azuredevops.Pipeline, bool) {
+		node := tree.GetCurrentNode()
+		if node == nil {
+			return azuredevops.Pipeline{}, false
+		}
+		pipeline, ok := node.GetReference().(azuredevops.Pipeline)
+		return pipeline, ok
+	}
+
+	pipelineNodeText := func(pipeline azuredevops.Pipeline) string {
+		text := fmt.Sprintf("%s %s", latestRunIcon(pipeline), tview.Escape(pipeline.Name))
+		if !pipeline.IsEnabled() {
+			text += fmt.Sprintf(" [orange](%s)[-]", pipeline.Status)
+		}
+		return text
+	}
+
+	// Pipelines that appeared or changed since the previous refresh
+	changedIDs := map[int]bool{}
+
+	var addFolder func(parent *tview.TreeNode, folder *azuredevops.PipelineFolder)
+	addFolder = func(parent *tview.TreeNode, folder *azuredevops.PipelineFolder) {
+		for _, child := range folder.Folders {
+			node := tview.NewTreeNode("📁 " + tview.Escape(child.Name)).
+				SetColor(tcell.ColorYellow).
+				SetReference(child.Path).
+				SetExpanded(false)
+			parent.AddChild(node)
+			addFolder(node, child)
+		}
+		for _, pipeline := range folder.Pipelines {
+			node := tview.NewTreeNode(pipelineNodeText(pipeline)).
+				SetReference(pipeline)
+			if changedIDs[pipeline.ID] {
+				node.SetTextStyle(tcell.StyleDefault.Background(RefreshHighlightColor))
+			}
+			parent.AddChild(node)
+		}
+	}
+
+	redrawTree := func() {
+		// Keep the selection and the expanded folders across refreshes
+		var selectedID int
+		if pipeline, ok := selectedPipeline(); ok {
+			selectedID = pipeline.ID
+		}
+		expanded := map[string]bool{}
+		root.Walk(func(node, parent *tview.TreeNode) bool {
+			if path, ok := node.GetReference().(string); ok && node.IsExpanded() {
+				expanded[path] = true
+			}
+			return true
+		})
+
+		root.ClearChildren()
+		addFolder(root, azuredevops.BuildPipelineFolderTree(definitions))
+
+		var selected *tview.TreeNode
+		root.Walk(func(node, parent *tview.TreeNode) bool {
+			switch reference := node.GetReference().(type) {
+			case string:
+				node.SetExpanded(expanded[reference])
+			case azuredevops.Pipeline:
+				if reference.ID == selectedID || (selectedID == 0 && selected == nil) {
+					selected = node
+				}
+			}
+			return true
+		})
+		if selected != nil {
+			// Expand the folders up to the selected pipeline
+			tree.SetCurrentNode(selected)
+			for _, node := range tree.GetPath(selected) {
+				node.SetExpanded(true)
+			}
+			if pipeline, ok := selected.GetReference().(azuredevops.Pipeline); ok {
+				detailsTextView.SetText(pipelineDefinitionToDetailsData(pipeline))
+			}
+		}
+	}
+
+	loadData := func() {
+		select {
+		case isFetching <- true:
+			latest, cachedAt, err := fetchCached("pipeline-definitions", client.GetPipelineDefinitionsWithLatestRun)
+			<-isFetching
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					log.Printf("Error fetching pipeline definitions: %v", err)
+					AnnounceError("❌ Error fetching pipeline definitions")
+					return
+				}
+				if !cachedAt.IsZero() {
+					AnnounceCached("pipeline definitions", cachedAt)
+					// Keep what is shown rather than going back to the cache
+					if len(definitions) > 0 {
+						return
+					}
+				}
+				var changed map[int]bool
+				pipelineID := func(pipeline azuredevops.Pipeline) int { return pipeline.ID }
+				definitions, changed = mergeRefreshed(definitions, latest, pipelineID, pipelineDefinitionFingerprint)
+				changedIDs = map[int]bool{}
+				for index := range changed {
+					changedIDs[definitions[index].ID] = true
+				}
+				redrawTree()
+			})
+		default:
+			// Another fetch is in progress, skip this one
+			return
+		}
+	}
+
+	// Show the cached definitions while the fresh ones load
+	if cached, _, ok := LoadCache[[]azuredevops.Pipeline]("pipeline-definitions"); ok {
+		definitions = cached
+		redrawTree()
+	}
+	go loadData()
+	StartAutoRefresh("definitions", loadData, nil)
+
+	tree.SetChangedFunc(func(node *tview.TreeNode) {
+		if pipeline, ok := node.GetReference().(azuredevops.Pipeline); ok {
+			detailsTextView.SetText(pipelineDefinitionToDetailsData(pipeline))
+		}
+	})
+	tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		node.SetExpanded(!node.IsExpanded())
+	})
+
+	togglePaused := func(pipeline azuredevops.Pipeline) {
+		status, verb := "paused", "Pause"
+		if !pipeline.IsEnabled() {
+			status, verb = "enabled", "Enable"
+		}
+		ShowConfirm(fmt.Sprintf("%s new runs of %s?", verb, pipeline.Name), tree, func() {
+			Announce(fmt.Sprintf("⏳ Updating %s...", pipeline.Name), -1)
+			go func() {
+				err := client.SetPipelineQueueStatus(pipeline.ID, status)
+				if err != nil {
+					log.Printf("Error updating pipeline %d: %v", pipeline.ID, err)
+					app.QueueUpdateDraw(func() {
+						AnnounceError(fmt.Sprintf("❌ Error updating %s", pipeline.Name))
+					})
+					return
+				}
+				app.QueueUpdateDraw(func() {
+					Announce(fmt.Sprintf("✅ %s is now %s", pipeline.Name, status), 0)
+				})
+				loadData()
+			}()
+		})
+	}
+
+	mainWindow.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'r':
+			Announce("⏳ Refreshing pipeline definitions...", 3)
+			go loadData()
+			return nil
+		case 'y':
+			if pipeline, ok := selectedPipeline(); ok {
+				ShowPipelineYAML(pipeline.ID, tree)
+			}
+			return nil
+		case 'n':
+			if pipeline, ok := selectedPipeline(); ok {
+				ShowQueuePipelineRunForm(pipeline.ID, "", tree, func(run *azuredevops.PipelineRun) {
+					go loadData()
+				})
+			}
+			return nil
+		case 'p':
+			if pipeline, ok := selectedPipeline(); ok {
+				togglePaused(pipeline)
+			}
+			return nil
+		}
+		return event
+	})
+
+	return "Definitions", mainWindow
+}
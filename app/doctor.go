@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+)
+
+// doctorReport is the outcome of the doctor, printed as JSON for support tickets
+type doctorReport struct {
+	Version            string                    `json:"version"`
+	Platform           string                    `json:"platform"`
+	ConfigPath         string                    `json:"configPath"`
+	Organization       string                    `json:"organization"`
+	OrganizationSource string                    `json:"organizationSource"`
+	Project            string                    `json:"project"`
+	ProjectSource      string                    `json:"projectSource"`
+	Layers             []azuredevops.ConfigLayer `json:"layers"`
+	Checks             []azuredevops.Check       `json:"checks"`
+}
+
+// RunDoctor checks the installation, the connection to the organization and project, lazyaz.toml and its extensions,
+// and the helpers of the extensions, then reports where the organization and project come from, by precedence
+func RunDoctor(args []string, opts *Options) error {
+	flags := flag.NewFlagSet("lazyaz doctor", flag.ContinueOnError)
+	opts.registerConnectionFlags(flags)
+	output := flags.String("output", "text", "Output format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q, available formats: text, json", *output)
+	}
+	if err := opts.setupLogging(); err != nil {
+		return err
+	}
+
+	report := doctorReport{
+		Version:  versionString(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Checks:   azuredevops.InstallationChecks(),
+	}
+
+	config, path, configErr := loadAppConfig(opts.ConfigPath)
+	report.ConfigPath, ConfigPath = path, path
+	layers, err := opts.configLayers(config)
+	if err != nil {
+		return err
+	}
+	report.Layers = azuredevops.ConfigLayers(layers...)
+	resolved, err := azuredevops.MergeConfigLayers(report.Layers...)
+	if err != nil {
+		resolved = &azuredevops.Config{}
+	}
+	report.Organization, report.OrganizationSource = resolved.Organization, resolved.OrganizationSource
+	report.Project, report.ProjectSource = resolved.Project, resolved.ProjectSource
+	report.Checks = append(report.Checks, azuredevops.NewClient(resolved).ConnectionChecks()...)
+	report.Checks = append(report.Checks, configurationChecks(config, path, configErr)...)
+	report.Checks = append(report.Checks, helperChecks()...)
+
+	if *output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printDoctorReport(os.Stdout, report)
+	}
+
+	failures := 0
+	for _, check := range report.Checks {
+		if !check.OK {
+			failures++
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d of %d checks failed", failures, len(report.Checks))
+	}
+	return nil
+}
+
+// configurationChecks checks that lazyaz.toml parses and that every extension can initialize
+func configurationChecks(config *AppConfig, path string, configErr error) []azuredevops.Check {
+	if configErr != nil {
+		return []azuredevops.Check{{
+			Group:          azuredevops.CheckGroupConfiguration,
+			Name:           "lazyaz.toml",
+			Detail:         configErr.Error(),
+			Recommendation: "Fix the syntax of lazyaz.toml, or give its path with --config.",
+		}}
+	}
+	checks := []azuredevops.Check{{Group: azuredevops.CheckGroupConfiguration, Name: "lazyaz.toml", OK: true, Detail: path}}
+
+	ids := make([]string, 0, len(config.Extensions))
+	for id := range config.Extensions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		extension := config.Extensions[id]
+		check := azuredevops.Check{Group: azuredevops.CheckGroupConfiguration, Name: "Extension " + id, OK: true, Detail: extension.Name}
+		if ok, reason := extension.TryInitialize(id); !ok {
+			check.OK, check.Detail, check.Recommendation = false, "cannot initialize", reason
+		} else if extension.EntryPoint(id) == nil {
+			check.OK, check.Detail, check.Recommendation = false, "entry point not found", "Check the ID of the extension in lazyaz.toml."
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// helperChecks checks the commands the extensions use to copy to the clipboard and open the browser
+func helperChecks() []azuredevops.Check {
+	clipboard := azuredevops.Check{Group: azuredevops.CheckGroupHelpers, Name: "Clipboard", OK: CheckClipboardUtility()}
+	if !clipboard.OK {
+		clipboard.Detail = ErrNoClipboard.Error()
+		clipboard.Recommendation = "Clipboard utility not found. Please install xsel or xclip."
+	}
+	browser := azuredevops.Check{Group: azuredevops.CheckGroupHelpers, Name: "Browser", OK: CheckBrowserUtility(), Detail: browserCommand()}
+	if !browser.OK {
+		browser.Detail = browserCommand() + " not found"
+		browser.Recommendation = fmt.Sprintf("Install %s to open items in the browser.", browserCommand())
+	}
+	return []azuredevops.Check{clipboard, browser}
+}
+
+// printDoctorReport prints the checks by group, the precedence of the organization and project, then the recommendations
+func printDoctorReport(out io.Writer, report doctorReport) {
+	headings := map[string]string{
+		azuredevops.CheckGroupInstallation:  "Installation:",
+		azuredevops.CheckGroupConnection:    "Connection:",
+		azuredevops.CheckGroupConfiguration: "Configuration:",
+		azuredevops.CheckGroupHelpers:       "Helpers:",
+	}
+	var recommendations []string
+	group := ""
+	for _, check := range report.Checks {
+		if check.Group != group {
+			if group != "" {
+				fmt.Fprintln(out)
+			}
+			group = check.Group
+			fmt.Fprintln(out, headings[group])
+		}
+		fmt.Fprintln(out, check)
+		if !check.OK && check.Recommendation != "" && !slices.Contains(recommendations, check.Recommendation) {
+			recommendations = append(recommendations, check.Recommendation)
+		}
+		if check.Group == azuredevops.CheckGroupConfiguration && check.Name == "lazyaz.toml" {
+			printConfigPrecedence(out, "Organization", report.Layers, func(layer azuredevops.ConfigLayer) string { return layer.Organization })
+			printConfigPrecedence(out, "Project", report.Layers, func(layer azuredevops.ConfigLayer) string { return layer.Project })
+		}
+	}
+
+	if len(recommendations) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Recommendations:")
+		for _, recommendation := range recommendations {
+			fmt.Fprintln(out, "  - "+recommendation)
+		}
+	}
+}
+
+// printConfigPrecedence lists the value of each layer, by precedence, marking the one in use
+func printConfigPrecedence(out io.Writer, name string, layers []azuredevops.ConfigLayer, value func(azuredevops.ConfigLayer) string) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	used := false
+	for _, layer := range layers {
+		switch {
+		case value(layer) != "" && !used:
+			used = true
+			fmt.Fprintf(w, "  %s\t%s\t%s\t\033[32m✓ in use\033[0m\n", name, layer.Source, value(layer))
+		case value(layer) != "":
+			fmt.Fprintf(w, "  %s\t%s\t%s\toverridden\n", name, layer.Source, value(layer))
+		default:
+			fmt.Fprintf(w, "  %s\t%s\t-\t\n", name, layer.Source)
+		}
+	}
+	if !used {
+		fmt.Fprintf(w, "  %s\tnot set\t\t\033[31mX\033[0m\n", name)
+	}
+	w.Flush()
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const extensionResultOverlay = "extension-result"
+
+// ExtensionCommandResult is the outcome of a command extension
+type ExtensionCommandResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Duration time.Duration
+}
+
+// extensionItem describes the item given to an extension
+type extensionItem struct {
+	Kind  string
+	ID    int
+	Title string
+	URL   string
+}
+
+// describeExtensionItem tells the kind, ID, title and URL of a work item, pull request or pipeline run
+func describeExtensionItem(domain interface{}) (extensionItem, error) {
+	switch v := domain.(type) {
+	case azuredevops.WorkItem:
+		return extensionItem{azuredevops.ItemWorkItem, v.ID, v.Title, v.GetURL(_organization, _project)}, nil
+	case azuredevops.PullRequestDetails:
+		return extensionItem{azuredevops.ItemPullRequest, v.ID, v.Title, v.GetURL()}, nil
+	case azuredevops.PipelineRun:
+		return extensionItem{azuredevops.ItemPipelineRun, v.ID, v.DefinitionName + " " + v.BuildNumber, v.GetWebURL()}, nil
+	default:
+		return extensionItem{}, fmt.Errorf("unsupported domain type: %s", reflect.TypeOf(domain))
+	}
+}
+
+// RunCommand runs the command of the extension in the shell, giving it the item as JSON on stdin
+// and in the LAZYAZ_* environment variables. The command is killed after the timeout of the extension.
+// A command exiting with a non-zero code returns an error along with its output.
+func (e ExtensionConfig) RunCommand(id string, domain interface{}) (ExtensionCommandResult, error) {
+	var result ExtensionCommandResult
+	item, err := describeExtensionItem(domain)
+	if err != nil {
+		return result, err
+	}
+	input, err := json.Marshal(domain)
+	if err != nil {
+		return result, fmt.Errorf("failed to encode the item: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.TimeoutDuration())
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", e.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", e.Command)
+	}
+	cmd.Env = append(os.Environ(),
+		"LAZYAZ_EXTENSION="+id,
+		"LAZYAZ_ITEM_KIND="+item.Kind,
+		"LAZYAZ_ITEM_ID="+strconv.Itoa(item.ID),
+		"LAZYAZ_ITEM_TITLE="+item.Title,
+		"LAZYAZ_ITEM_URL="+item.URL,
+		"LAZYAZ_ORGANIZATION="+_organization,
+		"LAZYAZ_PROJECT="+_project,
+	)
+	// Children of the shell may keep the output open after the shell is killed
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	started := time.Now()
+	err = cmd.Run()
+	result = ExtensionCommandResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+		Duration: time.Since(started),
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result, fmt.Errorf("timed out after %s", e.TimeoutDuration())
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return result, fmt.Errorf("exited with code %d", result.ExitCode)
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to run the command: %v", err)
+	}
+	return result, nil
+}
+
+// RunExtension runs the extension against the item, after confirmation when the extension asks for it.
+// Command and script extensions run in the background, the output of commands is shown in the status bar or the result panel.
+// onDone is called on the UI thread with the error of the extension, if any.
+func RunExtension(extension ExtensionConfig, domain interface{}, focus tview.Primitive, onDone func(err error)) {
+	run := func() {
+		if extension.ID == "export_to_template" {
+			RunExportToTemplate(extension, []interface{}{domain}, focus, onDone)
+			return
+		}
+		if extension.KindOf() == ExtensionKindScript {
+			RunScriptExtension(extension, nil, domain, focus, onDone)
+			return
+		}
+		if extension.KindOf() != ExtensionKindCommand {
+			_, err := extension.EntryPoint(extension.ID).(func(interface{}) (string, error))(domain)
+			announceExtensionResult(extension, err)
+			onDone(err)
+			return
+		}
+		Announce(fmt.Sprintf("⏳ Running %s...", extension.Name), -1)
+		go func() {
+			result, err := extension.RunCommand(extension.ID, domain)
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					logger.Error("Extension command failed", "extension", extension.ID, "error", err, "stderr", result.Stderr)
+				}
+				if extension.Output == ExtensionOutputPanel {
+					ShowExtensionResult(extension, result, err, focus)
+				} else {
+					announceCommandResult(extension, result, err)
+				}
+				onDone(err)
+			})
+		}()
+	}
+	if !extension.Confirm {
+		run()
+		return
+	}
+	item, err := describeExtensionItem(domain)
+	if err != nil {
+		announceExtensionResult(extension, err)
+		onDone(err)
+		return
+	}
+	ShowConfirm(fmt.Sprintf("Run %s on %d %s?", extension.Name, item.ID, item.Title), focus, run)
+}
+
+// RunExtensionOnItems runs the extension against the items, like RunExtension for a single item.
+// Several items are run one after the other in the background, after a single confirmation.
+func RunExtensionOnItems(extension ExtensionConfig, items []interface{}, focus tview.Primitive, onDone func(err error)) {
+	if len(items) == 1 {
+		RunExtension(extension, items[0], focus, onDone)
+		return
+	}
+	if extension.ID == "export_to_template" {
+		// The items are exported together, after picking the template
+		RunExportToTemplate(extension, items, focus, onDone)
+		return
+	}
+	runOnItems(extension, items, extension.Confirm, focus, onDone, func(domain interface{}) (ExtensionCommandResult, error) {
+		switch extension.KindOf() {
+		case ExtensionKindCommand:
+			return extension.RunCommand(extension.ID, domain)
+		case ExtensionKindScript:
+			script, err := LoadScriptExtension(extension.ID, extension)
+			if err == nil {
+				_, err = script.Call(nil, domain, focus)
+			}
+			return ExtensionCommandResult{}, err
+		default:
+			_, err := extension.EntryPoint(extension.ID).(func(interface{}) (string, error))(domain)
+			return ExtensionCommandResult{}, err
+		}
+	})
+}
+
+// runOnItems runs the extension against each of the items in the background. The outputs of a command
+// extension are shown together in the result panel, otherwise the number of failed items is told in the status bar.
+func runOnItems(extension ExtensionConfig, items []interface{}, confirm bool, focus tview.Primitive, onDone func(err error), run func(domain interface{}) (ExtensionCommandResult, error)) {
+	start := func() {
+		Announce(fmt.Sprintf("⏳ Running %s on %d items...", extension.Name, len(items)), -1)
+		go func() {
+			var combined ExtensionCommandResult
+			var stdout, stderr strings.Builder
+			var firstErr error
+			failures := 0
+			started := time.Now()
+			for _, domain := range items {
+				result, err := run(domain)
+				item, _ := describeExtensionItem(domain)
+				fmt.Fprintf(&stdout, "── %d %s ──\n%s\n", item.ID, item.Title, strings.TrimRight(result.Stdout, "\n"))
+				stderr.WriteString(result.Stderr)
+				if err != nil {
+					logger.Error("Extension failed", "extension", extension.ID, "item", item.ID, "error", err, "stderr", result.Stderr)
+					failures++
+					combined.ExitCode = result.ExitCode
+					if firstErr == nil {
+						firstErr = fmt.Errorf("%d: %v", item.ID, err)
+					}
+				}
+			}
+			combined.Stdout, combined.Stderr, combined.Duration = stdout.String(), stderr.String(), time.Since(started)
+
+			app.QueueUpdateDraw(func() {
+				var err error
+				if failures > 0 {
+					err = fmt.Errorf("failed on %d of %d items, %v", failures, len(items), firstErr)
+				}
+				switch {
+				case extension.KindOf() == ExtensionKindCommand && extension.Output == ExtensionOutputPanel:
+					ShowExtensionResult(extension, combined, err, focus)
+				case err != nil:
+					AnnounceError(tview.Escape(fmt.Sprintf("❌ %s %s", extension.Name, firstLine(err.Error()))))
+				default:
+					Announce(fmt.Sprintf("✅ [green]%s done on %d items[white]", extension.Name, len(items)), 0)
+				}
+				onDone(err)
+			})
+		}()
+	}
+	if !confirm {
+		start()
+		return
+	}
+	ShowConfirm(fmt.Sprintf("Run %s on %d items?", extension.Name, len(items)), focus, start)
+}
+
+// announceExtensionResult tells the outcome of a builtin extension in the status bar
+func announceExtensionResult(extension ExtensionConfig, err error) {
+	switch {
+	case err == nil:
+		Announce(fmt.Sprintf("✅ [green]%s done[white]", extension.Name), 0)
+	case errors.Is(err, ErrNoClipboard):
+		AnnounceError(fmt.Sprintf("❌ %s failed: no clipboard utility found (install xsel or xclip)", extension.Name))
+	default:
+		AnnounceError(fmt.Sprintf("❌ %s failed", extension.Name))
+	}
+}
+
+// announceCommandResult tells the first line of the output of a command extension in the status bar,
+// or the first line of its errors when it failed
+func announceCommandResult(extension ExtensionConfig, result ExtensionCommandResult, err error) {
+	if err != nil {
+		message := fmt.Sprintf("❌ %s %v", extension.Name, err)
+		if line := firstLine(result.Stderr); line != "" {
+			message += ": " + line
+		}
+		AnnounceError(tview.Escape(message))
+		return
+	}
+	if line := firstLine(result.Stdout); line != "" {
+		Announce(fmt.Sprintf("✅ [green]%s:[white] %s", extension.Name, tview.Escape(line)), 0)
+		return
+	}
+	Announce(fmt.Sprintf("✅ [green]%s done[white]", extension.Name), 0)
+}
+
+// firstLine returns the first line of the text that is not blank
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// ShowExtensionResult shows the output, the errors and the exit code of a command extension
+func ShowExtensionResult(extension ExtensionConfig, result ExtensionCommandResult, err error, focus tview.Primitive) {
+	var content strings.Builder
+	content.WriteString(tview.Escape(result.Stdout))
+	if result.Stderr != "" {
+		fmt.Fprintf(&content, "\n[red]%s[white]", tview.Escape(result.Stderr))
+	}
+	if err != nil {
+		fmt.Fprintf(&content, "\n[red]%s %s[white]", extension.Name, tview.Escape(err.Error()))
+	}
+	textView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetText(content.String())
+	textView.SetBorder(true).
+		SetTitle(fmt.Sprintf(" %s (exit %d, %s) ", extension.Name, result.ExitCode, result.Duration.Round(time.Millisecond)))
+	if err != nil {
+		textView.SetBorderColor(tcell.ColorRed)
+	}
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]↑/↓[white] scroll  [yellow]q[white] close")
+
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(textView, 0, 1, true).
+		AddItem(statusBar, 1, 0, false)
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+			CloseOverlay(extensionResultOverlay, focus)
+			return nil
+		}
+		return event
+	})
+
+	Overlays.AddPage(extensionResultOverlay, layout, true, true)
+	app.SetFocus(textView)
+}
@@ -1,15 +1,12 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
 	"log"
-	"os"
 	"os/exec"
 	"reflect"
 	"runtime"
 	"strings"
-	"text/template"
 
 	"github.com/aldnav/lazyaz/pkg/azuredevops"
 )
@@ -42,97 +39,45 @@ const DEFAULT_PIPELINERUN_TEMPLATE = `
 // ErrNoClipboard is returned when no clipboard utility is available
 var ErrNoClipboard = fmt.Errorf("no clipboard utility found (install xsel or xclip)")
 
-// ExportToTemplate exports the given domain object to a template
-// It accepts WorkItem, PullRequestDetails, or PipelineRun
-func ExportToTemplate(domain interface{}) (string, error) {
-	var tmpl string
-	var d interface{}
-
-	switch v := domain.(type) {
-	case azuredevops.WorkItem:
-		var err error
-		tmpl, err = loadTemplate("workitem")
-		if err != nil {
-			return "NOK", err
-		}
-		d = v
-	case azuredevops.PullRequestDetails:
-		var err error
-		tmpl, err = loadTemplate("pullrequest")
-		if err != nil {
-			return "NOK", err
-		}
-		d = v
-	case azuredevops.PipelineRun:
-		var err error
-		tmpl, err = loadTemplate("pipelinerun")
-		if err != nil {
-			return "NOK", err
-		}
-		d = v
-	default:
-		err := fmt.Errorf("unsupported domain type: %s", reflect.TypeOf(domain))
-		log.Printf("%v", err)
-		return "NOK", err
-	}
-
-	applied, applyErr := applyTemplate(tmpl, d)
-	if applyErr != nil {
-		log.Printf("Failed to apply template: %s", applyErr)
-		return "NOK", applyErr
-	}
-	// Copy the rendered template to clipboard
-	err := copyToClipboard(applied)
-	if err != nil {
-		log.Printf("Failed to copy to clipboard: %s", err)
-		return "NOK", err
-	}
-
-	// fmt.Printf("Parameter passed: %+v\n", domain)
-	return "OK", nil
+// Built-in templates by item kind, used when the templates directory has none
+var defaultTemplates = map[string]string{
+	azuredevops.ItemWorkItem:    DEFAULT_WORKITEM_TEMPLATE,
+	azuredevops.ItemPullRequest: DEFAULT_PULLREQUEST_TEMPLATE,
+	azuredevops.ItemPipelineRun: DEFAULT_PIPELINERUN_TEMPLATE,
 }
 
-func loadTemplate(domain string) (string, error) {
-	tmpl := ""
-	switch domain {
-	case "workitem":
-		tmpl = "workitem.md"
-	case "pullrequest":
-		tmpl = "pullrequest.md"
-	case "pipelinerun":
-		tmpl = "pipelinerun.md"
-	}
-	if tmpl == "" {
-		return "", fmt.Errorf("unsupported domain type: %s", domain)
-	}
-	// Read from home / .lazyaz/templates/
-	content, err := os.ReadFile(fmt.Sprintf("%s/.lazyaz/templates/%s", os.Getenv("HOME"), tmpl))
-	if err != nil {
-		defaultTemplate := ""
-		switch domain {
-		case "workitem":
-			defaultTemplate = DEFAULT_WORKITEM_TEMPLATE
-		case "pullrequest":
-			defaultTemplate = DEFAULT_PULLREQUEST_TEMPLATE
-		case "pipelinerun":
-			defaultTemplate = DEFAULT_PIPELINERUN_TEMPLATE
+// exportExtension returns the configuration of the export_to_template extension
+func exportExtension(config *AppConfig) ExtensionConfig {
+	extension := ExtensionConfig{Name: "Export to Template"}
+	if config != nil {
+		if configured, ok := config.Extensions["export_to_template"]; ok {
+			extension = configured
 		}
-		return defaultTemplate, nil
 	}
-	return string(content), nil
+	extension.ID = "export_to_template"
+	return extension
 }
 
-func applyTemplate(thetemplate string, domain interface{}) (string, error) {
-	tmpl, err := template.New("template").Parse(thetemplate)
+// ExportToTemplate exports the given domain object with the first template of its kind, to the output
+// of the export_to_template extension. It accepts WorkItem, PullRequestDetails, or PipelineRun
+func ExportToTemplate(domain interface{}) (string, error) {
+	item, err := describeExtensionItem(domain)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %s", err)
+		log.Printf("%v", err)
+		return "NOK", err
 	}
-	var rendered bytes.Buffer
-	err = tmpl.Execute(&rendered, domain)
+	extension := exportExtension(AppSettings)
+	templates, err := LoadTemplates(extension.TemplatesDir(), item.Kind)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute template: %s", err)
+		log.Printf("Failed to load templates: %s", err)
+		return "NOK", err
 	}
-	return rendered.String(), nil
+	output, file := extension.TemplateOutput(templates[0])
+	if _, _, err := ExportItems(templates[0], []interface{}{domain}, output, file); err != nil {
+		log.Printf("Failed to export: %s", err)
+		return "NOK", err
+	}
+	return "OK", nil
 }
 
 // copyToClipboard copies the given text to the clipboard
@@ -183,6 +128,20 @@ func CheckClipboardUtility() bool {
 	}
 }
 
+// browserCommand is the command opening URLs in the browser
+func browserCommand() string {
+	if runtime.GOOS == "windows" {
+		return "explorer"
+	}
+	return "open"
+}
+
+// CheckBrowserUtility checks if the command opening URLs in the browser is available
+func CheckBrowserUtility() bool {
+	_, err := exec.LookPath(browserCommand())
+	return err == nil
+}
+
 // Open in browser
 func OpenInBrowser(domain interface{}) (string, error) {
 	url := ""
@@ -200,13 +159,7 @@ func OpenInBrowser(domain interface{}) (string, error) {
 		return "NOK", fmt.Errorf("no URL found for domain type: %s", reflect.TypeOf(domain))
 	}
 
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("explorer", url)
-	} else {
-		cmd = exec.Command("open", url)
-	}
-
+	cmd := exec.Command(browserCommand(), url)
 	if err := cmd.Run(); err != nil {
 		return "NOK", fmt.Errorf("failed to open URL: %v", err)
 	}
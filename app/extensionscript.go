@@ -0,0 +1,599 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+const scriptPromptOverlay = "script-prompt"
+
+// Limits of the HTTP requests of the scripts
+const (
+	scriptHTTPTimeout  = 15 * time.Second
+	scriptHTTPMaxBytes = 10 << 20
+)
+
+// Statements allowed at the top level of the scripts, beyond the Starlark defaults
+var scriptFileOptions = &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true}
+
+// ScriptExtension is a loaded Starlark script: its global functions and the keys it bound
+type ScriptExtension struct {
+	ID        string
+	Extension ExtensionConfig
+	globals   starlark.StringDict
+	bindings  []ExtensionKeyBinding
+}
+
+// ExtensionKeyBinding is a key of an extension, working from the tables of the pages the extension applies to
+type ExtensionKeyBinding struct {
+	Key         keyBinding
+	Description string
+	Extension   ExtensionConfig
+	// Function of the script called with the item, the extension runs when there is none
+	Function starlark.Callable
+}
+
+var (
+	scriptExtensionsMu sync.Mutex
+	scriptExtensions   = map[string]*ScriptExtension{}
+)
+
+// expandHome replaces a leading ~ of the path with the home directory
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~"); ok && (rest == "" || rest[0] == '/' || rest[0] == filepath.Separator) {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// resolveConfigPath resolves a path of lazyaz.toml, ~ being the home directory and relative paths
+// being relative to the directory of lazyaz.toml
+func resolveConfigPath(path string) string {
+	path = expandHome(path)
+	if !filepath.IsAbs(path) && ConfigPath != "" {
+		path = filepath.Join(filepath.Dir(ConfigPath), path)
+	}
+	return filepath.Clean(path)
+}
+
+// LoadScriptExtension runs the script of the extension once, keeping its functions and the keys it binds
+func LoadScriptExtension(id string, extension ExtensionConfig) (*ScriptExtension, error) {
+	scriptExtensionsMu.Lock()
+	defer scriptExtensionsMu.Unlock()
+	if script, ok := scriptExtensions[id]; ok {
+		return script, nil
+	}
+
+	extension.ID = id
+	path := resolveConfigPath(extension.Script)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the script: %v", err)
+	}
+	script := &ScriptExtension{ID: id, Extension: extension}
+	run := script.newRun(nil, true)
+	defer run.timer.Stop()
+	globals, err := starlark.ExecFileOptions(scriptFileOptions, run.thread, path, src, script.predeclared())
+	if err != nil {
+		return nil, scriptError(err)
+	}
+	script.globals = globals
+	if _, ok := globals["run"].(starlark.Callable); !ok && len(script.bindings) == 0 {
+		return nil, fmt.Errorf("the script defines neither a run function nor keys")
+	}
+	scriptExtensions[id] = script
+	return script, nil
+}
+
+// scriptError adds the backtrace of the script to the error
+func scriptError(err error) error {
+	if evalErr, ok := err.(*starlark.EvalError); ok {
+		return fmt.Errorf("%s", evalErr.Backtrace())
+	}
+	return err
+}
+
+// Call calls the function of the script with the item, the run function when none is given.
+// Returns whether the script announced anything.
+func (s *ScriptExtension) Call(function starlark.Callable, domain interface{}, focus tview.Primitive) (bool, error) {
+	if function == nil {
+		run, ok := s.globals["run"].(starlark.Callable)
+		if !ok {
+			return false, fmt.Errorf("the script has no run function")
+		}
+		function = run
+	}
+	item, err := newScriptItem(domain)
+	if err != nil {
+		return false, err
+	}
+	run := s.newRun(focus, false)
+	defer run.timer.Stop()
+	if _, err := starlark.Call(run.thread, function, starlark.Tuple{item}, nil); err != nil {
+		return run.announced, scriptError(err)
+	}
+	return run.announced, nil
+}
+
+// RunScriptExtension calls the function of the script in the background, the run function when none is given.
+// onDone is called on the UI thread with the error of the script, if any.
+func RunScriptExtension(extension ExtensionConfig, function starlark.Callable, domain interface{}, focus tview.Primitive, onDone func(err error)) {
+	Announce(fmt.Sprintf("⏳ Running %s...", extension.Name), -1)
+	go func() {
+		announced := false
+		script, err := LoadScriptExtension(extension.ID, extension)
+		if err == nil {
+			announced, err = script.Call(function, domain, focus)
+		}
+		app.QueueUpdateDraw(func() {
+			switch {
+			case err != nil:
+				logger.Error("Extension script failed", "extension", extension.ID, "error", err)
+				AnnounceError(tview.Escape(fmt.Sprintf("❌ %s failed: %s", extension.Name, firstLine(err.Error()))))
+			case !announced:
+				Announce(fmt.Sprintf("✅ [green]%s done[white]", extension.Name), 0)
+			}
+			onDone(err)
+		})
+	}()
+}
+
+// scriptRun is the thread of a call of the script. Its timeout is paused while the user answers a prompt.
+type scriptRun struct {
+	script    *ScriptExtension
+	focus     tview.Primitive
+	thread    *starlark.Thread
+	loading   bool
+	announced bool
+	timer     *time.Timer
+	started   time.Time
+	remaining time.Duration
+}
+
+func (s *ScriptExtension) newRun(focus tview.Primitive, loading bool) *scriptRun {
+	timeout := s.Extension.TimeoutDuration()
+	run := &scriptRun{script: s, focus: focus, loading: loading, started: time.Now(), remaining: timeout}
+	run.thread = &starlark.Thread{
+		Name: s.ID,
+		Print: func(_ *starlark.Thread, message string) {
+			logger.Info("Extension script", "extension", s.ID, "message", message)
+		},
+	}
+	run.thread.SetLocal("run", run)
+	run.timer = time.AfterFunc(timeout, func() {
+		run.thread.Cancel(fmt.Sprintf("timed out after %s", timeout))
+	})
+	return run
+}
+
+func (r *scriptRun) pause() {
+	if r.timer.Stop() {
+		r.remaining -= time.Since(r.started)
+	}
+}
+
+func (r *scriptRun) resume() {
+	r.started = time.Now()
+	r.timer.Reset(r.remaining)
+}
+
+// predeclared are the globals of the script: the lazyaz module, json and struct
+func (s *ScriptExtension) predeclared() starlark.StringDict {
+	builtins := map[string]func(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error){
+		"announce":         scriptAnnounce,
+		"announce_error":   scriptAnnounceError,
+		"prompt":           scriptPrompt,
+		"confirm":          scriptConfirm,
+		"bind":             scriptBind,
+		"get_work_item":    scriptGetWorkItem,
+		"get_pull_request": scriptGetPullRequest,
+		"get_pipeline_run": scriptGetPipelineRun,
+		"update_work_item": scriptUpdateWorkItem,
+		"add_comment":      scriptAddComment,
+		"copy":             scriptCopy,
+		"open_url":         scriptOpenURL,
+		"read_file":        scriptReadFile,
+		"write_file":       scriptWriteFile,
+		"http_get":         scriptHTTPGet,
+		"http_post":        scriptHTTPPost,
+	}
+	members := starlark.StringDict{
+		"organization": starlark.String(_organization),
+		"project":      starlark.String(_project),
+	}
+	for name, builtin := range builtins {
+		members[name] = starlark.NewBuiltin(name, func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			return builtin(thread.Local("run").(*scriptRun), args, kwargs)
+		})
+	}
+	return starlark.StringDict{
+		"lazyaz": &starlarkstruct.Module{Name: "lazyaz", Members: members},
+		"json":   json.Module,
+		"struct": starlark.NewBuiltin("struct", starlarkstruct.Make),
+	}
+}
+
+func scriptAnnounce(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var message string
+	if err := starlark.UnpackArgs("announce", args, kwargs, "message", &message); err != nil {
+		return nil, err
+	}
+	if run.loading {
+		return nil, fmt.Errorf("announce: messages cannot be shown while the script loads")
+	}
+	run.announced = true
+	app.QueueUpdateDraw(func() { Announce(message, 0) })
+	return starlark.None, nil
+}
+
+func scriptAnnounceError(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var message string
+	if err := starlark.UnpackArgs("announce_error", args, kwargs, "message", &message); err != nil {
+		return nil, err
+	}
+	if run.loading {
+		return nil, fmt.Errorf("announce_error: messages cannot be shown while the script loads")
+	}
+	run.announced = true
+	app.QueueUpdateDraw(func() { AnnounceError(message) })
+	return starlark.None, nil
+}
+
+// ask shows a dialog and waits for the answer of the user, pausing the timeout of the script
+func (r *scriptRun) ask(name string, show func(done func(answer starlark.Value))) (starlark.Value, error) {
+	if r.loading {
+		return nil, fmt.Errorf("%s: dialogs cannot be shown while the script loads", name)
+	}
+	r.pause()
+	defer r.resume()
+	answers := make(chan starlark.Value, 1)
+	app.QueueUpdateDraw(func() {
+		show(func(answer starlark.Value) { answers <- answer })
+	})
+	return <-answers, nil
+}
+
+func scriptPrompt(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var message, value string
+	if err := starlark.UnpackArgs("prompt", args, kwargs, "message", &message, "default?", &value); err != nil {
+		return nil, err
+	}
+	return run.ask("prompt", func(done func(answer starlark.Value)) {
+		input := tview.NewInputField().
+			SetLabel(message + " ").
+			SetText(value)
+		form := tview.NewForm().
+			SetFieldBackgroundColor(tcell.ColorBlack).
+			SetButtonBackgroundColor(tcell.ColorWhite).
+			SetButtonTextColor(tcell.ColorBlack)
+		submit := func() {
+			CloseOverlay(scriptPromptOverlay, run.focus)
+			done(starlark.String(input.GetText()))
+		}
+		cancel := func() {
+			CloseOverlay(scriptPromptOverlay, run.focus)
+			done(starlark.None)
+		}
+		input.SetDoneFunc(func(key tcell.Key) {
+			if key == tcell.KeyEnter {
+				submit()
+			}
+		})
+		form.AddFormItem(input).
+			AddButton("OK", submit).
+			AddButton("Cancel", cancel).
+			SetCancelFunc(cancel)
+		form.SetBorder(true).
+			SetTitle(fmt.Sprintf(" %s ", run.script.Extension.Name))
+		ShowOverlay(scriptPromptOverlay, form, 70, 7)
+	})
+}
+
+func scriptConfirm(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var message string
+	if err := starlark.UnpackArgs("confirm", args, kwargs, "message", &message); err != nil {
+		return nil, err
+	}
+	return run.ask("confirm", func(done func(answer starlark.Value)) {
+		modal := tview.NewModal().
+			SetText(message).
+			AddButtons([]string{"Cancel", "Confirm"}).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				CloseOverlay(scriptPromptOverlay, run.focus)
+				done(starlark.Bool(buttonLabel == "Confirm"))
+			})
+		Overlays.AddPage(scriptPromptOverlay, modal, true, true)
+		app.SetFocus(modal)
+	})
+}
+
+func scriptBind(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key, description string
+	var function starlark.Callable
+	if err := starlark.UnpackArgs("bind", args, kwargs, "key", &key, "function", &function, "description?", &description); err != nil {
+		return nil, err
+	}
+	if !run.loading {
+		return nil, fmt.Errorf("bind: keys are bound while the script loads")
+	}
+	binding, err := parseKeyBinding(key)
+	if err != nil {
+		return nil, fmt.Errorf("bind: %v", err)
+	}
+	if page, conflict := binding.ConflictsWith(run.script.Extension.AppliesTo); conflict {
+		return nil, fmt.Errorf("bind: key %q is already used on %s", key, page)
+	}
+	if description == "" {
+		description = run.script.Extension.Name
+	}
+	run.script.bindings = append(run.script.bindings, ExtensionKeyBinding{
+		Key:         binding,
+		Description: description,
+		Extension:   run.script.Extension,
+		Function:    function,
+	})
+	return starlark.None, nil
+}
+
+// scriptItemValue converts the item fetched by the client to a script value
+func scriptItemValue[T any](item *T, err error) (starlark.Value, error) {
+	if err != nil {
+		return nil, err
+	}
+	return newScriptItem(*item)
+}
+
+func scriptGetWorkItem(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id int
+	var details bool
+	if err := starlark.UnpackArgs("get_work_item", args, kwargs, "id", &id, "details?", &details); err != nil {
+		return nil, err
+	}
+	workItem, err := client.GetWorkItem(id)
+	if err == nil && details {
+		_, err = workItem.GetMoreWorkItemDetails()
+	}
+	return scriptItemValue(workItem, err)
+}
+
+func scriptGetPullRequest(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id int
+	if err := starlark.UnpackArgs("get_pull_request", args, kwargs, "id", &id); err != nil {
+		return nil, err
+	}
+	return scriptItemValue(client.GetPRDetails(strconv.Itoa(id)))
+}
+
+func scriptGetPipelineRun(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id int
+	if err := starlark.UnpackArgs("get_pipeline_run", args, kwargs, "id", &id); err != nil {
+		return nil, err
+	}
+	return scriptItemValue(client.GetPipelineRun(id))
+}
+
+// allowUpdates checks that the extension may change items
+func (r *scriptRun) allowUpdates(name string) error {
+	if !r.script.Extension.AllowUpdates {
+		return fmt.Errorf("%s: the extension is not allowed to update items, see allow_updates", name)
+	}
+	return nil
+}
+
+func scriptUpdateWorkItem(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id int
+	var fields *starlark.Dict
+	if err := starlark.UnpackArgs("update_work_item", args, kwargs, "id", &id, "fields", &fields); err != nil {
+		return nil, err
+	}
+	if err := run.allowUpdates("update_work_item"); err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for _, entry := range fields.Items() {
+		name, ok := starlark.AsString(entry[0])
+		if !ok {
+			return nil, fmt.Errorf("update_work_item: field names are strings, got %s", entry[0].Type())
+		}
+		value, ok := starlark.AsString(entry[1])
+		if !ok {
+			value = entry[1].String()
+		}
+		values[name] = value
+	}
+	return scriptItemValue(client.UpdateWorkItem(id, values))
+}
+
+func scriptAddComment(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var item starlark.Value
+	var text string
+	if err := starlark.UnpackArgs("add_comment", args, kwargs, "item", &item, "text", &text); err != nil {
+		return nil, err
+	}
+	if err := run.allowUpdates("add_comment"); err != nil {
+		return nil, err
+	}
+	scriptItem, ok := item.(*scriptItem)
+	if !ok {
+		return nil, fmt.Errorf("add_comment: expected a work item or a pull request, got %s", item.Type())
+	}
+	var err error
+	switch v := scriptItem.value.(type) {
+	case azuredevops.WorkItem:
+		err = client.AddWorkItemComment(v.ID, text)
+	case azuredevops.PullRequestDetails:
+		err = client.AddPullRequestComment(&v, text)
+	default:
+		err = fmt.Errorf("add_comment: comments are added to work items and pull requests, not %s", scriptItem.kind)
+	}
+	return starlark.None, err
+}
+
+// scriptCopy copies the text to the clipboard, which stays on the machine and is not sandboxed
+func scriptCopy(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var text string
+	if err := starlark.UnpackArgs("copy", args, kwargs, "text", &text); err != nil {
+		return nil, err
+	}
+	return starlark.None, copyToClipboard(text)
+}
+
+func scriptOpenURL(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rawURL string
+	if err := starlark.UnpackArgs("open_url", args, kwargs, "url", &rawURL); err != nil {
+		return nil, err
+	}
+	// The browser sends the URL out as well, so it is limited to the hosts of allow_network
+	if err := allowURL("open_url", rawURL, run.script.Extension.AllowNetwork); err != nil {
+		return nil, err
+	}
+	return starlark.None, exec.Command(browserCommand(), rawURL).Run()
+}
+
+// resolvePath makes the path absolute, following its symbolic links, or those of its directory for a new file
+func resolvePath(path string) string {
+	path, _ = filepath.Abs(resolveConfigPath(path))
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		return filepath.Join(dir, filepath.Base(path))
+	}
+	return path
+}
+
+// allowPath resolves the path, which must be in one of the allowed directories
+func allowPath(name string, path string, allowed []string, setting string) (string, error) {
+	resolved := resolvePath(path)
+	for _, dir := range allowed {
+		rel, err := filepath.Rel(resolvePath(dir), resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%s: access to %s is not allowed, see %s", name, path, setting)
+}
+
+func scriptReadFile(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	if err := starlark.UnpackArgs("read_file", args, kwargs, "path", &path); err != nil {
+		return nil, err
+	}
+	extension := run.script.Extension
+	resolved, err := allowPath("read_file", path, append(slices.Clone(extension.AllowRead), extension.AllowWrite...), "allow_read")
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("read_file: %v", err)
+	}
+	return starlark.String(content), nil
+}
+
+func scriptWriteFile(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path, content string
+	if err := starlark.UnpackArgs("write_file", args, kwargs, "path", &path, "content", &content); err != nil {
+		return nil, err
+	}
+	resolved, err := allowPath("write_file", path, run.script.Extension.AllowWrite, "allow_write")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(resolved, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("write_file: %v", err)
+	}
+	return starlark.None, nil
+}
+
+// allowURL checks that the host of the URL is one of the allowed hosts, e.g. "hooks.slack.com" or "*.example.com"
+func allowURL(name string, rawURL string, allowed []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("%s: expected an http or https URL, got %q", name, rawURL)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if host == pattern || (strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: access to %s is not allowed, see allow_network", name, host)
+}
+
+// httpRequest sends the request to an allowed host, returning a struct of the status code and the body
+func (r *scriptRun) httpRequest(name, method, rawURL, body string, headers *starlark.Dict) (starlark.Value, error) {
+	allowed := r.script.Extension.AllowNetwork
+	if err := allowURL(name, rawURL, allowed); err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(method, rawURL, strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if headers != nil {
+		for _, entry := range headers.Items() {
+			key, _ := starlark.AsString(entry[0])
+			value, _ := starlark.AsString(entry[1])
+			request.Header.Set(key, value)
+		}
+	}
+	httpClient := &http.Client{
+		Timeout: scriptHTTPTimeout,
+		CheckRedirect: func(redirect *http.Request, via []*http.Request) error {
+			return allowURL(name, redirect.URL.String(), allowed)
+		},
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(response.Body, scriptHTTPMaxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"status": starlark.MakeInt(response.StatusCode),
+		"body":   starlark.String(content),
+	}), nil
+}
+
+func scriptHTTPGet(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rawURL string
+	var headers *starlark.Dict
+	if err := starlark.UnpackArgs("http_get", args, kwargs, "url", &rawURL, "headers?", &headers); err != nil {
+		return nil, err
+	}
+	return run.httpRequest("http_get", http.MethodGet, rawURL, "", headers)
+}
+
+func scriptHTTPPost(run *scriptRun, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rawURL, body string
+	var headers *starlark.Dict
+	if err := starlark.UnpackArgs("http_post", args, kwargs, "url", &rawURL, "body", &body, "headers?", &headers); err != nil {
+		return nil, err
+	}
+	return run.httpRequest("http_post", http.MethodPost, rawURL, body, headers)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAllowPath(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := filepath.Join(root, "allowed")
+	for _, dir := range []string{allowed, filepath.Join(root, "allowed2"), filepath.Join(root, "outside")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "outside", "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "outside", "secret.txt"), filepath.Join(allowed, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "outside"), filepath.Join(allowed, "linkdir")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"file", filepath.Join(allowed, "notes.txt"), filepath.Join(allowed, "notes.txt")},
+		{"nested file", filepath.Join(allowed, "a", "..", "notes.txt"), filepath.Join(allowed, "notes.txt")},
+		{"directory itself", allowed, allowed},
+		{"parent escape", filepath.Join(allowed, "..", "outside", "secret.txt"), ""},
+		{"sibling sharing the prefix", filepath.Join(root, "allowed2", "notes.txt"), ""},
+		{"symbolic link to a file outside", filepath.Join(allowed, "link.txt"), ""},
+		{"new file in a symbolic link to a directory outside", filepath.Join(allowed, "linkdir", "new.txt"), ""},
+		{"parent directory", root, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := allowPath("read_file", tt.path, []string{allowed}, "allow_read")
+			if tt.expected == "" {
+				if err == nil || !strings.Contains(err.Error(), "is not allowed") {
+					t.Errorf("Expected %s not to be allowed, got %q, %v", tt.path, resolved, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected %s to be allowed, got %v", tt.path, err)
+			}
+			if resolved != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, resolved)
+			}
+		})
+	}
+}
+
+func TestAllowURL(t *testing.T) {
+	allowed := []string{"hooks.slack.com", "*.example.com"}
+	tests := []struct {
+		url         string
+		expectedErr string
+	}{
+		{"https://hooks.slack.com/services/x", ""},
+		{"http://HOOKS.slack.com/services/x", ""},
+		{"https://api.example.com/v1", ""},
+		{"https://a.b.example.com:8443/v1", ""},
+		{"https://example.com/v1", "is not allowed"},
+		{"https://evilexample.com/v1", "is not allowed"},
+		{"https://example.com.evil.net/v1", "is not allowed"},
+		{"https://slack.com/", "is not allowed"},
+		{"https://hooks.slack.com@evil.net/", "is not allowed"},
+		{"ftp://hooks.slack.com/file", "expected an http or https URL"},
+		{"file:///etc/passwd", "expected an http or https URL"},
+		{"javascript:alert(1)", "expected an http or https URL"},
+		{"hooks.slack.com/services/x", "expected an http or https URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := allowURL("http_get", tt.url, allowed)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Expected %s to be allowed, got %v", tt.url, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestHTTPRequestRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("reached"))
+	}))
+	defer target.Close()
+	// The target is reached as localhost, the redirecting server as 127.0.0.1
+	targetURL := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/local" {
+			w.Write([]byte("local"))
+			return
+		}
+		http.Redirect(w, r, targetURL+"/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		allowed     []string
+		path        string
+		expectedErr bool
+	}{
+		{"allowed host", []string{"127.0.0.1"}, "/local", false},
+		{"redirect to a host not allowed", []string{"127.0.0.1"}, "/redirect", true},
+		{"redirect to an allowed host", []string{"127.0.0.1", "localhost"}, "/redirect", false},
+		{"host not allowed", []string{"localhost"}, "/local", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &scriptRun{script: &ScriptExtension{Extension: ExtensionConfig{AllowNetwork: tt.allowed}}}
+			_, err := run.httpRequest("http_get", http.MethodGet, server.URL+tt.path, "", nil)
+			if tt.expectedErr {
+				if err == nil || !strings.Contains(err.Error(), "is not allowed") {
+					t.Errorf("Expected the request not to be allowed, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+)
+
+// ErrNotInRepository is returned when lazyaz is not running inside a git repository
+var ErrNotInRepository = fmt.Errorf("not inside a git repository")
+
+// ErrDirtyWorkingTree is returned when the local repository has uncommitted changes
+var ErrDirtyWorkingTree = fmt.Errorf("working tree has uncommitted changes, commit or stash them first")
+
+// runGitCommand executes a git command in the current directory and returns the trimmed output
+func runGitCommand(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// findRemoteForRepository returns the name of the git remote pointing to the given Azure Repos URL
+func findRemoteForRepository(repositoryURL string) (string, error) {
+	if _, err := runGitCommand("rev-parse", "--show-toplevel"); err != nil {
+		return "", ErrNotInRepository
+	}
+	wanted := azuredevops.RepositoryIdentity(repositoryURL)
+	if wanted == "" {
+		return "", fmt.Errorf("unrecognized repository URL: %s", repositoryURL)
+	}
+
+	output, err := runGitCommand("remote", "-v")
+	if err != nil {
+		return "", err
+	}
+	// Each line looks like: origin	https://dev.azure.com/org/project/_git/repo (fetch)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if azuredevops.RepositoryIdentity(fields[1]) == wanted {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no remote in this repository points to %s", repositoryURL)
+}
+
+// isWorkingTreeDirty reports whether tracked files have uncommitted changes
+func isWorkingTreeDirty() (bool, error) {
+	output, err := runGitCommand("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, err
+	}
+	return output != "", nil
+}
+
+// CheckoutPullRequest fetches and checks out the pull request in the local repository.
+// By default the source branch is checked out as a local tracking branch. When useMergeRef is set,
+// the merge commit Azure DevOps builds for the PR is checked out as a detached HEAD instead.
+func CheckoutPullRequest(pr azuredevops.PullRequestDetails, useMergeRef bool) (string, error) {
+	repositoryURL := pr.RepositoryURL
+	if repositoryURL == "" {
+		repositoryURL = fmt.Sprintf("%s%s/_git/%s", _organization, pr.Project, pr.Repository)
+	}
+	remote, err := findRemoteForRepository(repositoryURL)
+	if err != nil {
+		return "", err
+	}
+
+	dirty, err := isWorkingTreeDirty()
+	if err != nil {
+		return "", err
+	}
+	if dirty {
+		return "", ErrDirtyWorkingTree
+	}
+
+	if useMergeRef {
+		if _, err := runGitCommand("fetch", remote, pr.GetMergeRefName()); err != nil {
+			return "", err
+		}
+		if _, err := runGitCommand("checkout", "--detach", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Checked out merge of PR %d (detached)", pr.ID), nil
+	}
+
+	branch := pr.GetShortBranchName()
+	remoteBranch := remote + "/" + branch
+	if _, err := runGitCommand("fetch", remote, fmt.Sprintf("+%s:refs/remotes/%s", pr.SourceRefName, remoteBranch)); err != nil {
+		return "", err
+	}
+
+	if _, err := runGitCommand("rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+		// No local branch yet, create one tracking the remote
+		if _, err := runGitCommand("checkout", "-b", branch, "--track", remoteBranch); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Checked out %s", branch), nil
+	}
+
+	// Local branch exists, only move it forward so local commits are never lost
+	if _, err := runGitCommand("checkout", branch); err != nil {
+		return "", err
+	}
+	if _, err := runGitCommand("merge", "--ff-only", remoteBranch); err != nil {
+		return "", fmt.Errorf("checked out %s but it has diverged from %s", branch, remoteBranch)
+	}
+	return fmt.Sprintf("Checked out %s (up to date with %s)", branch, remoteBranch), nil
+}
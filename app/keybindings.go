@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// keyBinding is a key of the configuration or of an extension, e.g. "o", "ctrl+o", "alt+o" or "f5"
+type keyBinding struct {
+	Name string
+	key  tcell.Key
+	r    rune
+	alt  bool
+}
+
+// Keys handled by the application before the pages, which cannot be bound
+var reservedKeys = []tcell.Key{tcell.KeyCtrlC, tcell.KeyCtrlK, tcell.KeyCtrlN, tcell.KeyEscape}
+
+// Keys of the tables of all pages, and of each page, which extensions cannot bind on the page
+var pageKeys = map[string]string{
+	"":             "/\\qdrw[] jkhlgG",
+	"workitems":    "",
+	"pullrequests": "cC",
+	"pipelines":    "fmnlgaTpxet",
+}
+
+// Named keys moving through the tables
+var tableKeys = []tcell.Key{
+	tcell.KeyEnter, tcell.KeyTab, tcell.KeyBacktab, tcell.KeyUp, tcell.KeyDown, tcell.KeyLeft, tcell.KeyRight,
+	tcell.KeyPgUp, tcell.KeyPgDn, tcell.KeyHome, tcell.KeyEnd,
+}
+
+// Named keys by their lowercase names, e.g. "f5" or "enter"
+var namedKeys = func() map[string]tcell.Key {
+	keys := map[string]tcell.Key{}
+	for key, name := range tcell.KeyNames {
+		if !strings.HasPrefix(name, "Ctrl-") {
+			keys[strings.ToLower(name)] = key
+		}
+	}
+	return keys
+}()
+
+// parseKeyBinding parses a key such as "o", "O", "ctrl+o", "alt+o" or "f5"
+func parseKeyBinding(name string) (keyBinding, error) {
+	binding := keyBinding{Name: name}
+	parts := strings.Split(strings.TrimSpace(name), "+")
+	ctrl := false
+	for _, modifier := range parts[:len(parts)-1] {
+		switch strings.ToLower(strings.TrimSpace(modifier)) {
+		case "ctrl":
+			ctrl = true
+		case "alt":
+			binding.alt = true
+		default:
+			return binding, fmt.Errorf("unknown modifier %q in key %q", modifier, name)
+		}
+	}
+
+	last := parts[len(parts)-1]
+	if runes := []rune(last); len(runes) == 1 {
+		r := runes[0]
+		if !ctrl {
+			binding.key, binding.r = tcell.KeyRune, r
+			return binding, nil
+		}
+		r = unicode.ToLower(r)
+		if r < 'a' || r > 'z' {
+			return binding, fmt.Errorf("only letters can be used with ctrl in key %q", name)
+		}
+		binding.key = tcell.KeyCtrlA + tcell.Key(r-'a')
+	} else {
+		key, ok := namedKeys[strings.ToLower(last)]
+		if !ok || ctrl {
+			return binding, fmt.Errorf("unknown key %q", name)
+		}
+		binding.key = key
+	}
+	for _, reserved := range reservedKeys {
+		if binding.key == reserved {
+			return binding, fmt.Errorf("key %q is reserved by the application", name)
+		}
+	}
+	return binding, nil
+}
+
+// Matches reports whether the event is the key
+func (b keyBinding) Matches(event *tcell.EventKey) bool {
+	if event.Key() != b.key {
+		return false
+	}
+	if b.key == tcell.KeyRune && event.Rune() != b.r {
+		return false
+	}
+	return (event.Modifiers()&tcell.ModAlt != 0) == b.alt
+}
+
+// ConflictsWith returns the page among the domains whose own key is the same, if any
+func (b keyBinding) ConflictsWith(domains []string) (string, bool) {
+	if b.alt {
+		return "", false
+	}
+	if b.key != tcell.KeyRune && slices.Contains(tableKeys, b.key) {
+		return "every page", true
+	}
+	if b.key != tcell.KeyRune {
+		return "", false
+	}
+	if strings.ContainsRune(pageKeys[""], b.r) {
+		return "every page", true
+	}
+	for _, domain := range domains {
+		if strings.ContainsRune(pageKeys[domain], b.r) {
+			return domain, true
+		}
+	}
+	return "", false
+}
+
+// String describes the key as in the hotkeys, e.g. "Ctrl+O" or "Alt+X"
+func (b keyBinding) String() string {
+	var name string
+	switch {
+	case b.key == tcell.KeyRune && b.r == ' ':
+		name = "Space"
+	case b.key == tcell.KeyRune:
+		name = string(b.r)
+	case b.key >= tcell.KeyCtrlA && b.key <= tcell.KeyCtrlZ:
+		name = "Ctrl+" + string(rune('A'+b.key-tcell.KeyCtrlA))
+	default:
+		name = tcell.KeyNames[b.key]
+	}
+	if b.alt {
+		name = "Alt+" + name
+	}
+	return name
+}
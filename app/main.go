@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 	"time"
 
@@ -33,52 +36,107 @@ var userProfileErr error
 var localTzLocation *time.Location
 
 var ExtRegistry *Registry
+var AppSettings *AppConfig
 
 // TODO Move to own file
 var DetailsPanelBorderColorExpanded = tcell.ColorYellow
 
+const usageText = `lazyaz, a terminal UI for Azure DevOps
+
+Usage:
+  lazyaz [flags]                       Start the application
+  lazyaz open [wi|pr|run] <id-or-url>  Start the application on the details of an item
+  lazyaz show wi|pr|run <id-or-url>    Print the details of an item
+  lazyaz export wi|pr|run <id-or-url>  Render an item with a template, see lazyaz export --help
+  lazyaz workitems list                List work items, see lazyaz workitems list --help
+  lazyaz prs list                      List pull requests
+  lazyaz pipelines list|runs           List pipelines or pipeline runs
+  lazyaz doctor                        Check the installation and the configuration
+  lazyaz version                       Print the version
+  lazyaz completion bash|zsh|fish|powershell
+                                       Print the completion script of a shell
+
+The organization and project are taken, by precedence, from --org and --project, the --profile of lazyaz.toml,
+organization and project of lazyaz.toml, the Azure CLI defaults and finally AZURE_DEVOPS_ORG and AZURE_DEVOPS_PROJECT.
+
+Flags:
+`
+
+// exitOnError exits with the error, if any, or successfully
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
 func main() {
-	cliArgs := os.Args[1:]
+	StartupOptions.Offline = os.Getenv("LAZYAZ_OFFLINE") == "1"
+	flags := flag.NewFlagSet("lazyaz", flag.ContinueOnError)
+	StartupOptions.registerConnectionFlags(flags)
+	StartupOptions.registerStartupFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usageText)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+	cliArgs := flags.Args()
 
 	if len(cliArgs) > 0 {
 		switch cliArgs[0] {
-		case "doctor":
-			azuredevops.Doctor()
+		case "version":
+			fmt.Println("lazyaz", versionString())
 			os.Exit(0)
+		case "completion":
+			exitOnError(RunCompletion(cliArgs[1:], os.Stdout))
+		case "__complete":
+			exitOnError(RunComplete(cliArgs[1:], os.Stdout))
+		case "doctor":
+			exitOnError(RunDoctor(cliArgs[1:], &StartupOptions))
+		case "show":
+			exitOnError(RunShowCommand(cliArgs[1:], os.Stdout, &StartupOptions))
+		case "export":
+			exitOnError(RunExportCommand(cliArgs[1:], os.Stdout, &StartupOptions))
+		case "open":
+			var err error
+			StartupItem, err = ParseOpenArgs(cliArgs[1:], &StartupOptions)
+			if err != nil {
+				exitOnError(err)
+			}
+		default:
+			if !IsHeadlessCommand(cliArgs) {
+				flags.Usage()
+				exitOnError(fmt.Errorf("unknown command: %s", cliArgs[0]))
+			}
+			exitOnError(RunHeadlessCommand(cliArgs, os.Stdout, &StartupOptions))
 		}
 	}
-
-	logger.Debug("Application starting...")
-	var err error
-	localTzLocation, err = time.LoadLocation("Local")
-	if err != nil {
-		logger.Error("Error loading local timezone", "error", err)
-		logger.Debug("Using UTC")
-		localTzLocation = time.UTC
-	} else {
-		logger.Debug("Using local timezone", "timezone", localTzLocation)
+	if err := StartupOptions.validateStartup(); err != nil {
+		exitOnError(err)
 	}
+	configErr := connect(&StartupOptions)
 
-	// Integrate with Azure DevOps early on init
-	config, configErr := azuredevops.NewConfig()
-	if configErr != nil {
-		logger.Error("Configuration error", "error", configErr)
+	// Initialize registry
+	ExtRegistry = InitRegistry(AppSettings)
+	// Resume watching the items of the previous sessions
+	if err := Watches.Load(); err != nil {
+		logger.Error("Error loading watches", "error", err)
 	}
-	_organization = config.Organization
-	_project = config.Project
-	client = azuredevops.NewClient(config)
-	// Get current user
-	activeUser, userProfileErr = client.GetUserProfile()
-	if userProfileErr != nil {
-		logger.Error("Error fetching user profile", "error", userProfileErr)
+	if !OfflineMode {
+		Watches.Start()
 	}
-	// Initialize registry
-	ExtRegistry = InitRegistry()
 
 	slides := []Slide{
 		WorkItemsPage,
 		PullRequestsPage,
 		PipelinesPage,
+		PipelineDefinitionsPage,
 	}
 
 	pages := tview.NewPages()
@@ -119,6 +177,16 @@ func main() {
 		fmt.Fprintf(info, `[black]["%d"][limegreen:black] %d %s [""][black]`, index, index+1, title)
 	}
 	info.Highlight("0")
+	// Start on the page of the command line, or the page of the item to open
+	goToSlide(slices.Index(startupPages, StartupOptions.startupPage()))
+	if StartupItem != nil {
+		switch StartupItem.Kind {
+		case azuredevops.ItemPullRequest:
+			goToSlide(1)
+		case azuredevops.ItemPipelineRun:
+			goToSlide(2)
+		}
+	}
 
 	// Connection status
 	connectionStatus := tview.NewTextView().
@@ -166,6 +234,18 @@ func main() {
 
 	// Shortcuts to navigate between slides
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		MarkInteraction()
+
+		// Dialogs handle their own keys, including Escape to close them
+		if HasOverlay() {
+			return event
+		}
+
+		if event.Key() == tcell.KeyCtrlN {
+			ShowNotifications(app.GetFocus())
+			return nil
+		}
+
 		if event.Key() == tcell.KeyCtrlK {
 			// Enable hotkey for keyboard shortcuts
 			// log.Println("CMD+K captured")
@@ -198,8 +278,19 @@ func main() {
 		return event
 	})
 	app.EnableMouse(true)
+	app.SetMouseCapture(func(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+		if action != tview.MouseMove {
+			MarkInteraction()
+		}
+		return event, action
+	})
+	MarkInteraction()
 
-	if configErr != nil && userProfileErr != nil {
+	if OfflineMode {
+		connectionStatusText = fmt.Sprintf("✈️ Offline, browsing cached data of %s (read-only) ", _organization)
+		connectionStatus.SetText(connectionStatusText)
+		connectionStatus.SetTextColor(tcell.ColorYellow)
+	} else if configErr != nil && userProfileErr != nil {
 		connectionStatusText = "🚨 Error connecting to Azure DevOps: Inspect logs for more details."
 		connectionStatus.SetText(connectionStatusText)
 		connectionStatus.SetTextColor(tcell.ColorRed)
@@ -209,10 +300,70 @@ func main() {
 		connectionStatus.SetTextColor(tcell.ColorGreen)
 	}
 
+	Overlays.AddPage(mainOverlayPage, layout, true, true)
+	app.SetAfterDrawFunc(writeTerminalNotifications)
+
 	// Start the application.
-	if err := app.SetRoot(layout, true).EnableMouse(true).EnablePaste(true).Run(); err != nil {
+	if err := app.SetRoot(Overlays, true).EnableMouse(true).EnablePaste(true).Run(); err != nil {
 		logger.Error("Terminal UI error", "error", err)
 		panic(err)
 	}
+	StopAutoRefresh()
 	logger.Debug("Application exiting...")
 }
+
+// connect sets up the logs, then connects to Azure DevOps and loads the configuration,
+// for the terminal UI and the headless commands alike. It returns the error of the Azure DevOps configuration, if any.
+func connect(opts *Options) error {
+	if err := opts.setupLogging(); err != nil {
+		return err
+	}
+	logger.Debug("Application starting...")
+	// Browse the cached data without calling Azure DevOps
+	OfflineMode = opts.Offline
+
+	var err error
+	localTzLocation, err = time.LoadLocation("Local")
+	if err != nil {
+		logger.Error("Error loading local timezone", "error", err)
+		logger.Debug("Using UTC")
+		localTzLocation = time.UTC
+	} else {
+		logger.Debug("Using local timezone", "timezone", localTzLocation)
+	}
+
+	// Find and load configuration, which can set the organization and project
+	AppSettings, ConfigPath, err = loadAppConfig(opts.ConfigPath)
+	if err != nil {
+		logger.Error("Error loading configuration", "error", err)
+		if AppSettings == nil {
+			AppSettings = &AppConfig{}
+		}
+	} else {
+		logger.Debug(fmt.Sprintf("Loaded configuration from %s", ConfigPath))
+	}
+
+	// Integrate with Azure DevOps early on init
+	layers, configErr := opts.configLayers(AppSettings)
+	var config *azuredevops.Config
+	if configErr == nil {
+		config, configErr = azuredevops.ResolveConfig(layers...)
+	}
+	if configErr != nil {
+		logger.Error("Configuration error", "error", configErr)
+		config = &azuredevops.Config{}
+	} else {
+		logger.Debug("Using Azure DevOps", "organization", config.Organization, "from", config.OrganizationSource,
+			"project", config.Project, "project_from", config.ProjectSource)
+	}
+	_organization = config.Organization
+	_project = config.Project
+	client = azuredevops.NewClient(config)
+	azuredevops.SetOffline(OfflineMode)
+	// Get current user, the cached one when offline
+	activeUser, _, userProfileErr = fetchCached("user", client.GetUserProfile)
+	if userProfileErr != nil {
+		logger.Error("Error fetching user profile", "error", userProfileErr)
+	}
+	return configErr
+}
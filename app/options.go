@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strings"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+)
+
+// Set by the release build
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+// Pages the application can start on, in the order of the slides
+var startupPages = []string{"workitems", "prs", "pipelines", "definitions"}
+
+// Filters of the work items and pull requests pages, in the order of their dropdowns
+var (
+	workItemFilters    = []string{"me", "was-ever-me", "all"}
+	pullRequestFilters = []string{"mine", "assigned-to-me", "all", "active", "completed", "abandoned"}
+)
+
+// Options are the command-line flags of the application and the commands
+type Options struct {
+	Organization string
+	Project      string
+	Profile      string
+	ConfigPath   string
+	Page         string
+	Filter       string
+	Debug        bool
+	LogFile      string
+	Offline      bool
+}
+
+// Flags of the command line, also read by the pages for the page and filter to start with
+var StartupOptions Options
+
+// registerConnectionFlags registers the flags of the connection, the configuration and the logs
+func (o *Options) registerConnectionFlags(flags *flag.FlagSet) {
+	flags.StringVar(&o.Organization, "org", o.Organization, "Azure DevOps organization URL, e.g. https://dev.azure.com/my-org")
+	flags.StringVar(&o.Project, "project", o.Project, "Azure DevOps project")
+	flags.StringVar(&o.Profile, "profile", o.Profile, "Profile of lazyaz.toml with the organization and project")
+	flags.StringVar(&o.ConfigPath, "config", o.ConfigPath, "Path of lazyaz.toml")
+	flags.BoolVar(&o.Debug, "debug", o.Debug, "Log debug messages")
+	flags.StringVar(&o.LogFile, "log-file", o.LogFile, "Write the logs to the file instead of stderr")
+	flags.BoolVar(&o.Offline, "offline", o.Offline, "Browse the cached data without calling Azure DevOps")
+}
+
+// registerStartupFlags registers the flags of the page and filter the application starts with
+func (o *Options) registerStartupFlags(flags *flag.FlagSet) {
+	flags.StringVar(&o.Page, "page", o.Page, "Page to start on: "+strings.Join(startupPages, ", "))
+	flags.StringVar(&o.Filter, "filter", o.Filter, "Filter of the page to start on, or pipeline runs preset")
+}
+
+// validateStartup checks the page and filter to start with
+func (o *Options) validateStartup() error {
+	if o.Page != "" && !slices.Contains(startupPages, o.Page) {
+		return fmt.Errorf("unknown page %q, available pages: %s", o.Page, strings.Join(startupPages, ", "))
+	}
+	if o.Filter == "" {
+		return nil
+	}
+	switch o.startupPage() {
+	case "workitems":
+		if !slices.Contains(workItemFilters, o.Filter) {
+			return fmt.Errorf("unknown work items filter %q, available filters: %s", o.Filter, strings.Join(workItemFilters, ", "))
+		}
+	case "prs":
+		if !slices.Contains(pullRequestFilters, o.Filter) {
+			return fmt.Errorf("unknown pull requests filter %q, available filters: %s", o.Filter, strings.Join(pullRequestFilters, ", "))
+		}
+	case "definitions":
+		return fmt.Errorf("the definitions page has no filters")
+	}
+	return nil
+}
+
+// startupPage is the page to start on, the work items by default
+func (o *Options) startupPage() string {
+	if o.Page == "" {
+		return startupPages[0]
+	}
+	return o.Page
+}
+
+// startupFilter returns the filter of the command line when starting on the page, empty otherwise
+func startupFilter(page string) string {
+	if StartupOptions.startupPage() != page {
+		return ""
+	}
+	return StartupOptions.Filter
+}
+
+// configLayers are the sources of the organization and project that take precedence over the Azure CLI
+// defaults and the environment variables: the flags, then the profile and lazyaz.toml
+func (o *Options) configLayers(config *AppConfig) ([]azuredevops.ConfigLayer, error) {
+	layers := []azuredevops.ConfigLayer{{Source: "flags", Organization: o.Organization, Project: o.Project}}
+	if config == nil {
+		return layers, nil
+	}
+	if o.Profile != "" {
+		profile, ok := config.Profiles[o.Profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q", o.Profile)
+		}
+		layers = append(layers, azuredevops.ConfigLayer{
+			Source:       "profile " + o.Profile,
+			Organization: profile.Organization,
+			Project:      profile.Project,
+		})
+	}
+	return append(layers, azuredevops.ConfigLayer{
+		Source:       "lazyaz.toml",
+		Organization: config.Organization,
+		Project:      config.Project,
+	}), nil
+}
+
+// setupLogging sends the logs of the application and the client to the log file, at the debug level when enabled
+func (o *Options) setupLogging() error {
+	var output io.Writer = os.Stderr
+	if o.LogFile != "" {
+		file, err := os.OpenFile(o.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("error opening the log file: %v", err)
+		}
+		output = file
+	}
+	level := slog.LevelInfo
+	if o.Debug || os.Getenv("LAZYAZ_DEBUG") == "1" {
+		level = slog.LevelDebug
+	}
+	logger = slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{Level: level}))
+	azuredevops.SetLogger(logger)
+	log.SetOutput(output)
+	return nil
+}
+
+// versionString describes the version of the build, falling back to the module version of `go install`
+func versionString() string {
+	if version == "dev" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			return info.Main.Version
+		}
+	}
+	return fmt.Sprintf("%s (commit %s, built %s)", version, commit, date)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the headless commands
+var OutputFormats = []string{"table", "json", "csv", "yaml"}
+
+// recordFields returns the JSON names of the fields of the records, in the order of the struct
+func recordFields[T any]() []string {
+	var fields []string
+	recordType := reflect.TypeFor[T]()
+	for i := range recordType.NumField() {
+		name, _, _ := strings.Cut(recordType.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// normalizeFieldName makes "Assigned To", "assigned_to" and "assignedto" the same field
+func normalizeFieldName(name string) string {
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// resolveFields maps the requested fields to the fields of the records
+func resolveFields(requested []string, available []string) ([]string, error) {
+	var fields []string
+	for _, name := range requested {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		index := slices.IndexFunc(available, func(field string) bool {
+			return normalizeFieldName(field) == normalizeFieldName(name)
+		})
+		if index < 0 {
+			return nil, fmt.Errorf("unknown field %q, available fields: %s", name, strings.Join(available, ", "))
+		}
+		if !slices.Contains(fields, available[index]) {
+			fields = append(fields, available[index])
+		}
+	}
+	return fields, nil
+}
+
+// formatValue formats a value of a record for the table and CSV outputs. Dates are shown
+// in the local time zone to the minute when localDates is set, and kept as they are otherwise.
+func formatValue(value any, localDates bool) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		// Unset dates
+		if strings.HasPrefix(value, "0001-01-01T") {
+			return ""
+		}
+		if !localDates {
+			return value
+		}
+		if date, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return date.In(time.Local).Format("2006-01-02 15:04")
+		}
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case []any:
+		values := make([]string, len(value))
+		for i, item := range value {
+			values[i] = formatValue(item, localDates)
+		}
+		return strings.Join(values, ", ")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// selectedRecord is a record with only the selected fields, which keep their order in JSON and YAML
+type selectedRecord struct {
+	fields []string
+	values map[string]any
+}
+
+func (r selectedRecord) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range r.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(field)
+		value, err := json.Marshal(r.values[field])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (r selectedRecord) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, field := range r.fields {
+		var value yaml.Node
+		if err := value.Encode(r.values[field]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field}, &value)
+	}
+	return node, nil
+}
+
+// WriteRecords writes the records in the format. Only the fields are written when given,
+// otherwise the default fields for the table and CSV outputs and all fields for JSON and YAML.
+func WriteRecords[T any](w io.Writer, records []T, format string, fields []string, defaultFields []string) error {
+	available := recordFields[T]()
+	selected, err := resolveFields(fields, available)
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		if format == "table" || format == "csv" {
+			selected = defaultFields
+		} else {
+			selected = available
+		}
+	}
+
+	// Records as generic values, keeping the names of the JSON output
+	rows := make([]selectedRecord, len(records))
+	for i, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		rows[i].fields = selected
+		if err := json.Unmarshal(data, &rows[i].values); err != nil {
+			return err
+		}
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(rows)
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write(selected); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			values := make([]string, len(selected))
+			for i, field := range selected {
+				values[i] = formatValue(row.values[field], false)
+			}
+			if err := writer.Write(values); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case "table":
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		headers := make([]string, len(selected))
+		for i, field := range selected {
+			headers[i] = strings.ToUpper(field)
+		}
+		fmt.Fprintln(writer, strings.Join(headers, "\t"))
+		for _, row := range rows {
+			values := make([]string, len(selected))
+			for i, field := range selected {
+				// Keep each record on a single line
+				values[i] = strings.Join(strings.Fields(formatValue(row.values[field], true)), " ")
+			}
+			fmt.Fprintln(writer, strings.Join(values, "\t"))
+		}
+		return writer.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q, available formats: %s", format, strings.Join(OutputFormats, ", "))
+	}
+}
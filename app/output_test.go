@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type outputRecord struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	AssignedTo string    `json:"assignedTo,omitempty"`
+	Tags       []string  `json:"tags"`
+	Created    time.Time `json:"createdDate"`
+	Closed     time.Time `json:"closedDate"`
+	Internal   string    `json:"-"`
+}
+
+func TestRecordFields(t *testing.T) {
+	expected := []string{"id", "title", "assignedTo", "tags", "createdDate", "closedDate"}
+	if got := recordFields[outputRecord](); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestResolveFields(t *testing.T) {
+	available := recordFields[outputRecord]()
+	tests := []struct {
+		name        string
+		requested   []string
+		expected    []string
+		expectedErr string
+	}{
+		{"none", nil, nil, ""},
+		{"as in the JSON output", []string{"id", "assignedTo"}, []string{"id", "assignedTo"}, ""},
+		{"case, spaces, underscores and dashes", []string{"ID", "Assigned To", "created_date", "closed-date"}, []string{"id", "assignedTo", "createdDate", "closedDate"}, ""},
+		{"requested order", []string{"title", "id"}, []string{"title", "id"}, ""},
+		{"duplicates and blanks", []string{"id", " ", "Id", " title "}, []string{"id", "title"}, ""},
+		{"unknown field", []string{"id", "state"}, nil, `unknown field "state", available fields: id, title, assignedTo, tags, createdDate, closedDate`},
+		{"field left out of the JSON output", []string{"internal"}, nil, `unknown field "internal"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveFields(tt.requested, available)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected an error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	previous := time.Local
+	time.Local = time.FixedZone("UTC+2", 2*60*60)
+	defer func() { time.Local = previous }()
+
+	tests := []struct {
+		name       string
+		value      any
+		localDates bool
+		expected   string
+	}{
+		{"nil", nil, false, ""},
+		{"string", "Fix the build", false, "Fix the build"},
+		{"integer", float64(1234), false, "1234"},
+		{"decimal", 2.5, false, "2.5"},
+		{"boolean", true, false, "true"},
+		{"list", []any{"a", float64(1)}, false, "a, 1"},
+		{"unset date", "0001-01-01T00:00:00Z", true, ""},
+		{"unset date kept as is", "0001-01-01T00:00:00Z", false, ""},
+		{"local date", "2024-03-01T23:30:15.123Z", true, "2024-03-02 01:30"},
+		{"date kept as is", "2024-03-01T23:30:15.123Z", false, "2024-03-01T23:30:15.123Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatValue(tt.value, tt.localDates); got != tt.expected {
+				t.Errorf("formatValue(%v, %v) = %q, expected %q", tt.value, tt.localDates, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriteRecords(t *testing.T) {
+	previous := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = previous }()
+
+	records := []outputRecord{
+		{ID: 1, Title: "Fix the build", AssignedTo: "Jane Doe", Tags: []string{"ci", "urgent"}, Created: time.Date(2024, 3, 1, 9, 30, 15, 0, time.UTC)},
+		{ID: 2, Title: "Write the\ndocs", Tags: []string{}, Created: time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)},
+	}
+	defaultFields := []string{"id", "title"}
+
+	tests := []struct {
+		name        string
+		format      string
+		fields      []string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:   "table with the default fields",
+			format: "table",
+			expected: "ID  TITLE\n" +
+				"1   Fix the build\n" +
+				"2   Write the docs\n",
+		},
+		{
+			name:   "table with fields",
+			format: "table",
+			fields: []string{"assigned to", "tags", "Created Date"},
+			expected: "ASSIGNEDTO  TAGS        CREATEDDATE\n" +
+				"Jane Doe    ci, urgent  2024-03-01 09:30\n" +
+				"                        2024-03-02 10:00\n",
+		},
+		{
+			name:   "csv with fields",
+			format: "csv",
+			fields: []string{"id", "tags", "createdDate", "closedDate"},
+			expected: "id,tags,createdDate,closedDate\n" +
+				"1,\"ci, urgent\",2024-03-01T09:30:15Z,\n" +
+				"2,,2024-03-02T10:00:00Z,\n",
+		},
+		{
+			name:   "csv with the default fields",
+			format: "csv",
+			expected: "id,title\n" +
+				"1,Fix the build\n" +
+				"2,\"Write the\ndocs\"\n",
+		},
+		{
+			name:   "json in the order of the fields",
+			format: "json",
+			fields: []string{"title", "id"},
+			expected: "[\n" +
+				"  {\n    \"title\": \"Fix the build\",\n    \"id\": 1\n  },\n" +
+				"  {\n    \"title\": \"Write the\\ndocs\",\n    \"id\": 2\n  }\n" +
+				"]\n",
+		},
+		{
+			name:   "json with all the fields",
+			format: "json",
+			expected: "[\n" +
+				"  {\n    \"id\": 1,\n    \"title\": \"Fix the build\",\n    \"assignedTo\": \"Jane Doe\",\n    \"tags\": [\n      \"ci\",\n      \"urgent\"\n    ],\n" +
+				"    \"createdDate\": \"2024-03-01T09:30:15Z\",\n    \"closedDate\": \"0001-01-01T00:00:00Z\"\n  },\n" +
+				"  {\n    \"id\": 2,\n    \"title\": \"Write the\\ndocs\",\n    \"assignedTo\": null,\n    \"tags\": [],\n" +
+				"    \"createdDate\": \"2024-03-02T10:00:00Z\",\n    \"closedDate\": \"0001-01-01T00:00:00Z\"\n  }\n" +
+				"]\n",
+		},
+		{
+			name:   "yaml in the order of the fields",
+			format: "yaml",
+			fields: []string{"title", "id", "tags"},
+			expected: "- title: Fix the build\n  id: 1\n  tags:\n    - ci\n    - urgent\n" +
+				"- title: |-\n    Write the\n    docs\n  id: 2\n  tags: []\n",
+		},
+		{
+			name:        "unknown field",
+			format:      "csv",
+			fields:      []string{"state"},
+			expectedErr: `unknown field "state"`,
+		},
+		{
+			name:        "unknown format",
+			format:      "xml",
+			expectedErr: `unknown output format "xml"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteRecords(&buf, records, tt.format, tt.fields, defaultFields)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected an error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := buf.String(); got != tt.expected {
+				t.Errorf("Expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/rivo/tview"
+)
+
+// Number of rows left below the selection when the next page starts loading
+const loadMoreThreshold = 5
+
+// pager follows the pages loaded in a table and shows the number of rows
+// and whether more are available in the actions bar. It is used on the UI thread.
+type pager struct {
+	Indicator         *tview.TextView
+	continuationToken string
+	firstPageSize     int
+	loading           bool
+	// generation changes when the first page is loaded again, so pages of the previous list are dropped
+	generation int
+}
+
+func newPager() *pager {
+	return &pager{
+		Indicator: tview.NewTextView().
+			SetDynamicColors(true).
+			SetTextAlign(tview.AlignRight),
+	}
+}
+
+// resetPager starts over from the first page
+func resetPager[T any](p *pager, page azuredevops.Page[T]) {
+	p.generation++
+	p.loading = false
+	p.continuationToken = page.ContinuationToken
+	p.firstPageSize = len(page.Items)
+	p.show(len(page.Items))
+}
+
+// show the number of rows, and whether more are available
+func (p *pager) show(rows int) {
+	switch {
+	case p.loading:
+		p.Indicator.SetText(fmt.Sprintf("[yellow]%d rows, loading more...[white] ", rows))
+	case p.continuationToken != "":
+		p.Indicator.SetText(fmt.Sprintf("%d rows, [green]more available ↓[white] ", rows))
+	default:
+		p.Indicator.SetText(fmt.Sprintf("[gray]%d rows[white] ", rows))
+	}
+}
+
+// shouldLoadMore reports whether the next page is to be loaded with the selection at the index
+func (p *pager) shouldLoadMore(index int, rows int) bool {
+	return !p.loading && p.continuationToken != "" && index >= rows-loadMoreThreshold
+}
+
+// loadNextPage fetches the next page in the background and gives its items to onLoaded on the UI thread.
+// The page is dropped when the list was loaded again in the meantime.
+func loadNextPage[T any](p *pager, rows int, fetch func(continuationToken string) (azuredevops.Page[T], error), onLoaded func(items []T)) {
+	token := p.continuationToken
+	generation := p.generation
+	p.loading = true
+	p.show(rows)
+	go func() {
+		page, err := fetch(token)
+		app.QueueUpdateDraw(func() {
+			if generation != p.generation {
+				return
+			}
+			p.loading = false
+			if err != nil {
+				log.Printf("Error loading the next page: %v", err)
+				AnnounceError("❌ Error loading more rows")
+				p.show(rows)
+				return
+			}
+			p.continuationToken = page.ContinuationToken
+			onLoaded(page.Items)
+		})
+	}()
+}
+
+// keepLoadedPages returns the items of the refreshed first page followed by the items of the further pages,
+// which are not refreshed. The continuation token is only taken from the refreshed page when no further pages were loaded.
+func keepLoadedPages[T any](p *pager, previous []T, page azuredevops.Page[T], id func(T) int) []T {
+	items := page.Items
+	if p.firstPageSize > 0 && len(previous) > p.firstPageSize {
+		items = appendMissing(items, previous[p.firstPageSize:], id)
+	} else {
+		p.continuationToken = page.ContinuationToken
+	}
+	p.firstPageSize = len(page.Items)
+	return items
+}
+
+// appendMissing appends the items of more that are not in items yet, compared by ID
+func appendMissing[T any](items []T, more []T, id func(T) int) []T {
+	seen := make(map[int]bool, len(items))
+	for _, item := range items {
+		seen[id(item)] = true
+	}
+	for _, item := range more {
+		if !seen[id(item)] {
+			seen[id(item)] = true
+			items = append(items, item)
+		}
+	}
+	return items
+}
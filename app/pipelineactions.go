@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const queueRunOverlay = "queue-run"
+
+// ShowQueuePipelineRunForm loads the pipeline definition, its YAML parameters and variables,
+// then shows a form to queue a new run. onQueued is called on the UI thread with the new run.
+func ShowQueuePipelineRunForm(pipelineID int, branch string, focus tview.Primitive, onQueued func(run *azuredevops.PipelineRun)) {
+	Announce("⏳ Loading pipeline...", -1)
+	go func() {
+		definition, err := client.GetPipelineDefinition(pipelineID)
+		if err != nil {
+			log.Printf("Error fetching pipeline definition: %v", err)
+			app.QueueUpdateDraw(func() {
+				AnnounceError("❌ Error fetching pipeline definition")
+			})
+			return
+		}
+		if branch == "" {
+			branch = definition.DefaultBranch
+		}
+
+		// Parameters and variables are optional, the run can still be queued without them
+		pipelineYAML := &azuredevops.PipelineYAML{}
+		content, err := client.GetPipelineDefinitionYAML(definition, branch)
+		if err != nil {
+			logger.Warn("Cannot read pipeline parameters", "error", err)
+		} else if parsed, err := azuredevops.ParsePipelineYAML(content); err != nil {
+			logger.Warn("Cannot parse pipeline parameters", "error", err)
+		} else {
+			pipelineYAML = parsed
+		}
+		variables, err := client.GetPipelineVariables(pipelineID)
+		if err != nil {
+			logger.Warn("Cannot read pipeline variables", "error", err)
+		}
+
+		app.QueueUpdateDraw(func() {
+			AnnouncementStatus.SetText("")
+			showQueueForm(definition, branch, pipelineYAML, variables, focus, onQueued)
+		})
+	}()
+}
+
+func showQueueForm(
+	definition *azuredevops.PipelineDefinition,
+	branch string,
+	pipelineYAML *azuredevops.PipelineYAML,
+	variables []azuredevops.PipelineVariable,
+	focus tview.Primitive,
+	onQueued func(run *azuredevops.PipelineRun),
+) {
+	form := tview.NewForm().
+		SetFieldBackgroundColor(tcell.ColorBlack).
+		SetButtonBackgroundColor(tcell.ColorWhite).
+		SetButtonTextColor(tcell.ColorBlack)
+	form.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Run pipeline: %s ", definition.Name))
+
+	branchInput := tview.NewInputField().
+		SetLabel("Branch").
+		SetText(strings.TrimPrefix(branch, "refs/heads/")).
+		SetFieldWidth(40)
+	form.AddFormItem(branchInput)
+
+	// Each form item has a getter so values can be collected when the run is queued
+	parameterValues := map[string]func() string{}
+	for _, parameter := range pipelineYAML.Parameters {
+		if !parameter.IsQueueable() {
+			continue
+		}
+		switch {
+		case parameter.Type == "boolean":
+			checkbox := tview.NewCheckbox().
+				SetLabel(parameter.Label()).
+				SetChecked(parameter.DefaultString() == "true")
+			form.AddFormItem(checkbox)
+			parameterValues[parameter.Name] = func() string {
+				return fmt.Sprintf("%t", checkbox.IsChecked())
+			}
+		case len(parameter.Values) > 0:
+			dropdown := tview.NewDropDown().
+				SetLabel(parameter.Label()).
+				SetOptions(parameter.Values, nil).
+				SetCurrentOption(max(slices.Index(parameter.Values, parameter.DefaultString()), 0))
+			form.AddFormItem(dropdown)
+			parameterValues[parameter.Name] = func() string {
+				_, value := dropdown.GetCurrentOption()
+				return value
+			}
+		default:
+			input := tview.NewInputField().
+				SetLabel(parameter.Label()).
+				SetText(parameter.DefaultString()).
+				SetFieldWidth(40)
+			form.AddFormItem(input)
+			parameterValues[parameter.Name] = input.GetText
+		}
+	}
+
+	// Only variables marked as settable at queue time are shown, and only changed values are sent
+	variableValues := map[string]func() string{}
+	variableDefaults := map[string]string{}
+	for _, variable := range variables {
+		if !variable.AllowOverride {
+			continue
+		}
+		input := tview.NewInputField().
+			SetLabel("$" + variable.Name).
+			SetFieldWidth(40)
+		if variable.IsSecret {
+			input.SetMaskCharacter('*')
+		} else {
+			input.SetText(variable.Value)
+			variableDefaults[variable.Name] = variable.Value
+		}
+		form.AddFormItem(input)
+		variableValues[variable.Name] = input.GetText
+	}
+
+	var stagesToSkip func() []string
+	if len(pipelineYAML.Stages) > 0 {
+		checkboxes := map[string]*tview.Checkbox{}
+		for _, stage := range pipelineYAML.Stages {
+			checkbox := tview.NewCheckbox().SetLabel("Skip stage " + stage)
+			checkboxes[stage] = checkbox
+			form.AddFormItem(checkbox)
+		}
+		stagesToSkip = func() []string {
+			var skipped []string
+			for _, stage := range pipelineYAML.Stages {
+				if checkboxes[stage].IsChecked() {
+					skipped = append(skipped, stage)
+				}
+			}
+			return skipped
+		}
+	} else {
+		input := tview.NewInputField().
+			SetLabel("Stages to skip").
+			SetPlaceholder("comma separated").
+			SetFieldWidth(40)
+		form.AddFormItem(input)
+		stagesToSkip = func() []string {
+			var skipped []string
+			for _, stage := range strings.Split(input.GetText(), ",") {
+				if stage = strings.TrimSpace(stage); stage != "" {
+					skipped = append(skipped, stage)
+				}
+			}
+			return skipped
+		}
+	}
+
+	closeForm := func() {
+		CloseOverlay(queueRunOverlay, focus)
+	}
+
+	form.AddButton("Run", func() {
+		request := azuredevops.PipelineRunRequest{
+			PipelineID:         definition.ID,
+			Branch:             strings.TrimSpace(branchInput.GetText()),
+			TemplateParameters: map[string]string{},
+			Variables:          map[string]string{},
+			StagesToSkip:       stagesToSkip(),
+		}
+		for name, value := range parameterValues {
+			request.TemplateParameters[name] = value()
+		}
+		for name, value := range variableValues {
+			if current := value(); current != variableDefaults[name] {
+				request.Variables[name] = current
+			}
+		}
+		closeForm()
+
+		Announce(fmt.Sprintf("⏳ Queueing %s on %s...", definition.Name, request.Branch), -1)
+		go func() {
+			run, err := client.QueuePipelineRun(request)
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					log.Printf("Error queueing pipeline run: %v", err)
+					AnnounceError("❌ Error queueing pipeline run")
+					return
+				}
+				Announce(fmt.Sprintf("✅ Queued run %s", run.BuildNumber), 0)
+				onQueued(run)
+			})
+		}()
+	})
+	form.AddButton("Cancel", closeForm)
+	form.SetCancelFunc(closeForm)
+
+	// Each form item takes a line plus padding, with room for the buttons and border
+	height := min(form.GetFormItemCount()*2+5, 40)
+	ShowOverlay(queueRunOverlay, form, 70, height)
+}
+
+// ConfirmCancelPipelineRun asks for confirmation then cancels the in-progress run.
+// onUpdated is called on the UI thread with the refreshed run.
+func ConfirmCancelPipelineRun(run azuredevops.PipelineRun, focus tview.Primitive, onUpdated func(run *azuredevops.PipelineRun)) {
+	if !run.IsInProgress() {
+		AnnounceError(fmt.Sprintf("❌ Run %s is not in progress", run.BuildNumber))
+		return
+	}
+	ShowConfirm(fmt.Sprintf("Cancel run %s of %s?", run.BuildNumber, run.DefinitionName), focus, func() {
+		runPipelineRunAction(run, "Canceling", func() error {
+			return client.CancelPipelineRun(run.ID)
+		}, onUpdated)
+	})
+}
+
+// ConfirmRetryPipelineRun asks for confirmation then retries only the failed jobs of the run.
+// onUpdated is called on the UI thread with the refreshed run.
+func ConfirmRetryPipelineRun(run azuredevops.PipelineRun, focus tview.Primitive, onUpdated func(run *azuredevops.PipelineRun)) {
+	if !run.CanRetry() {
+		AnnounceError(fmt.Sprintf("❌ Run %s has no failed jobs to retry", run.BuildNumber))
+		return
+	}
+	ShowConfirm(fmt.Sprintf("Retry failed jobs of run %s of %s?", run.BuildNumber, run.DefinitionName), focus, func() {
+		runPipelineRunAction(run, "Retrying", func() error {
+			return client.RetryPipelineRun(run.ID)
+		}, onUpdated)
+	})
+}
+
+// ConfirmRerunPipelineRun asks for confirmation then queues a new run with the same branch, commit, parameters and variables.
+// onQueued is called on the UI thread with the new run.
+func ConfirmRerunPipelineRun(run azuredevops.PipelineRun, focus tview.Primitive, onQueued func(run *azuredevops.PipelineRun)) {
+	if run.IsInProgress() {
+		AnnounceError(fmt.Sprintf("❌ Run %s is still in progress", run.BuildNumber))
+		return
+	}
+	ShowConfirm(fmt.Sprintf("Re-run %s on %s?", run.DefinitionName, run.SourceBranch), focus, func() {
+		Announce(fmt.Sprintf("⏳ Re-running %s...", run.BuildNumber), -1)
+		go func() {
+			queued, err := client.RerunPipelineRun(&run)
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					log.Printf("Error re-running pipeline run %d: %v", run.ID, err)
+					AnnounceError("❌ Error re-running pipeline run")
+					return
+				}
+				Announce(fmt.Sprintf("✅ Queued run %s", queued.BuildNumber), 0)
+				onQueued(queued)
+			})
+		}()
+	})
+}
+
+// runPipelineRunAction performs the action in the background then reloads the run
+func runPipelineRunAction(run azuredevops.PipelineRun, verb string, action func() error, onUpdated func(run *azuredevops.PipelineRun)) {
+	Announce(fmt.Sprintf("⏳ %s run %s...", verb, run.BuildNumber), -1)
+	go func() {
+		err := action()
+		var updated *azuredevops.PipelineRun
+		if err == nil {
+			updated, err = client.GetPipelineRun(run.ID)
+		}
+		app.QueueUpdateDraw(func() {
+			if err != nil {
+				log.Printf("Error %s pipeline run %d: %v", strings.ToLower(verb), run.ID, err)
+				AnnounceError(fmt.Sprintf("❌ %s run %s failed", verb, run.BuildNumber))
+				return
+			}
+			Announce(fmt.Sprintf("✅ %s run %s", verb, run.BuildNumber), 0)
+			onUpdated(updated)
+		})
+	}()
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/dustin/go-humanize"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	pipelineApprovalsOverlay = "pipeline-approvals"
+	approvalDecisionOverlay  = "approval-decision"
+)
+
+// The waiting runs are looked up among the latest in-progress runs, a few timelines at a time
+const (
+	maxWaitingRunsChecked  = 10
+	waitingRunsConcurrency = 4
+)
+
+// inProgressRunIDs returns the IDs of the latest in-progress runs, at most maxWaitingRunsChecked
+func inProgressRunIDs(runs []azuredevops.PipelineRun) []int {
+	var ids []int
+	for _, run := range runs {
+		if run.IsInProgress() && len(ids) < maxWaitingRunsChecked {
+			ids = append(ids, run.ID)
+		}
+	}
+	return ids
+}
+
+// fetchPendingChecks looks up the checks each run is waiting on, see inProgressRunIDs
+func fetchPendingChecks(runIDs []int) map[int][]string {
+	checks := map[int][]string{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, waitingRunsConcurrency)
+	for _, runID := range runIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			records, err := client.GetPipelineRunTimeline(runID)
+			if err != nil {
+				log.Printf("Error fetching timeline of run %d: %v", runID, err)
+				return
+			}
+			mu.Lock()
+			checks[runID] = azuredevops.PendingCheckpoints(records)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+// ShowPendingApprovals lists the pending approvals assigned to the user, or all of the project,
+// and lets the user approve or reject them with a comment.
+// onDecided is called on the UI thread after an approval was approved or rejected.
+func ShowPendingApprovals(focus tview.Primitive, onDecided func(approval azuredevops.Approval)) {
+	var approvals []azuredevops.Approval
+	var shown []azuredevops.Approval
+	var showAll bool
+
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false).
+		SetSeparator(' ')
+	table.SetSelectedStyle(tcell.StyleDefault.
+		Foreground(tcell.ColorBlack).
+		Background(tcell.ColorLimeGreen))
+	table.SetBorder(true)
+
+	detailsTextView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWordWrap(true)
+	detailsTextView.SetBorder(true)
+
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]a[white] approve  [yellow]x[white] reject  [yellow]m[white] mine/all  [yellow]r[white] refresh  [yellow]q[white] close")
+
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(table, 0, 2, true).
+		AddItem(detailsTextView, 0, 1, false).
+		AddItem(statusBar, 1, 0, false)
+
+	showDetails := func(row int) {
+		if row < 1 || row > len(shown) {
+			detailsTextView.SetText("")
+			return
+		}
+		approval := shown[row-1]
+		var details strings.Builder
+		fmt.Fprintf(&details, "[blue]Pipeline[white]  %s\n", tview.Escape(approval.Pipeline.Name))
+		fmt.Fprintf(&details, "[blue]Run[white]       %s (%d)\n", tview.Escape(approval.Pipeline.Owner.Name), approval.GetRunID())
+		fmt.Fprintf(&details, "[blue]Requested[white] %s\n", humanize.Time(approval.CreatedOn))
+		if approval.MinRequiredApprovers > 0 {
+			fmt.Fprintf(&details, "[blue]Required[white]  %d approver(s)\n", approval.MinRequiredApprovers)
+		}
+		for _, step := range approval.Steps {
+			color := "[white]"
+			switch step.Status {
+			case "approved":
+				color = "[green]"
+			case "rejected":
+				color = "[red]"
+			}
+			fmt.Fprintf(&details, "  %s- %s: %s[white]", color, tview.Escape(step.AssignedApprover.DisplayName), step.Status)
+			if step.Comment != "" {
+				fmt.Fprintf(&details, " \"%s\"", tview.Escape(step.Comment))
+			}
+			details.WriteString("\n")
+		}
+		if approval.Instructions != "" {
+			fmt.Fprintf(&details, "\n[blue]Instructions[white]\n%s\n", tview.Escape(approval.Instructions))
+		}
+		detailsTextView.SetText(details.String())
+	}
+
+	redraw := func() {
+		shown = nil
+		for _, approval := range approvals {
+			if showAll || approval.IsAssignedTo(activeUser) {
+				shown = append(shown, approval)
+			}
+		}
+		title := " Pending approvals assigned to me "
+		if showAll {
+			title = " All pending approvals "
+		}
+		table.SetTitle(title)
+
+		table.Clear()
+		for column, header := range []string{"Pipeline", "Run", "Requested", "Approvers"} {
+			table.SetCell(0, column, tview.NewTableCell(header).
+				SetTextColor(tcell.ColorYellow).
+				SetSelectable(false))
+		}
+		for i, approval := range shown {
+			approverColor := tcell.ColorWhite
+			if approval.IsAssignedTo(activeUser) {
+				approverColor = tcell.ColorGreen
+			}
+			table.SetCell(i+1, 0, tview.NewTableCell(approval.Pipeline.Name))
+			table.SetCell(i+1, 1, tview.NewTableCell(approval.Pipeline.Owner.Name).SetTextColor(tcell.ColorRed))
+			table.SetCell(i+1, 2, tview.NewTableCell(humanize.Time(approval.CreatedOn)))
+			table.SetCell(i+1, 3, tview.NewTableCell(strings.Join(approval.GetPendingApprovers(), ", ")).
+				SetTextColor(approverColor).
+				SetExpansion(1))
+		}
+		if len(shown) == 0 {
+			table.SetCell(1, 0, tview.NewTableCell("No pending approvals. Press m to show all of the project").
+				SetTextColor(tcell.ColorGray).
+				SetSelectable(false))
+		}
+		table.Select(1, 0)
+		showDetails(1)
+	}
+
+	loadApprovals := func() {
+		detailsTextView.SetText("[yellow]Loading...[white]")
+		go func() {
+			latest, err := client.GetPendingApprovals()
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					log.Printf("Error fetching pending approvals: %v", err)
+					detailsTextView.SetText("[red]Error fetching pending approvals[white]")
+					return
+				}
+				approvals = latest
+				redraw()
+			})
+		}()
+	}
+
+	decide := func(approve bool) {
+		row, _ := table.GetSelection()
+		if row < 1 || row > len(shown) {
+			return
+		}
+		approval := shown[row-1]
+		verb, progress, done := "Reject", "Rejecting", "Rejected"
+		if approve {
+			verb, progress, done = "Approve", "Approving", "Approved"
+		}
+
+		closeForm := func() {
+			CloseOverlay(approvalDecisionOverlay, table)
+		}
+		commentInput := tview.NewInputField().
+			SetLabel("Comment").
+			SetFieldWidth(50)
+		form := tview.NewForm().
+			SetFieldBackgroundColor(tcell.ColorBlack).
+			SetButtonBackgroundColor(tcell.ColorWhite).
+			SetButtonTextColor(tcell.ColorBlack).
+			AddFormItem(commentInput)
+		form.SetBorder(true).
+			SetTitle(fmt.Sprintf(" %s %s %s ", verb, approval.Pipeline.Name, approval.Pipeline.Owner.Name))
+		form.AddButton(verb, func() {
+			comment := strings.TrimSpace(commentInput.GetText())
+			closeForm()
+			Announce(fmt.Sprintf("⏳ %s %s...", progress, approval.Pipeline.Owner.Name), -1)
+			go func() {
+				err := client.UpdateApproval(approval.ID, approve, comment)
+				app.QueueUpdateDraw(func() {
+					if err != nil {
+						log.Printf("Error updating approval %s: %v", approval.ID, err)
+						AnnounceError(fmt.Sprintf("❌ %s of %s failed", verb, approval.Pipeline.Owner.Name))
+						return
+					}
+					Announce(fmt.Sprintf("✅ %s %s", done, approval.Pipeline.Owner.Name), 0)
+					loadApprovals()
+					onDecided(approval)
+				})
+			}()
+		})
+		form.AddButton("Cancel", closeForm)
+		form.SetCancelFunc(closeForm)
+		ShowOverlay(approvalDecisionOverlay, form, 70, 7)
+	}
+
+	table.SetSelectionChangedFunc(func(row, column int) {
+		showDetails(row)
+	})
+
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			CloseOverlay(pipelineApprovalsOverlay, focus)
+			return nil
+		case event.Rune() == 'a':
+			decide(true)
+			return nil
+		case event.Rune() == 'x':
+			decide(false)
+			return nil
+		case event.Rune() == 'm':
+			showAll = !showAll
+			redraw()
+			return nil
+		case event.Rune() == 'r':
+			loadApprovals()
+			return nil
+		}
+		return event
+	})
+
+	Overlays.AddPage(pipelineApprovalsOverlay, layout, true, true)
+	app.SetFocus(table)
+	loadApprovals()
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/dustin/go-humanize"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const pipelineArtifactsOverlay = "pipeline-artifacts"
+
+// pathSize returns the size of a file, or the total size of the files in a directory
+func pathSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// downloadWithProgress runs the download in the background, reporting progress in the status bar
+// by watching the size of the target path grow towards the expected size (if known)
+func downloadWithProgress(name string, target string, expectedSize int64, download func() error) {
+	done := make(chan error, 1)
+	go func() {
+		done <- download()
+	}()
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		started := time.Now()
+		for {
+			select {
+			case err := <-done:
+				app.QueueUpdateDraw(func() {
+					if err != nil {
+						log.Printf("Error downloading %s: %v", name, err)
+						AnnounceError(fmt.Sprintf("❌ Error downloading %s", name))
+						return
+					}
+					Announce(fmt.Sprintf("✅ Downloaded %s to %s", name, target), 0)
+				})
+				return
+			case <-ticker.C:
+				downloaded := pathSize(target)
+				progress := humanize.IBytes(uint64(downloaded))
+				if expectedSize > 0 {
+					progress = fmt.Sprintf("%d%% (%s / %s)", min(downloaded*100/expectedSize, 100), progress, humanize.IBytes(uint64(expectedSize)))
+				}
+				message := fmt.Sprintf("⬇ Downloading %s: %s, %s", name, progress, time.Since(started).Truncate(time.Second))
+				app.QueueUpdateDraw(func() {
+					AnnouncementStatus.SetText(message)
+				})
+			}
+		}
+	}()
+}
+
+// ShowPipelineRunArtifacts lists the artifacts of the run, the files of container artifacts,
+// and downloads the selected artifact or file to a chosen directory
+func ShowPipelineRunArtifacts(run *azuredevops.PipelineRun, focus tview.Primitive) {
+	root := tview.NewTreeNode(fmt.Sprintf("Artifacts of %s %s", run.DefinitionName, run.BuildNumber)).
+		SetColor(tcell.ColorYellow).
+		SetSelectable(false)
+	tree := tview.NewTreeView().
+		SetRoot(root).
+		SetCurrentNode(root).
+		SetGraphicsColor(tcell.ColorGray)
+	tree.SetBorder(true).
+		SetTitle(" Artifacts ")
+
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]Space[white] browse files  [yellow]Enter[white] download  [yellow]q[white] close")
+	directoryInput := tview.NewInputField().
+		SetLabel("Download to: ").
+		SetFieldTextColor(tcell.ColorGreen).
+		SetFieldBackgroundColor(tcell.ColorBlack)
+
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(tree, 0, 1, true).
+		AddItem(statusBar, 1, 0, false)
+
+	defaultDirectory, err := os.Getwd()
+	if err != nil {
+		defaultDirectory = "."
+	}
+
+	// Lazily list the files of a container artifact under its node
+	loadItems := func(node *tview.TreeNode, artifact azuredevops.PipelineArtifact) {
+		node.AddChild(tview.NewTreeNode("Loading...").SetColor(tcell.ColorYellow).SetSelectable(false))
+		go func() {
+			items, err := client.GetArtifactItems(&artifact, run.ProjectID)
+			app.QueueUpdateDraw(func() {
+				node.ClearChildren()
+				if err != nil {
+					log.Printf("Error fetching files of artifact %s: %v", artifact.Name, err)
+					node.AddChild(tview.NewTreeNode("Error fetching files").SetColor(tcell.ColorRed).SetSelectable(false))
+					return
+				}
+				_, artifactPath, _ := artifact.GetContainerLocation()
+				for _, item := range items {
+					if !item.IsFile() {
+						continue
+					}
+					relativePath := strings.TrimPrefix(strings.TrimPrefix(item.Path, artifactPath), "/")
+					node.AddChild(tview.NewTreeNode(fmt.Sprintf("%s [gray]%s[-]", tview.Escape(relativePath), humanize.IBytes(uint64(item.FileLength)))).
+						SetReference(item))
+				}
+				if len(node.GetChildren()) == 0 {
+					node.AddChild(tview.NewTreeNode("No files").SetColor(tcell.ColorGray).SetSelectable(false))
+				}
+			})
+		}()
+	}
+
+	download := func(node *tview.TreeNode) {
+		reference := node.GetReference()
+		if reference == nil {
+			return
+		}
+		directoryInput.SetText(defaultDirectory).
+			SetDoneFunc(func(key tcell.Key) {
+				layout.RemoveItem(directoryInput)
+				app.SetFocus(tree)
+				directory := strings.TrimSpace(directoryInput.GetText())
+				if key != tcell.KeyEnter || directory == "" {
+					return
+				}
+				if err := os.MkdirAll(directory, 0755); err != nil {
+					AnnounceError(fmt.Sprintf("❌ Cannot create %s", directory))
+					return
+				}
+				defaultDirectory = directory
+
+				switch item := reference.(type) {
+				case azuredevops.PipelineArtifact:
+					target := filepath.Join(directory, item.Name)
+					downloadWithProgress(item.Name, target, item.Size, func() error {
+						return client.DownloadPipelineArtifact(run.ID, item.Name, target)
+					})
+				case azuredevops.ArtifactItem:
+					name := filepath.Base(item.Path)
+					downloadWithProgress(name, filepath.Join(directory, name), item.FileLength, func() error {
+						_, err := client.DownloadArtifactItem(&item, directory)
+						return err
+					})
+				}
+			})
+		layout.AddItem(directoryInput, 1, 0, false)
+		app.SetFocus(directoryInput)
+	}
+
+	tree.SetSelectedFunc(download)
+
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if app.GetFocus() == directoryInput {
+			return event
+		}
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			CloseOverlay(pipelineArtifactsOverlay, focus)
+			return nil
+		case event.Rune() == ' ':
+			node := tree.GetCurrentNode()
+			if node == nil {
+				return nil
+			}
+			if artifact, ok := node.GetReference().(azuredevops.PipelineArtifact); ok && artifact.IsContainer() {
+				if len(node.GetChildren()) == 0 {
+					loadItems(node, artifact)
+					node.SetExpanded(true)
+				} else {
+					node.SetExpanded(!node.IsExpanded())
+				}
+			}
+			return nil
+		}
+		return event
+	})
+
+	Overlays.AddPage(pipelineArtifactsOverlay, layout, true, true)
+	app.SetFocus(tree)
+
+	root.AddChild(tview.NewTreeNode("Loading...").SetColor(tcell.ColorYellow).SetSelectable(false))
+	go func() {
+		artifacts, err := run.GetArtifacts(client)
+		app.QueueUpdateDraw(func() {
+			root.ClearChildren()
+			if err != nil {
+				log.Printf("Error fetching artifacts of run %d: %v", run.ID, err)
+				root.AddChild(tview.NewTreeNode("Error fetching artifacts").SetColor(tcell.ColorRed).SetSelectable(false))
+				return
+			}
+			if len(artifacts) == 0 {
+				root.AddChild(tview.NewTreeNode("No artifacts published").SetColor(tcell.ColorGray).SetSelectable(false))
+				return
+			}
+			for _, artifact := range artifacts {
+				text := tview.Escape(artifact.Name)
+				if artifact.Size > 0 {
+					text += fmt.Sprintf(" [gray]%s[-]", humanize.IBytes(uint64(artifact.Size)))
+				}
+				if artifact.IsContainer() {
+					text = "▸ " + text
+				}
+				root.AddChild(tview.NewTreeNode(text).SetReference(artifact))
+			}
+			tree.SetCurrentNode(root.GetChildren()[0])
+		})
+	}()
+}
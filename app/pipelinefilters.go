@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const pipelineFiltersOverlay = "pipeline-filters"
+
+// ShowPipelineRunsFilterForm shows the filters of the pipeline runs, prefilled with the current ones.
+// Presets can be loaded into the form, and the current filters saved as a preset.
+// onApply is called with the chosen filters; the pipeline of a preset is kept in its PipelineID.
+func ShowPipelineRunsFilterForm(current PipelineRunsPreset, focus tview.Primitive, onApply func(filter PipelineRunsPreset)) {
+	presets := LoadPipelineRunsPresets(AppSettings)
+	presetNames := make([]string, 0, len(presets))
+	for name := range presets {
+		presetNames = append(presetNames, name)
+	}
+	slices.Sort(presetNames)
+
+	form := tview.NewForm().
+		SetFieldBackgroundColor(tcell.ColorBlack).
+		SetButtonBackgroundColor(tcell.ColorWhite).
+		SetButtonTextColor(tcell.ColorBlack)
+	form.SetBorder(true).
+		SetTitle(" Filter pipeline runs ")
+
+	// The empty option means the filter is not applied
+	withAny := func(values []string) []string {
+		return append([]string{""}, slices.DeleteFunc(values, func(value string) bool { return value == "all" })...)
+	}
+	reasons := withAny(azuredevops.PipelineRunReasons())
+	results := withAny(azuredevops.PipelineRunResults())
+	statuses := withAny(azuredevops.PipelineRunStatuses())
+
+	branchInput := tview.NewInputField().
+		SetLabel("Branch").
+		SetPlaceholder("refs/heads/main").
+		SetFieldWidth(40)
+	reasonDropdown := tview.NewDropDown().
+		SetLabel("Reason").
+		SetOptions(reasons, nil)
+	resultDropdown := tview.NewDropDown().
+		SetLabel("Result").
+		SetOptions(results, nil)
+	statusDropdown := tview.NewDropDown().
+		SetLabel("Status").
+		SetOptions(statuses, nil)
+	requestedForInput := tview.NewInputField().
+		SetLabel("Requested for").
+		SetPlaceholder("user@example.com").
+		SetFieldWidth(40)
+	myRunsCheckbox := tview.NewCheckbox().
+		SetLabel("My runs only")
+	topInput := tview.NewInputField().
+		SetLabel("Top").
+		SetAcceptanceFunc(tview.InputFieldInteger).
+		SetFieldWidth(6)
+	presetNameInput := tview.NewInputField().
+		SetLabel("Save as preset").
+		SetPlaceholder("name").
+		SetFieldWidth(30)
+
+	// pipelineID is not shown in the form, it is carried over from the current filter or the preset
+	pipelineID := current.PipelineID
+	fill := func(filter PipelineRunsPreset) {
+		pipelineID = filter.PipelineID
+		branchInput.SetText(filter.Branch)
+		reasonDropdown.SetCurrentOption(max(slices.Index(reasons, filter.Reason), 0))
+		resultDropdown.SetCurrentOption(max(slices.Index(results, filter.Result), 0))
+		statusDropdown.SetCurrentOption(max(slices.Index(statuses, filter.Status), 0))
+		requestedForInput.SetText(filter.RequestedFor)
+		myRunsCheckbox.SetChecked(filter.MyRuns)
+		topInput.SetText("")
+		if filter.Top > 0 {
+			topInput.SetText(strconv.Itoa(filter.Top))
+		}
+	}
+	collect := func() PipelineRunsPreset {
+		_, reason := reasonDropdown.GetCurrentOption()
+		_, result := resultDropdown.GetCurrentOption()
+		_, status := statusDropdown.GetCurrentOption()
+		top, _ := strconv.Atoi(topInput.GetText())
+		return PipelineRunsPreset{
+			PipelineID:   pipelineID,
+			Branch:       strings.TrimSpace(branchInput.GetText()),
+			Reason:       reason,
+			Result:       result,
+			Status:       status,
+			RequestedFor: strings.TrimSpace(requestedForInput.GetText()),
+			MyRuns:       myRunsCheckbox.IsChecked(),
+			Top:          top,
+		}
+	}
+
+	if len(presetNames) > 0 {
+		presetDropdown := tview.NewDropDown().
+			SetLabel("Preset").
+			SetOptions(append([]string{""}, presetNames...), func(name string, index int) {
+				if preset, ok := presets[name]; ok {
+					fill(preset)
+				}
+			})
+		form.AddFormItem(presetDropdown)
+	}
+	form.AddFormItem(branchInput).
+		AddFormItem(reasonDropdown).
+		AddFormItem(resultDropdown).
+		AddFormItem(statusDropdown).
+		AddFormItem(requestedForInput).
+		AddFormItem(myRunsCheckbox).
+		AddFormItem(topInput).
+		AddFormItem(presetNameInput)
+	fill(current)
+
+	closeForm := func() {
+		CloseOverlay(pipelineFiltersOverlay, focus)
+	}
+	form.AddButton("Apply", func() {
+		filter := collect()
+		if name := strings.TrimSpace(presetNameInput.GetText()); name != "" {
+			if err := SavePipelineRunsPreset(name, filter); err != nil {
+				log.Printf("Error saving pipeline preset %s: %v", name, err)
+				AnnounceError(fmt.Sprintf("❌ Error saving preset %s", name))
+			} else {
+				Announce(fmt.Sprintf("✅ Saved preset %s", name), 0)
+			}
+		}
+		closeForm()
+		onApply(filter)
+	})
+	form.AddButton("Clear", func() {
+		fill(PipelineRunsPreset{PipelineID: pipelineID})
+	})
+	form.AddButton("Cancel", closeForm)
+	form.SetCancelFunc(closeForm)
+
+	height := min(form.GetFormItemCount()*2+5, 40)
+	ShowOverlay(pipelineFiltersOverlay, form, 70, height)
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const pipelineLogsOverlay = "pipeline-logs"
+
+// How often logs of in-progress runs are polled for new lines
+const pipelineLogsPollInterval = 3 * time.Second
+
+var logTimestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T[\d:.]+Z `)
+var logMarkerPattern = regexp.MustCompile(`^##\[(\w+)\]`)
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+var _logMarkerColors = map[string]string{
+	"error":   "[red]",
+	"warning": "[yellow]",
+	"section": "[green]",
+	"command": "[blue]",
+	"debug":   "[gray]",
+	"group":   "[::b]",
+}
+
+// stripANSI removes ANSI escape sequences, used for searching and saving logs
+func stripANSI(line string) string {
+	return ansiPattern.ReplaceAllString(line, "")
+}
+
+// formatLogLine converts a raw log line to tview markup.
+// The timestamp is dimmed, ##[marker] prefixes are replaced by colors and ANSI colors are translated.
+func formatLogLine(line string) string {
+	line = strings.TrimRight(line, "\r")
+	timestamp := logTimestampPattern.FindString(line)
+	line = strings.TrimPrefix(line, timestamp)
+
+	color := ""
+	if marker := logMarkerPattern.FindStringSubmatch(line); marker != nil {
+		if marker[1] == "endgroup" {
+			return ""
+		}
+		color = _logMarkerColors[marker[1]]
+		line = strings.TrimPrefix(line, marker[0])
+		if marker[1] == "group" {
+			line = "▸ " + line
+		}
+	}
+
+	formatted := tview.TranslateANSI(tview.Escape(line))
+	if color != "" {
+		formatted = color + formatted + "[-:-:-]"
+	}
+	if timestamp != "" {
+		formatted = "[gray]" + strings.TrimSpace(timestamp) + "[-] " + formatted
+	}
+	return formatted
+}
+
+// ShowPipelineRunLogs opens the log viewer for the run, starting on the given log or the latest one if 0.
+// Logs of in-progress runs are streamed until the run completes or the viewer is closed.
+func ShowPipelineRunLogs(run azuredevops.PipelineRun, initialLogID int, focus tview.Primitive) {
+	var logs []azuredevops.PipelineLog
+	var logNames map[int]string
+	var currentLogID int
+	var lines []string
+	var following = run.IsInProgress()
+	var populating bool
+	// Search related variables
+	var searchText string
+	var matchLines []int
+	var currentMatchIndex = -1
+	stopPolling := make(chan struct{})
+
+	logList := tview.NewList().
+		ShowSecondaryText(false).
+		SetHighlightFullLine(true).
+		SetSelectedBackgroundColor(tcell.ColorLimeGreen).
+		SetSelectedTextColor(tcell.ColorBlack)
+	logList.SetBorder(true).
+		SetTitle(" Logs ")
+
+	logView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetRegions(true).
+		SetScrollable(true).
+		SetWrap(true)
+	logView.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Run %s ", run.BuildNumber))
+
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true)
+	promptInput := tview.NewInputField().
+		SetFieldTextColor(tcell.ColorGreen).
+		SetFieldBackgroundColor(tcell.ColorBlack)
+
+	body := tview.NewFlex().
+		SetDirection(tview.FlexColumn).
+		AddItem(logList, 0, 1, true).
+		AddItem(logView, 0, 3, false)
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(body, 0, 1, true).
+		AddItem(statusBar, 1, 0, false)
+
+	updateStatus := func() {
+		status := "[yellow]Tab[white] switch  [yellow]/[white] search  [yellow]n/N[white] next/prev  [yellow]s[white] save  [yellow]f[white] follow  [yellow]q[white] close"
+		if len(matchLines) > 0 {
+			status = fmt.Sprintf("Match %d/%d  ", currentMatchIndex+1, len(matchLines)) + status
+		} else if searchText != "" {
+			status = "[red]No matches![white]  " + status
+		}
+		if following {
+			status = "[green]● Following[white]  " + status
+		}
+		statusBar.SetText(status)
+	}
+
+	render := func() {
+		matchIdx := 0
+		var text strings.Builder
+		for i, line := range lines {
+			formatted := formatLogLine(line)
+			if matchIdx < len(matchLines) && matchLines[matchIdx] == i {
+				fmt.Fprintf(&text, `["match-%d"]%s[""]`+"\n", matchIdx, formatted)
+				matchIdx++
+			} else {
+				text.WriteString(formatted + "\n")
+			}
+		}
+		logView.SetText(text.String())
+		if following {
+			logView.ScrollToEnd()
+		} else if currentMatchIndex >= 0 {
+			logView.Highlight(fmt.Sprintf("match-%d", currentMatchIndex)).ScrollToHighlight()
+		}
+		updateStatus()
+	}
+
+	// findMatches appends the indices of the lines from start matching the search
+	findMatches := func(start int) {
+		if searchText == "" {
+			return
+		}
+		normalizedSearchText := strings.ToLower(searchText)
+		for i := start; i < len(lines); i++ {
+			if strings.Contains(strings.ToLower(stripANSI(lines[i])), normalizedSearchText) {
+				matchLines = append(matchLines, i)
+			}
+		}
+	}
+
+	search := func() {
+		matchLines = nil
+		currentMatchIndex = -1
+		findMatches(0)
+		if len(matchLines) > 0 {
+			currentMatchIndex = 0
+			following = false
+		}
+		render()
+	}
+
+	// appendLines adds the new lines of a streaming log, keeping the current match and following
+	appendLines := func(newLines []string) {
+		start := len(lines)
+		lines = append(lines, newLines...)
+		findMatches(start)
+		if currentMatchIndex < 0 && len(matchLines) > 0 {
+			currentMatchIndex = 0
+		}
+		render()
+	}
+
+	moveToMatch := func(step int) {
+		if len(matchLines) == 0 {
+			return
+		}
+		following = false
+		currentMatchIndex = (currentMatchIndex + step + len(matchLines)) % len(matchLines)
+		logView.Highlight(fmt.Sprintf("match-%d", currentMatchIndex)).ScrollToHighlight()
+		updateStatus()
+	}
+
+	loadLog := func(logID int) {
+		currentLogID = logID
+		lines = nil
+		logView.SetTitle(fmt.Sprintf(" %s ", logNames[logID]))
+		logView.SetText("[yellow]Loading...[white]")
+		go func() {
+			logLines, err := client.GetPipelineRunLogLines(run.ID, logID, 1)
+			app.QueueUpdateDraw(func() {
+				if logID != currentLogID {
+					return
+				}
+				if err != nil {
+					log.Printf("Error fetching log %d of run %d: %v", logID, run.ID, err)
+					logView.SetText("[red]Error fetching log[white]")
+					return
+				}
+				lines = logLines
+				search()
+			})
+		}()
+	}
+
+	setLogs := func(latestLogs []azuredevops.PipelineLog, latestNames map[int]string) {
+		logs = latestLogs
+		logNames = latestNames
+		selected := logList.GetCurrentItem()
+		// Repopulating the list fires change events which must not switch the current log
+		populating = true
+		defer func() { populating = false }()
+		logList.Clear()
+		for _, pipelineLog := range logs {
+			name, ok := logNames[pipelineLog.ID]
+			if !ok {
+				name = "Log " + strconv.Itoa(pipelineLog.ID)
+				logNames[pipelineLog.ID] = name
+			}
+			logList.AddItem(fmt.Sprintf("%s [gray](%d)[-]", tview.Escape(name), pipelineLog.LineCount), "", 0, nil)
+		}
+		if selected >= 0 && selected < logList.GetItemCount() {
+			logList.SetCurrentItem(selected)
+		}
+	}
+
+	fetchLogs := func() ([]azuredevops.PipelineLog, map[int]string, error) {
+		latestLogs, err := client.GetPipelineRunLogs(run.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		names := map[int]string{}
+		if records, err := client.GetPipelineRunTimeline(run.ID); err == nil {
+			names = azuredevops.PipelineLogNames(records)
+		}
+		return latestLogs, names, nil
+	}
+
+	logList.SetChangedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
+		if !populating && index >= 0 && index < len(logs) && logs[index].ID != currentLogID {
+			loadLog(logs[index].ID)
+		}
+	})
+	logList.SetSelectedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
+		app.SetFocus(logView)
+	})
+
+	// Poll in-progress runs for new logs and new lines of the current log
+	pollLogs := func() {
+		ticker := time.NewTicker(pipelineLogsPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopPolling:
+				return
+			case <-ticker.C:
+			}
+			latestRun, err := client.GetPipelineRun(run.ID)
+			if err != nil {
+				log.Printf("Error fetching pipeline run %d: %v", run.ID, err)
+				continue
+			}
+			latestLogs, names, err := fetchLogs()
+			if err != nil {
+				log.Printf("Error fetching logs of run %d: %v", run.ID, err)
+				continue
+			}
+
+			snapshot := make(chan [2]int, 1)
+			app.QueueUpdate(func() {
+				snapshot <- [2]int{currentLogID, len(lines)}
+			})
+			state := <-snapshot
+			logID, lineCount := state[0], state[1]
+			var newLines []string
+			if logID != 0 {
+				newLines, err = client.GetPipelineRunLogLines(run.ID, logID, lineCount+1)
+				if err != nil {
+					log.Printf("Error fetching log %d of run %d: %v", logID, run.ID, err)
+				}
+			}
+
+			app.QueueUpdateDraw(func() {
+				if len(latestLogs) != len(logs) {
+					setLogs(latestLogs, names)
+				}
+				if logID == currentLogID && lineCount == len(lines) && len(newLines) > 0 {
+					appendLines(newLines)
+				}
+				if !latestRun.IsInProgress() {
+					following = false
+					updateStatus()
+					Announce(fmt.Sprintf("🏁 Run %s finished: %s", latestRun.BuildNumber, latestRun.Result), 0)
+				}
+			})
+			if !latestRun.IsInProgress() {
+				return
+			}
+		}
+	}
+
+	closePrompt := func() {
+		layout.RemoveItem(promptInput)
+		app.SetFocus(logView)
+	}
+	openPrompt := func(label string, text string, onDone func(text string)) {
+		promptInput.SetLabel(label).
+			SetText(text).
+			SetDoneFunc(func(key tcell.Key) {
+				closePrompt()
+				if key == tcell.KeyEnter {
+					onDone(strings.TrimSpace(promptInput.GetText()))
+				}
+			})
+		layout.AddItem(promptInput, 1, 0, false)
+		app.SetFocus(promptInput)
+	}
+
+	saveLog := func(path string) {
+		if path == "" || len(lines) == 0 {
+			return
+		}
+		plainLines := make([]string, len(lines))
+		for i, line := range lines {
+			plainLines[i] = stripANSI(line)
+		}
+		if err := os.WriteFile(path, []byte(strings.Join(plainLines, "\n")+"\n"), 0644); err != nil {
+			log.Printf("Error saving log to %s: %v", path, err)
+			AnnounceError("❌ Error saving log")
+			return
+		}
+		Announce("✅ Saved log to "+path, 0)
+	}
+
+	closeViewer := func() {
+		close(stopPolling)
+		CloseOverlay(pipelineLogsOverlay, focus)
+	}
+
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if app.GetFocus() == promptInput {
+			return event
+		}
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			closeViewer()
+			return nil
+		case event.Key() == tcell.KeyTab:
+			if logList.HasFocus() {
+				app.SetFocus(logView)
+			} else {
+				app.SetFocus(logList)
+			}
+			return nil
+		case event.Rune() == '/':
+			openPrompt("/", searchText, func(text string) {
+				searchText = text
+				search()
+			})
+			return nil
+		case event.Rune() == 'n':
+			moveToMatch(1)
+			return nil
+		case event.Rune() == 'N':
+			moveToMatch(-1)
+			return nil
+		case event.Rune() == 'f':
+			following = !following
+			if following {
+				logView.ScrollToEnd()
+			}
+			updateStatus()
+			return nil
+		case event.Rune() == 's':
+			defaultPath := fmt.Sprintf("%s-%s-%d.log", strings.ReplaceAll(run.DefinitionName, " ", "_"), run.BuildNumber, currentLogID)
+			openPrompt("Save to: ", defaultPath, saveLog)
+			return nil
+		}
+		return event
+	})
+
+	Overlays.AddPage(pipelineLogsOverlay, layout, true, true)
+	app.SetFocus(logList)
+	updateStatus()
+
+	go func() {
+		latestLogs, names, err := fetchLogs()
+		app.QueueUpdateDraw(func() {
+			if err != nil {
+				log.Printf("Error fetching logs of run %d: %v", run.ID, err)
+				logView.SetText("[red]Error fetching logs[white]")
+				return
+			}
+			if len(latestLogs) == 0 {
+				logView.SetText("[yellow]No logs yet[white]")
+			}
+			setLogs(latestLogs, names)
+			if len(logs) > 0 {
+				// Start with the most recent log unless asked otherwise, the one most likely to be of interest
+				initialIndex := len(logs) - 1
+				for i, pipelineLog := range logs {
+					if pipelineLog.ID == initialLogID {
+						initialIndex = i
+					}
+				}
+				logList.SetCurrentItem(initialIndex)
+				if currentLogID != logs[initialIndex].ID {
+					loadLog(logs[initialIndex].ID)
+				}
+			}
+		})
+		if run.IsInProgress() {
+			pollLogs()
+		}
+	}()
+}
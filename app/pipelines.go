@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"slices"
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/dustin/go-humanize"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"golang.org/x/text/cases"
@@ -19,32 +22,40 @@ const pipelineTableData = `Run ID|Build Number|Status|Result|Pipeline|Source Bra
 1|Loading...|Loading...|Loading...|Loading...|Loading...|Loading...|Loading...|Loading...
 `
 
-// Fetch pipeline definitions
-func fetchDefinitions() ([]azuredevops.Pipeline, error) {
-	definitions, err := client.GetPipelineDefinitions()
+// Number of errors in a row after which a queued run is no longer watched
+const maxWatchRunErrors = 30
+
+// Fetch pipeline definitions, falling back to the cached ones
+func fetchDefinitions() ([]azuredevops.Pipeline, time.Time, error) {
+	definitions, cachedAt, err := fetchCached("pipelines", client.GetPipelineDefinitions)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching pipeline definitions: %v", err)
+		return nil, cachedAt, fmt.Errorf("error fetching pipeline definitions: %v", err)
 	}
-	return definitions, nil
+	return definitions, cachedAt, nil
 }
 
-// Fetch pipeline runs
-func fetchRuns() ([]azuredevops.PipelineRun, error) {
-	runs, err := client.GetPipelineRuns()
-	if err != nil {
-		return nil, fmt.Errorf("error fetching pipeline runs: %v", err)
+// withConfiguredTop applies the number of runs of the configuration when the filter has none
+func withConfiguredTop(filter azuredevops.PipelineRunsFilter) azuredevops.PipelineRunsFilter {
+	if filter.Top <= 0 && AppSettings != nil {
+		filter.Top = AppSettings.Pipelines.Top
 	}
-	return runs, nil
+	return filter
 }
 
-func fetchRunsFiltered(pipelineDefinitionId int) ([]azuredevops.PipelineRun, error) {
-	runs, err := client.GetPipelineRunsFiltered(pipelineDefinitionId, "", "", "", "", "")
+// Fetch the first page of pipeline runs, falling back to the cached page of the same filter
+func fetchRunsFiltered(filter azuredevops.PipelineRunsFilter) (azuredevops.Page[azuredevops.PipelineRun], time.Time, error) {
+	filter = withConfiguredTop(filter)
+	page, cachedAt, err := fetchCached(cacheNameOf("pipelineruns", filter), func() (azuredevops.Page[azuredevops.PipelineRun], error) {
+		return client.GetPipelineRunsPage(filter, "")
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error fetching pipeline runs: %v", err)
+		return azuredevops.Page[azuredevops.PipelineRun]{}, cachedAt, fmt.Errorf("error fetching pipeline runs: %v", err)
 	}
-	return runs, nil
+	return page, cachedAt, nil
 }
 
+func pipelineRunID(run azuredevops.PipelineRun) int { return run.ID }
+
 func _runsToTableData(runs []azuredevops.PipelineRun) string {
 
 	tableData := "Run ID|Build Number|Status|Result|Pipeline|Source Branch|Queue Time|Reason|Requested For\n"
@@ -53,7 +64,7 @@ func _runsToTableData(runs []azuredevops.PipelineRun) string {
 			"%d|%s|%s|%s|%s|%s|%s|%s|%s",
 			run.ID,
 			run.BuildNumber,
-			cases.Title(language.English).String(run.Status),
+			runStatusText(run),
 			cases.Title(language.English).String(run.Result),
 			run.DefinitionName,
 			run.SourceBranch,
@@ -68,6 +79,19 @@ func _runsToTableData(runs []azuredevops.PipelineRun) string {
 	return tableData
 }
 
+// pipelineRunFingerprint changes whenever the run progresses
+func pipelineRunFingerprint(run azuredevops.PipelineRun) string {
+	return fmt.Sprintf("%s|%s|%s", run.Status, run.Result, run.FinishTime)
+}
+
+// runStatusText is the status shown in the table, including the checks an in-progress run waits on
+func runStatusText(run azuredevops.PipelineRun) string {
+	if len(run.PendingChecks) > 0 {
+		return "Waiting: " + strings.Join(run.PendingChecks, ", ")
+	}
+	return cases.Title(language.English).String(run.Status)
+}
+
 var _runResultColors = map[string]tcell.Color{
 	"Succeeded":           tcell.ColorGreen,
 	"Partially Succeeded": tcell.ColorYellow,
@@ -85,7 +109,7 @@ var _runStatusColors = map[string]tcell.Color{
 	"Canceling":   tcell.ColorOrange,
 }
 
-func redrawRunsTable(table *tview.Table, runs []azuredevops.PipelineRun) {
+func redrawRunsTable(table *tview.Table, runs []azuredevops.PipelineRun, selection *ItemSelection) {
 	table.Clear()
 	tableData := _runsToTableData(runs)
 	for row, line := range strings.Split(tableData, "\n") {
@@ -109,6 +133,9 @@ func redrawRunsTable(table *tview.Table, runs []azuredevops.PipelineRun) {
 					if _, ok := _runStatusColors[cell]; ok {
 						color = _runStatusColors[cell]
 					}
+					if len(run.PendingChecks) > 0 {
+						color = tcell.ColorFuchsia
+					}
 				}
 
 				if column == 3 {
@@ -137,10 +164,11 @@ func redrawRunsTable(table *tview.Table, runs []azuredevops.PipelineRun) {
 			}
 		}
 	}
+	markSelectedRows(table, runs, pipelineRunID, selection)
 	table.Select(0, 0)
 }
 
-func pipelineRunToDetailsData(run *azuredevops.PipelineRun) string {
+func pipelineRunToDetailsData(run *azuredevops.PipelineRun, artifactsErr error) string {
 	var buf bytes.Buffer
 	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', tabwriter.TabIndent)
 
@@ -151,6 +179,9 @@ func pipelineRunToDetailsData(run *azuredevops.PipelineRun) string {
 	fmt.Fprintf(w, "%sRun ID%s\t%d\n", keyColor, valueColor, run.ID)
 	fmt.Fprintf(w, "%sBuild Number%s\t%s\n", keyColor, valueColor, run.BuildNumber)
 	fmt.Fprintf(w, "%sStatus%s\t%s\n", keyColor, valueColor, cases.Title(language.English).String(run.Status))
+	if len(run.PendingChecks) > 0 {
+		fmt.Fprintf(w, "%sWaiting On%s\t[fuchsia]%s[white] (press p to review approvals)\n", keyColor, valueColor, strings.Join(run.PendingChecks, ", "))
+	}
 	if run.Result == "failed" {
 		highlightColor = "[red]"
 	} else if run.Result == "succeeded" {
@@ -159,6 +190,9 @@ func pipelineRunToDetailsData(run *azuredevops.PipelineRun) string {
 		highlightColor = ""
 	}
 	fmt.Fprintf(w, "%sResult%s\t%s%s[white]\n", keyColor, valueColor, highlightColor, cases.Title(language.English).String(run.Result))
+	if run.Result == "failed" || run.Result == "partiallySucceeded" {
+		fmt.Fprintf(w, "\t[gray]Press Shift+T to see the failed tests[white]\n")
+	}
 	highlightColor = ""
 	fmt.Fprintf(w, "%sPipeline%s\t%s\n", keyColor, valueColor, run.DefinitionName)
 	fmt.Fprintf(w, "%sURL%s\t%s\n", keyColor, valueColor, run.GetWebURL())
@@ -187,6 +221,24 @@ func pipelineRunToDetailsData(run *azuredevops.PipelineRun) string {
 	fmt.Fprintf(w, "%sRepository Type%s\t%s\n", keyColor, valueColor, run.RepositoryType)
 	fmt.Fprintf(w, "%sLogs URL%s\t%s\n", keyColor, valueColor, run.LogsURL)
 
+	fmt.Fprintf(w, "\n%sArtifacts%s\n", keyColor, valueColor)
+	if artifactsErr != nil {
+		fmt.Fprintf(w, "\t[red]Error fetching the artifacts: %s[white]\n", tview.Escape(artifactsErr.Error()))
+	} else if !run.IsArtifactsFetched {
+		fmt.Fprintf(w, "%s\n", "[yellow]Loading...[white]")
+	} else if len(run.Artifacts) == 0 {
+		fmt.Fprintf(w, "\t-\n")
+	} else {
+		for _, artifact := range run.Artifacts {
+			size := "-"
+			if artifact.Size > 0 {
+				size = humanize.IBytes(uint64(artifact.Size))
+			}
+			fmt.Fprintf(w, "\t- %s\t%s\t%s\n", artifact.Name, size, artifact.Type)
+		}
+		fmt.Fprintf(w, "\t[gray]Press a to browse and download[white]\n")
+	}
+
 	w.Flush()
 	return buf.String()
 }
@@ -199,6 +251,7 @@ func PipelinesPage(nextSlide func()) (title string, content tview.Primitive) {
 	// Details panel variables
 	var detailsVisible bool
 	var detailsPanelIsExpanded bool
+	var loadingRunID int
 	// Actions specific for pipelines
 	actionsPanel := tview.NewFlex().
 		SetDirection(tview.FlexColumn)
@@ -218,6 +271,45 @@ func PipelinesPage(nextSlide func()) (title string, content tview.Primitive) {
 	actionsPanel.AddItem(dropdown, 0, 1, false)
 	var pipelineIds []int
 	var currentPipelineDefinitionId int
+	// Filters other than the pipeline, which is picked from the dropdown
+	var runsFilter PipelineRunsPreset
+	// Start with the preset given on the command line
+	if name := startupFilter("pipelines"); name != "" {
+		if preset, ok := LoadPipelineRunsPresets(AppSettings)[name]; ok {
+			currentPipelineDefinitionId = preset.PipelineID
+			preset.PipelineID = 0
+			runsFilter = preset
+		} else {
+			AnnounceError(fmt.Sprintf("❌ Unknown pipeline runs preset %s", name))
+		}
+	}
+	filterStatus := tview.NewTextView().
+		SetDynamicColors(true)
+	actionsPanel.AddItem(filterStatus, 0, 1, false)
+	// checkRunsFilter drops "my runs" from the filter when the user is not known, telling the user so
+	// rather than showing the runs of everyone as if they were theirs
+	checkRunsFilter := func(filter PipelineRunsPreset) PipelineRunsPreset {
+		if _, err := filter.ToFilter(activeUser); err != nil {
+			AnnounceError(fmt.Sprintf("❌ Cannot filter my runs: %v", err))
+			filter.MyRuns = false
+		}
+		return filter
+	}
+	runsFilter = checkRunsFilter(runsFilter)
+	currentRunsFilter := func() azuredevops.PipelineRunsFilter {
+		// Checked by checkRunsFilter
+		filter, _ := runsFilter.ToFilter(activeUser)
+		filter.PipelineID = currentPipelineDefinitionId
+		return filter
+	}
+	showFilterStatus := func() {
+		if description := runsFilter.String(); description != "" {
+			filterStatus.SetText("[yellow]Filters:[white] " + tview.Escape(description))
+		} else {
+			filterStatus.SetText("[gray]No filters (press f)[white]")
+		}
+	}
+	showFilterStatus()
 	// Search related variables
 	var searchText, previousSearchText string
 	var searchMode bool = false
@@ -226,12 +318,16 @@ func PipelinesPage(nextSlide func()) (title string, content tview.Primitive) {
 	var currentMatchIndex int = -1
 	searchStatus := tview.NewTextView().SetText("").SetTextAlign(tview.AlignRight)
 	actionsPanel.AddItem(searchStatus, 0, 1, false)
+	runsPager := newPager()
+	actionsPanel.AddItem(runsPager.Indicator, 28, 0, false)
 
 	table := tview.NewTable().
 		SetFixed(1, 1).
 		SetBorders(false).
 		SetSelectable(true, false).
 		SetSeparator(' ')
+	// Items selected with space for the extensions
+	selection := NewItemSelection()
 
 	table.SetSelectedStyle(tcell.StyleDefault.
 		Foreground(tcell.ColorBlack).
@@ -274,18 +370,48 @@ func PipelinesPage(nextSlide func()) (title string, content tview.Primitive) {
 	detailActionsPanel := tview.NewFlex().
 		SetDirection(tview.FlexColumn)
 	detailsPanel.AddItem(detailActionsPanel, 1, 1, false)
-	AttachPipelineRunExtensions(detailActionsPanel, table, &runs)
+	AttachPipelineRunExtensions(detailActionsPanel, table, &runs, selection)
 
-	displayPipelineRunDetails := func(runs []azuredevops.PipelineRun, index int) {
-		if index >= 0 && index < len(runs) {
-			currentPipelineRun := runs[index]
-			details := pipelineRunToDetailsData(&currentPipelineRun)
-			detailsTextView.SetText(details)
+	displayCurrentPipelineRunDetails := func() {
+		if currentIndex < 0 || currentIndex >= len(runs) {
+			return
+		}
+		currentPipelineRun := runs[currentIndex]
+		detailsTextView.SetText(pipelineRunToDetailsData(&currentPipelineRun, nil))
+
+		// Fetch artifacts from `az pipelines runs artifact list`, into a copy as refreshes replace the runs.
+		// They are fetched again when the run is selected again after an error.
+		if !currentPipelineRun.IsArtifactsFetched {
+			loadingRunID = currentPipelineRun.ID
+			go func() {
+				_, err := currentPipelineRun.GetArtifacts(client)
+				if err != nil {
+					log.Printf("Error fetching artifacts of run %d: %v", currentPipelineRun.ID, err)
+				}
+				app.QueueUpdateDraw(func() {
+					if index := indexOfID(runs, pipelineRunID, currentPipelineRun.ID); index >= 0 && err == nil {
+						runs[index].Artifacts = currentPipelineRun.Artifacts
+						runs[index].IsArtifactsFetched = true
+					}
+					if loadingRunID == currentPipelineRun.ID {
+						detailsTextView.SetText(pipelineRunToDetailsData(&currentPipelineRun, err))
+					}
+				})
+			}()
 		}
 	}
 
-	displayCurrentPipelineRunDetails := func() {
-		displayPipelineRunDetails(runs, currentIndex)
+	// Load the next page of runs when the selection gets near the end of the table
+	loadMoreRuns := func() {
+		loadNextPage(runsPager, len(runs), func(continuationToken string) (azuredevops.Page[azuredevops.PipelineRun], error) {
+			return client.GetPipelineRunsPage(withConfiguredTop(currentRunsFilter()), continuationToken)
+		}, func(more []azuredevops.PipelineRun) {
+			rowOffset, _ := table.GetOffset()
+			runs = appendMissing(runs, more, pipelineRunID)
+			runsPager.show(len(runs))
+			redrawRunsTable(table, runs, selection)
+			restoreTableSelection(table, currentIndex, rowOffset)
+		})
 	}
 
 	table.SetSelectionChangedFunc(func(row, column int) {
@@ -293,6 +419,9 @@ func PipelinesPage(nextSlide func()) (title string, content tview.Primitive) {
 		if currentIndex < 0 {
 			currentIndex = 0
 		}
+		if runsPager.shouldLoadMore(currentIndex, len(runs)) {
+			loadMoreRuns()
+		}
 		if detailsVisible {
 			detailsTextView.SetText("")
 			displayCurrentPipelineRunDetails()
@@ -327,6 +456,21 @@ func PipelinesPage(nextSlide func()) (title string, content tview.Primitive) {
 		toggleDetailsPanel()
 	})
 
+	// Select the run of `lazyaz open` and show its details, listing it first when it is not listed
+	openPipelineRun := func(run *azuredevops.PipelineRun, index int) {
+		if run != nil {
+			runs = append([]azuredevops.PipelineRun{*run}, runs...)
+			redrawRunsTable(table, runs, selection)
+			index = 0
+		}
+		currentIndex = index
+		table.Select(index+1, 0)
+		if !detailsVisible {
+			toggleDetailsPanel()
+		}
+		app.SetFocus(table)
+	}
+
 	toggleExpandedDetailsPanel := func() {
 		if !detailsVisible {
 			return
@@ -346,59 +490,187 @@ func PipelinesPage(nextSlide func()) (title string, content tview.Primitive) {
 		}
 	}
 
+	// Replace the run in place and redraw the table keeping the current selection
+	refreshRunInPlace := func(updated azuredevops.PipelineRun) {
+		for i := range runs {
+			if runs[i].ID == updated.ID {
+				runs[i] = updated
+			}
+		}
+		row, column := table.GetSelection()
+		redrawRunsTable(table, runs, selection)
+		table.Select(row, column)
+		if detailsVisible {
+			displayCurrentPipelineRunDetails()
+		}
+	}
+
+	// Flag the in-progress runs waiting on approvals and checks, called off the UI thread
+	flagWaitingRuns := func() {
+		var runIDs []int
+		app.QueueUpdate(func() {
+			runIDs = inProgressRunIDs(runs)
+		})
+		checks := fetchPendingChecks(runIDs)
+		if len(checks) == 0 {
+			return
+		}
+		app.QueueUpdateDraw(func() {
+			updated := false
+			for i := range runs {
+				if runChecks, ok := checks[runs[i].ID]; ok && !slices.Equal(runs[i].PendingChecks, runChecks) {
+					runs[i].PendingChecks = runChecks
+					updated = true
+				}
+			}
+			if !updated {
+				return
+			}
+			row, column := table.GetSelection()
+			redrawRunsTable(table, runs, selection)
+			table.Select(row, column)
+			if detailsVisible {
+				displayCurrentPipelineRunDetails()
+			}
+		})
+	}
+
+	// Poll the run until it completes so its row shows live status. Errors are retried
+	// until maxWatchRunErrors of them in a row, e.g. when the run was deleted.
+	watchRunStatus := func(runID int) {
+		go func() {
+			failures := 0
+			for {
+				time.Sleep(10 * time.Second)
+				run, err := client.GetPipelineRun(runID)
+				if err != nil {
+					log.Printf("Error fetching pipeline run %d: %v", runID, err)
+					if failures++; failures >= maxWatchRunErrors {
+						return
+					}
+					continue
+				}
+				failures = 0
+				app.QueueUpdateDraw(func() {
+					refreshRunInPlace(*run)
+					if run.Status == "completed" {
+						Announce(fmt.Sprintf("🏁 Run %s finished: %s", run.BuildNumber, run.Result), 0)
+					}
+				})
+				if run.Status == "completed" {
+					return
+				}
+			}
+		}()
+	}
+
+	// Reload the runs, select the newly queued run and follow its status
+	selectQueuedRun := func(queued *azuredevops.PipelineRun) {
+		go func() {
+			page, _, err := fetchRunsFiltered(currentRunsFilter())
+			latestRuns := page.Items
+			if err != nil {
+				log.Printf("Error fetching pipeline runs: %v", err)
+				return
+			}
+			if !slices.ContainsFunc(latestRuns, func(run azuredevops.PipelineRun) bool { return run.ID == queued.ID }) {
+				latestRuns = append([]azuredevops.PipelineRun{*queued}, latestRuns...)
+			}
+			app.QueueUpdateDraw(func() {
+				runs = latestRuns
+				resetPager(runsPager, azuredevops.Page[azuredevops.PipelineRun]{Items: runs, ContinuationToken: page.ContinuationToken})
+				redrawRunsTable(table, runs, selection)
+				for i, run := range runs {
+					if run.ID == queued.ID {
+						currentIndex = i
+						table.Select(i+1, 0)
+						break
+					}
+				}
+				app.SetFocus(table)
+			})
+			watchRunStatus(queued.ID)
+		}()
+	}
+
+	// Refresh runs based on filter options
+	reloadRuns := func() {
+		go func() {
+			select {
+			case isFetching <- true:
+				app.QueueUpdateDraw(func() {
+					dropdown.SetLabel("Fetching ")
+				})
+				page, cachedAt, err := fetchRunsFiltered(currentRunsFilter())
+				runs = page.Items
+				<-isFetching
+				if err != nil {
+					log.Printf("Error fetching pipeline runs: %v", err)
+					app.QueueUpdateDraw(func() {
+						AnnounceError("❌ Error fetching pipeline runs")
+					})
+				} else if !cachedAt.IsZero() {
+					app.QueueUpdateDraw(func() {
+						AnnounceCached("pipeline runs", cachedAt)
+					})
+				}
+				if len(runs) > 0 {
+					app.QueueUpdateDraw(func() {
+						dropdown.SetLabel("")
+						resetPager(runsPager, page)
+						currentIndex = 0
+						redrawRunsTable(table, runs, selection)
+						closeDetailPanel()
+						app.SetFocus(table)
+						table.Select(0, 0)
+					})
+					flagWaitingRuns()
+				} else {
+					app.QueueUpdateDraw(func() {
+						dropdown.SetLabel("")
+						resetPager(runsPager, page)
+						table.Clear()
+						closeDetailPanel()
+						table.SetCell(0, 0, tview.NewTableCell("No runs found. Try other filters (press f, or \\ and Up or Down)").
+							SetTextColor(tcell.ColorRed).
+							SetAlign(tview.AlignCenter))
+					})
+				}
+			default:
+				// Another fetch is in progress, skip this one
+				return
+			}
+		}()
+	}
+
 	// ** Pipeline dropdown **
 	// Handle dropdown options selection
 	handleDropdownSelection := func() {
 		dropdown.SetSelectedFunc(func(text string, index int) {
 			app.SetFocus(table)
 			currentPipelineDefinitionId = pipelineIds[index]
-
-			// Refresh runs based on filter options
-			go func() {
-				select {
-				case isFetching <- true:
-					dropdown.SetLabel("Fetching ")
-					// TODO Support other filters
-					var err error
-					if currentPipelineDefinitionId == 0 {
-						runs, err = fetchRuns()
-					} else {
-						runs, err = fetchRunsFiltered(currentPipelineDefinitionId)
-					}
-					<-isFetching
-					if err != nil {
-						log.Printf("Error fetching pipeline runs: %v", err)
-					}
-					if len(runs) > 0 {
-						app.QueueUpdateDraw(func() {
-							dropdown.SetLabel("")
-							currentIndex = 0
-							redrawRunsTable(table, runs)
-							closeDetailPanel()
-							app.SetFocus(table)
-							table.Select(0, 0)
-						})
-					} else {
-						app.QueueUpdateDraw(func() {
-							dropdown.SetLabel("")
-							table.SetCell(0, 0, tview.NewTableCell("No runs found. Try other filters (press \\ and Up or Down)").
-								SetTextColor(tcell.ColorRed).
-								SetAlign(tview.AlignCenter))
-						})
-					}
-				default:
-					// Another fetch is in progress, skip this one
-					return
-				}
-			}()
+			reloadRuns()
 		})
 	}
+
+	// Apply the filters from the filter form or a preset, the preset may also pick the pipeline
+	applyRunsFilter := func(filter PipelineRunsPreset) {
+		pipelineID := filter.PipelineID
+		filter.PipelineID = 0
+		runsFilter = checkRunsFilter(filter)
+		showFilterStatus()
+		if index := slices.Index(pipelineIds, pipelineID); pipelineID != 0 && index >= 0 && pipelineID != currentPipelineDefinitionId {
+			dropdown.SetCurrentOption(index)
+			return
+		}
+		reloadRuns()
+	}
 	// Handle dropdown options display
 	setOptionsFromDefinitions := func(definitions []azuredevops.Pipeline) {
 		// Set options from definitions' names
 		pipelineNames := make([]string, len(definitions)+1)
 		pipelineNames[0] = "All"
-		pipelineIds = append(pipelineIds, 0)
+		pipelineIds = []int{0}
 		for i, definition := range definitions {
 			pipelineNames[i+1] = definition.Name + " [" + strconv.Itoa(definition.ID) + "]"
 			pipelineIds = append(pipelineIds, definition.ID)
@@ -406,7 +678,8 @@ func PipelinesPage(nextSlide func()) (title string, content tview.Primitive) {
 		dropdown.SetOptions(pipelineNames, func(text string, index int) {
 			currentPipelineDefinitionId = pipelineIds[index] // Why even have this here when it gets repeated on setSelectedFunc?
 		})
-		dropdown.SetCurrentOption(0)
+		// Keep the pipeline selected across refreshes
+		dropdown.SetCurrentOption(max(slices.Index(pipelineIds, currentPipelineDefinitionId), 0))
 		handleDropdownSelection()
 	}
 
@@ -496,36 +769,90 @@ func PipelinesPage(nextSlide func()) (title string, content tview.Primitive) {
 	loadData := func() {
 		select {
 		case isFetching <- true:
-			var err error
-			definitions, err := fetchDefinitions()
+			definitions, _, err := fetchDefinitions()
 			if err != nil {
-				<-isFetching // Release the lock
-				log.Fatalf("Error fetching pipeline definitions: %v", err)
-				AnnounceError("❌ Error fetching pipeline definitions")
+				log.Printf("Error fetching pipeline definitions: %v", err)
+			} else {
+				setOptionsFromDefinitions(definitions)
 			}
-			setOptionsFromDefinitions(definitions)
-			runs, err = fetchRuns()
+			page, cachedAt, err := fetchRunsFiltered(currentRunsFilter())
+			runs = page.Items
 			<-isFetching // Release the lock
 			if err != nil {
-				log.Fatalf("Error fetching pipeline runs: %v", err)
+				log.Printf("Error fetching pipeline runs: %v", err)
 				AnnounceError("❌ Error fetching pipeline runs")
+			} else if !cachedAt.IsZero() {
+				AnnounceCached("pipeline runs", cachedAt)
 			} else {
 				Announce("✅ Refresh done", 3)
 			}
-			redrawRunsTable(table, runs)
+			redrawRunsTable(table, runs, selection)
 			app.QueueUpdateDraw(func() {
+				resetPager(runsPager, page)
 				if detailsVisible {
 					displayCurrentPipelineRunDetails()
 				}
+				openStartupItem(azuredevops.ItemPipelineRun, runs, pipelineRunID, client.GetPipelineRun, openPipelineRun)
 			})
+			flagWaitingRuns()
 		default:
 			// Another fetch is in progress, skip this one
 			return
 		}
 	}
 
+	// Show the cached runs while the fresh ones load
+	if cached, savedAt, ok := LoadCache[azuredevops.Page[azuredevops.PipelineRun]](cacheNameOf("pipelineruns", withConfiguredTop(currentRunsFilter()))); ok && len(cached.Items) > 0 {
+		runs = cached.Items
+		resetPager(runsPager, cached)
+		redrawRunsTable(table, runs, selection)
+		Announce(fmt.Sprintf("⏳ Showing pipeline runs cached %s while refreshing...", humanize.Time(savedAt)), -1)
+	}
 	go loadData()
 
+	// Refresh in the background, highlighting the runs that appeared or changed
+	refreshInBackground := func() {
+		select {
+		case isFetching <- true:
+			latest, cachedAt, err := fetchRunsFiltered(currentRunsFilter())
+			<-isFetching
+			if err != nil || !cachedAt.IsZero() {
+				log.Printf("Error refreshing pipeline runs: %v", err)
+				return
+			}
+			app.QueueUpdateDraw(func() {
+				selectedID := -1
+				if currentIndex >= 0 && currentIndex < len(runs) {
+					selectedID = runs[currentIndex].ID
+				}
+				rowOffset, _ := table.GetOffset()
+				var changed map[int]bool
+				// The further pages loaded are kept as they are
+				refreshed := keepLoadedPages(runsPager, runs, latest, pipelineRunID)
+				runs, changed = mergeRefreshed(runs, refreshed, pipelineRunID, pipelineRunFingerprint)
+				if len(runs) == 0 {
+					return
+				}
+				runsPager.show(len(runs))
+				redrawRunsTable(table, runs, selection)
+				highlightRows(table, changed)
+				currentIndex = max(indexOfID(runs, pipelineRunID, selectedID), 0)
+				restoreTableSelection(table, currentIndex, rowOffset)
+				if detailsVisible {
+					displayCurrentPipelineRunDetails()
+				}
+				if len(changed) > 0 {
+					Announce(fmt.Sprintf("🔄 %d pipeline run(s) changed", len(changed)), 3)
+				}
+			})
+			flagWaitingRuns()
+		default:
+			// Another fetch is in progress, skip this one
+			return
+		}
+	}
+	StartAutoRefresh("pipelines", refreshInBackground, func() bool { return searchMode })
+
 	// Manage input capture
 	mainWindow.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		// Handle search mode activation with "/"
@@ -565,6 +892,132 @@ func PipelinesPage(nextSlide func()) (title string, content tview.Primitive) {
 			go loadData()
 			return nil
 		}
+
+		// Handle 'f' key to filter the runs, and 'm' key to toggle showing only my runs
+		if event.Rune() == 'f' && !searchMode {
+			filter := runsFilter
+			filter.PipelineID = currentPipelineDefinitionId
+			ShowPipelineRunsFilterForm(filter, table, applyRunsFilter)
+			return nil
+		}
+		if event.Rune() == 'm' && !searchMode {
+			filter := runsFilter
+			filter.MyRuns = !filter.MyRuns
+			applyRunsFilter(filter)
+			return nil
+		}
+
+		// Handle 'n' key to queue a new run of the selected run's pipeline or the filtered pipeline
+		if event.Rune() == 'n' && !searchMode {
+			pipelineID := currentPipelineDefinitionId
+			branch := ""
+			if currentIndex >= 0 && currentIndex < len(runs) {
+				pipelineID = runs[currentIndex].DefinitionID
+				branch = runs[currentIndex].SourceBranch
+			}
+			if pipelineID == 0 {
+				AnnounceError("❌ Select a run or a pipeline first")
+				return nil
+			}
+			ShowQueuePipelineRunForm(pipelineID, branch, table, selectQueuedRun)
+			return nil
+		}
+
+		// Handle 'l' key to view the logs of the selected run
+		if event.Rune() == 'l' && !searchMode {
+			if currentIndex >= 0 && currentIndex < len(runs) {
+				ShowPipelineRunLogs(runs[currentIndex], 0, table)
+			}
+			return nil
+		}
+
+		// Handle 'g' key to view the stages, jobs and tasks of the selected run
+		if event.Rune() == 'g' && !searchMode {
+			if currentIndex >= 0 && currentIndex < len(runs) {
+				ShowPipelineRunTimeline(runs[currentIndex], table)
+			}
+			return nil
+		}
+
+		// Handle 'a' key to browse and download the artifacts of the selected run
+		if event.Rune() == 'a' && !searchMode {
+			if currentIndex >= 0 && currentIndex < len(runs) {
+				// A copy, the artifacts being fetched in the background
+				run := runs[currentIndex]
+				ShowPipelineRunArtifacts(&run, table)
+			}
+			return nil
+		}
+
+		// Handle 'T' key to view the test results of the selected run
+		if event.Rune() == 'T' && !searchMode {
+			if currentIndex >= 0 && currentIndex < len(runs) {
+				ShowPipelineRunTestResults(runs[currentIndex], table)
+			}
+			return nil
+		}
+
+		// Handle 'p' key to review the pending approvals
+		if event.Rune() == 'p' && !searchMode {
+			ShowPendingApprovals(table, func(approval azuredevops.Approval) {
+				go flagWaitingRuns()
+				watchRunStatus(approval.GetRunID())
+			})
+			return nil
+		}
+
+		// Handle 'x', 'e' and 't' keys to cancel, re-run or retry failed jobs of the selected run
+		if (event.Rune() == 'x' || event.Rune() == 'e' || event.Rune() == 't') && !searchMode {
+			if currentIndex < 0 || currentIndex >= len(runs) {
+				return nil
+			}
+			run := runs[currentIndex]
+			onUpdated := func(updated *azuredevops.PipelineRun) {
+				refreshRunInPlace(*updated)
+				watchRunStatus(updated.ID)
+			}
+			switch event.Rune() {
+			case 'x':
+				ConfirmCancelPipelineRun(run, table, onUpdated)
+			case 'e':
+				ConfirmRerunPipelineRun(run, table, selectQueuedRun)
+			case 't':
+				ConfirmRetryPipelineRun(run, table, onUpdated)
+			}
+			return nil
+		}
+
+		// Handle 'w' key to be notified when the selected run finishes
+		if event.Rune() == 'w' && !searchMode {
+			if currentIndex < 0 || currentIndex >= len(runs) {
+				return nil
+			}
+			run := runs[currentIndex]
+			if !run.IsInProgress() && !Watches.IsWatched(WatchPipelineRun, run.ID) {
+				Announce(fmt.Sprintf("Run %s already finished", run.BuildNumber), 0)
+				return nil
+			}
+			ToggleWatch(Watch{
+				Kind:  WatchPipelineRun,
+				ID:    run.ID,
+				Title: run.DefinitionName + " " + run.BuildNumber,
+				State: pipelineRunWatchState(run),
+			})
+			return nil
+		}
+
+		// Handle space to select the highlighted item for the extensions
+		if event.Key() == tcell.KeyRune && event.Rune() == ' ' && !searchMode {
+			toggleSelectedRow(table, runs, pipelineRunID, selection, currentIndex)
+			return nil
+		}
+
+		// Handle the keys of the extensions, running against the selected items or the highlighted one
+		if !searchMode && HandleExtensionKey("pipelines", event, selectedItems(runs, pipelineRunID, selection, currentIndex), table, func() {
+			clearSelectedRows(table, runs, pipelineRunID, selection)
+		}) {
+			return nil
+		}
 		return event
 	})
 
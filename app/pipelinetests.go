@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const pipelineTestsOverlay = "pipeline-tests"
+
+// failedTest is a failed test with the name of the test run it belongs to
+type failedTest struct {
+	testRun string
+	result  azuredevops.TestResult
+}
+
+// testResultToDetailsData describes the failed test with its error message and stack trace
+func testResultToDetailsData(test failedTest) string {
+	var details strings.Builder
+	fmt.Fprintf(&details, "[blue]Test[white]      %s\n", tview.Escape(test.result.AutomatedTestName))
+	fmt.Fprintf(&details, "[blue]Test Run[white]  %s\n", tview.Escape(test.testRun))
+	if test.result.TestStorage != "" {
+		fmt.Fprintf(&details, "[blue]Storage[white]   %s\n", tview.Escape(test.result.TestStorage))
+	}
+	fmt.Fprintf(&details, "[blue]Outcome[white]   [red]%s[white]\n", test.result.Outcome)
+	fmt.Fprintf(&details, "[blue]Duration[white]  %s\n", test.result.Duration())
+	if test.result.IsFlaky() {
+		fmt.Fprintf(&details, "[blue]Flaky[white]     [yellow]Yes, this test is known to pass and fail on the same code[white]\n")
+	}
+	if test.result.ErrorMessage != "" {
+		fmt.Fprintf(&details, "\n[blue]Error Message[white]\n%s\n", tview.Escape(test.result.ErrorMessage))
+	}
+	if test.result.StackTrace != "" {
+		fmt.Fprintf(&details, "\n[blue]Stack Trace[white]\n[gray]%s[white]\n", tview.Escape(test.result.StackTrace))
+	}
+	return details.String()
+}
+
+// ShowPipelineRunTestResults shows the totals of the test runs published by the run
+// and the failed tests with their error message and stack trace
+func ShowPipelineRunTestResults(run azuredevops.PipelineRun, focus tview.Primitive) {
+	var tests []failedTest
+
+	summaryTextView := tview.NewTextView().
+		SetDynamicColors(true)
+	summaryTextView.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Tests of %s %s ", run.DefinitionName, run.BuildNumber))
+
+	list := tview.NewList().
+		ShowSecondaryText(false).
+		SetHighlightFullLine(true).
+		SetSelectedBackgroundColor(tcell.ColorLimeGreen).
+		SetSelectedTextColor(tcell.ColorBlack)
+	list.SetBorder(true).
+		SetTitle(" Failed tests ")
+
+	detailsTextView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWordWrap(true)
+	detailsTextView.SetBorder(true)
+
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]⚑[white] flaky  [yellow]Tab[white] switch focus  [yellow]r[white] refresh  [yellow]q[white] close")
+
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(summaryTextView, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(list, 0, 1, true).
+			AddItem(detailsTextView, 0, 2, false), 0, 4, true).
+		AddItem(statusBar, 1, 0, false)
+
+	list.SetChangedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
+		if index >= 0 && index < len(tests) {
+			detailsTextView.SetText(testResultToDetailsData(tests[index]))
+			detailsTextView.ScrollToBeginning()
+		}
+	})
+
+	loadTests := func() {
+		summaryTextView.SetText("[yellow]Loading...[white]")
+		go func() {
+			testRuns, err := client.GetTestRuns(run.ID)
+			if err != nil {
+				log.Printf("Error fetching test runs of run %d: %v", run.ID, err)
+				app.QueueUpdateDraw(func() {
+					summaryTextView.SetText("[red]Error fetching test runs[white]")
+				})
+				return
+			}
+
+			var summary strings.Builder
+			var totalPassed, totalFailed, totalSkipped int
+			var latestTests []failedTest
+			for _, testRun := range testRuns {
+				passed, failed, skipped := testRun.GetCounts()
+				totalPassed += passed
+				totalFailed += failed
+				totalSkipped += skipped
+				fmt.Fprintf(&summary, "%s: [green]%d passed[white], [red]%d failed[white], [gray]%d skipped[white]\n",
+					tview.Escape(testRun.Name), passed, failed, skipped)
+				if failed == 0 {
+					continue
+				}
+				results, err := client.GetFailedTestResults(testRun.ID)
+				if err != nil {
+					log.Printf("Error fetching failed tests of test run %d: %v", testRun.ID, err)
+					continue
+				}
+				for _, result := range results {
+					latestTests = append(latestTests, failedTest{testRun: testRun.Name, result: result})
+				}
+			}
+
+			app.QueueUpdateDraw(func() {
+				tests = latestTests
+				if len(testRuns) == 0 {
+					summaryTextView.SetText("No test results were published by this run")
+				} else {
+					summaryTextView.SetText(fmt.Sprintf("[blue]Total[white]: [green]%d passed[white], [red]%d failed[white], [gray]%d skipped[white]\n%s",
+						totalPassed, totalFailed, totalSkipped, summary.String()))
+				}
+				list.Clear()
+				detailsTextView.SetText("")
+				for _, test := range tests {
+					name := test.result.TestCaseTitle
+					if name == "" {
+						name = test.result.AutomatedTestName
+					}
+					if test.result.IsFlaky() {
+						name = "[yellow]⚑[-] " + tview.Escape(name)
+					} else {
+						name = "[red]✘[-] " + tview.Escape(name)
+					}
+					list.AddItem(name, "", 0, nil)
+				}
+				if len(tests) > 0 {
+					detailsTextView.SetText(testResultToDetailsData(tests[0]))
+				}
+			})
+		}()
+	}
+
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			CloseOverlay(pipelineTestsOverlay, focus)
+			return nil
+		case event.Key() == tcell.KeyTab:
+			if list.HasFocus() {
+				app.SetFocus(detailsTextView)
+			} else {
+				app.SetFocus(list)
+			}
+			return nil
+		case event.Rune() == 'r':
+			loadTests()
+			return nil
+		}
+		return event
+	})
+
+	Overlays.AddPage(pipelineTestsOverlay, layout, true, true)
+	app.SetFocus(list)
+	loadTests()
+}
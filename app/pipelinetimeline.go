@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"text/tabwriter"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+const pipelineTimelineOverlay = "pipeline-timeline"
+
+var _timelineResultIcons = map[string]string{
+	"succeeded":           "[green]✔[-]",
+	"succeededWithIssues": "[yellow]![-]",
+	"failed":              "[red]✘[-]",
+	"canceled":            "[gray]⊘[-]",
+	"skipped":             "[gray]↷[-]",
+	"abandoned":           "[gray]⊘[-]",
+}
+
+var _timelineStateIcons = map[string]string{
+	"pending":    "[blue]○[-]",
+	"inProgress": "[yellow]●[-]",
+}
+
+// timelineRecordText is the single line shown in the tree for a record
+func timelineRecordText(record azuredevops.TimelineRecord) string {
+	icon := _timelineStateIcons[record.State]
+	if record.State == "completed" {
+		icon = _timelineResultIcons[record.Result]
+	}
+	if icon == "" {
+		icon = "[white]·[-]"
+	}
+
+	text := fmt.Sprintf("%s %s", icon, tview.Escape(record.Name))
+	if duration := record.Duration(); duration > 0 {
+		text += fmt.Sprintf(" [gray]%s[-]", duration)
+	}
+	if record.ErrorCount > 0 {
+		text += fmt.Sprintf(" [red]%d error(s)[-]", record.ErrorCount)
+	}
+	if record.WarningCount > 0 {
+		text += fmt.Sprintf(" [yellow]%d warning(s)[-]", record.WarningCount)
+	}
+	return text
+}
+
+// timelineRecordToDetailsData describes the selected record, including its issue messages
+func timelineRecordToDetailsData(record azuredevops.TimelineRecord) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', tabwriter.TabIndent)
+
+	var keyColor = "[blue]"
+	var valueColor = "[white]"
+
+	fmt.Fprintf(w, "%s%s%s\t%s\n", keyColor, record.Type, valueColor, tview.Escape(record.Name))
+	fmt.Fprintf(w, "%sState%s\t%s\n", keyColor, valueColor, cases.Title(language.English).String(record.State))
+	fmt.Fprintf(w, "%sResult%s\t%s\n", keyColor, valueColor, cases.Title(language.English).String(record.Result))
+	fmt.Fprintf(w, "%sDuration%s\t%s\n", keyColor, valueColor, record.Duration())
+	if record.WorkerName != "" {
+		fmt.Fprintf(w, "%sAgent%s\t%s\n", keyColor, valueColor, record.WorkerName)
+	}
+	fmt.Fprintf(w, "%sErrors / Warnings%s\t%d / %d\n", keyColor, valueColor, record.ErrorCount, record.WarningCount)
+	if record.Log != nil {
+		fmt.Fprintf(w, "%sLog%s\t#%d (press Enter or l to open)\n", keyColor, valueColor, record.Log.ID)
+	}
+	if len(record.Issues) > 0 {
+		fmt.Fprintf(w, "\n%sIssues%s\n", keyColor, valueColor)
+		for _, issue := range record.Issues {
+			color := "[yellow]"
+			if issue.Type == "error" {
+				color = "[red]"
+			}
+			fmt.Fprintf(w, "%s- %s[white]\n", color, tview.Escape(issue.Message))
+		}
+	}
+
+	w.Flush()
+	return buf.String()
+}
+
+// ShowPipelineRunTimeline shows the stages, jobs and tasks of the run as a tree
+func ShowPipelineRunTimeline(run azuredevops.PipelineRun, focus tview.Primitive) {
+	root := tview.NewTreeNode(fmt.Sprintf("%s %s", run.DefinitionName, run.BuildNumber)).
+		SetColor(tcell.ColorYellow).
+		SetSelectable(false)
+	tree := tview.NewTreeView().
+		SetRoot(root).
+		SetCurrentNode(root).
+		SetGraphicsColor(tcell.ColorGray)
+	tree.SetBorder(true).
+		SetTitle(" Timeline ")
+
+	detailsTextView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWordWrap(true)
+	detailsTextView.SetBorder(true)
+
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]Enter/l[white] open log  [yellow]Space[white] expand/collapse  [yellow]r[white] refresh  [yellow]q[white] close")
+
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(tree, 0, 3, true).
+		AddItem(detailsTextView, 0, 1, false).
+		AddItem(statusBar, 1, 0, false)
+
+	var addNodes func(parent *tview.TreeNode, nodes []*azuredevops.TimelineNode)
+	addNodes = func(parent *tview.TreeNode, nodes []*azuredevops.TimelineNode) {
+		for _, node := range nodes {
+			treeNode := tview.NewTreeNode(timelineRecordText(node.Record)).
+				SetReference(node.Record).
+				SetSelectable(true)
+			// Only expand what needs attention, succeeded stages and jobs stay collapsed
+			treeNode.SetExpanded(node.Record.Result != "succeeded" && node.Record.Result != "skipped")
+			parent.AddChild(treeNode)
+			addNodes(treeNode, node.Children)
+		}
+	}
+
+	selectedRecord := func() (azuredevops.TimelineRecord, bool) {
+		node := tree.GetCurrentNode()
+		if node == nil {
+			return azuredevops.TimelineRecord{}, false
+		}
+		record, ok := node.GetReference().(azuredevops.TimelineRecord)
+		return record, ok
+	}
+
+	openLog := func() {
+		record, ok := selectedRecord()
+		if !ok || record.Log == nil {
+			AnnounceError("❌ No log for this step")
+			return
+		}
+		ShowPipelineRunLogs(run, record.Log.ID, tree)
+	}
+
+	loadTimeline := func() {
+		detailsTextView.SetText("[yellow]Loading...[white]")
+		go func() {
+			records, err := client.GetPipelineRunTimeline(run.ID)
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					log.Printf("Error fetching timeline of run %d: %v", run.ID, err)
+					detailsTextView.SetText("[red]Error fetching timeline[white]")
+					return
+				}
+				root.ClearChildren()
+				addNodes(root, azuredevops.BuildTimelineTree(records))
+				detailsTextView.SetText("")
+				// Select the first failed task, the most likely reason to look at the timeline
+				var firstSelectable, firstFailed *tview.TreeNode
+				root.Walk(func(node, parent *tview.TreeNode) bool {
+					record, ok := node.GetReference().(azuredevops.TimelineRecord)
+					if !ok {
+						return true
+					}
+					if firstSelectable == nil {
+						firstSelectable = node
+					}
+					if firstFailed == nil && record.Type == "Task" && record.Result == "failed" {
+						firstFailed = node
+					}
+					return node.IsExpanded()
+				})
+				if firstFailed != nil {
+					tree.SetCurrentNode(firstFailed)
+				} else if firstSelectable != nil {
+					tree.SetCurrentNode(firstSelectable)
+				}
+				if record, ok := selectedRecord(); ok {
+					detailsTextView.SetText(timelineRecordToDetailsData(record))
+				}
+			})
+		}()
+	}
+
+	tree.SetChangedFunc(func(node *tview.TreeNode) {
+		if record, ok := node.GetReference().(azuredevops.TimelineRecord); ok {
+			detailsTextView.SetText(timelineRecordToDetailsData(record))
+			detailsTextView.ScrollToBeginning()
+		}
+	})
+	tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		openLog()
+	})
+
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			CloseOverlay(pipelineTimelineOverlay, focus)
+			return nil
+		case event.Rune() == ' ':
+			if node := tree.GetCurrentNode(); node != nil {
+				node.SetExpanded(!node.IsExpanded())
+			}
+			return nil
+		case event.Rune() == 'l':
+			openLog()
+			return nil
+		case event.Rune() == 'r':
+			loadTimeline()
+			return nil
+		}
+		return event
+	})
+
+	Overlays.AddPage(pipelineTimelineOverlay, layout, true, true)
+	app.SetFocus(tree)
+	loadTimeline()
+}
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"slices"
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/dustin/go-humanize"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"golang.org/x/text/cases"
@@ -40,7 +43,29 @@ func _prsToTableData(prs []azuredevops.PullRequestDetails) string {
 	return tableData
 }
 
-func _redrawTable(table *tview.Table, prs []azuredevops.PullRequestDetails) {
+// pullRequestFingerprint changes whenever the pull request changes in a way shown in the table or details,
+// like a new reviewer or vote
+func pullRequestFingerprint(pr azuredevops.PullRequestDetails) string {
+	return fmt.Sprintf("%s|%s|%s|%t|%v|%v", pr.Title, pr.Status, pr.MergeStatus, pr.IsDraft, pr.Reviewers, pr.ReviewersVotes)
+}
+
+func pullRequestID(pr azuredevops.PullRequestDetails) int { return pr.ID }
+
+// pullRequestsFilterFor narrows down the pull requests of the filter of the page
+func pullRequestsFilterFor(filter string, userMail string) azuredevops.PRsFilter {
+	switch filter {
+	case "mine":
+		return azuredevops.PRsFilter{Creator: userMail}
+	case "assigned-to-me":
+		return azuredevops.PRsFilter{Reviewer: userMail, Status: "active"}
+	case "all", "completed", "abandoned":
+		return azuredevops.PRsFilter{Status: filter}
+	default:
+		return azuredevops.PRsFilter{Status: "active"}
+	}
+}
+
+func _redrawTable(table *tview.Table, prs []azuredevops.PullRequestDetails, selection *ItemSelection) {
 	table.Clear()
 	tableData := _prsToTableData(prs)
 	for row, line := range strings.Split(tableData, "\n") {
@@ -101,6 +126,7 @@ func _redrawTable(table *tview.Table, prs []azuredevops.PullRequestDetails) {
 			table.SetCell(row, column, tableCell)
 		}
 	}
+	markSelectedRows(table, prs, pullRequestID, selection)
 	table.Select(0, 0)
 }
 
@@ -168,12 +194,17 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 	var currentMatchIndex int = -1
 	// Dropdown variables
 	pullRequestFilter := "mine"
+	if filter := startupFilter("prs"); filter != "" {
+		pullRequestFilter = filter
+	}
 
 	table := tview.NewTable().
 		SetFixed(1, 1).
 		SetBorders(false).
 		SetSelectable(true, false).
 		SetSeparator(' ')
+	// Items selected with space for the extensions
+	selection := NewItemSelection()
 
 	table.SetSelectedStyle(tcell.StyleDefault.
 		Foreground(tcell.ColorBlack).
@@ -220,10 +251,14 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 				Foreground(tcell.ColorBlack),
 		).
 		SetOptions([]string{"Mine", "Assigned to me", "All", "Active", "Completed", "Abandoned"}, nil)
-	dropdown.SetCurrentOption(0)
+	dropdown.SetCurrentOption(slices.Index(pullRequestFilters, pullRequestFilter))
 	searchStatus := tview.NewTextView().SetText("").SetTextAlign(tview.AlignRight)
 	actionsPanel.AddItem(dropdown, 0, 1, false)
 	actionsPanel.AddItem(searchStatus, 0, 1, false)
+	prsPager := newPager()
+	actionsPanel.AddItem(prsPager.Indicator, 28, 0, false)
+	// Set once the pull requests can be fetched
+	var loadMorePRs func()
 
 	mainWindow.AddItem(tableFlex, 0, 1, true)
 	mainWindow.AddItem(actionsPanel, 1, 1, false)
@@ -232,7 +267,7 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 	detailActionsPanel := tview.NewFlex().
 		SetDirection(tview.FlexColumn)
 	detailsPanel.AddItem(detailActionsPanel, 1, 1, false)
-	AttachPullRequestExtensions(detailActionsPanel, table, &prs)
+	AttachPullRequestExtensions(detailActionsPanel, table, &prs, selection)
 
 	// Handle table enter key (View Pull Request details)
 
@@ -246,7 +281,14 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 			if !currentPullRequest.IsDetailFetched {
 				loadingPRID = currentPullRequest.ID
 				go func() {
-					prs[index].GetMorePRDetails()
+					if _, err := prs[index].GetMorePRDetails(); err == nil {
+						SaveCacheItem("pullrequest-details", prs[index].ID, prs[index])
+					} else if cached, ok := LoadCacheItem[azuredevops.PullRequestDetails]("pullrequest-details", prs[index].ID); ok {
+						prs[index].Description = cached.Description
+						prs[index].RepositoryURL = cached.RepositoryURL
+						prs[index].WorkItemRefs = cached.WorkItemRefs
+						prs[index].IsDetailFetched = true
+					}
 					app.QueueUpdateDraw(func() {
 						details := prToDetailsData(&prs[index])
 						if loadingPRID == currentPullRequest.ID {
@@ -268,6 +310,9 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 		if currentIndex < 0 {
 			currentIndex = 0
 		}
+		if prsPager.shouldLoadMore(currentIndex, len(prs)) {
+			loadMorePRs()
+		}
 		if detailsVisible {
 			detailsTextView.SetText("")
 			displayPullRequestDetails(prs, currentIndex)
@@ -333,6 +378,24 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 		toggleDetailsPanel()
 	})
 
+	// Select the pull request of `lazyaz open` and show its details, listing it first when it is not listed
+	openPullRequest := func(pr *azuredevops.PullRequestDetails, index int) {
+		if pr != nil {
+			prs = append([]azuredevops.PullRequestDetails{*pr}, prs...)
+			_redrawTable(table, prs, selection)
+			index = 0
+		}
+		currentIndex = index
+		table.Select(index+1, 0)
+		if !detailsVisible {
+			toggleDetailsPanel()
+		}
+		app.SetFocus(table)
+	}
+	fetchPullRequest := func(id int) (*azuredevops.PullRequestDetails, error) {
+		return client.GetPRDetails(strconv.Itoa(id))
+	}
+
 	// Handle search
 	closeSearch := func() {
 		searchMode = false
@@ -417,11 +480,30 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 			}
 		})
 
+	// Fetch the first page of the pull requests of the current filter, falling back to the cached one
+	fetchPRs := func() (azuredevops.Page[azuredevops.PullRequestDetails], time.Time, error) {
+		return fetchCached(cacheName("pullrequests", pullRequestFilter), func() (azuredevops.Page[azuredevops.PullRequestDetails], error) {
+			return client.GetPRsPage(pullRequestsFilterFor(pullRequestFilter, activeUser.Mail), "")
+		})
+	}
+
+	// Load the next page of pull requests when the selection gets near the end of the table
+	loadMorePRs = func() {
+		loadNextPage(prsPager, len(prs), func(continuationToken string) (azuredevops.Page[azuredevops.PullRequestDetails], error) {
+			return client.GetPRsPage(pullRequestsFilterFor(pullRequestFilter, activeUser.Mail), continuationToken)
+		}, func(more []azuredevops.PullRequestDetails) {
+			rowOffset, _ := table.GetOffset()
+			prs = appendMissing(prs, more, pullRequestID)
+			prsPager.show(len(prs))
+			_redrawTable(table, prs, selection)
+			restoreTableSelection(table, currentIndex, rowOffset)
+		})
+	}
+
 	// Handle dropdown selection of Pull Requests
 	dropdown.SetSelectedFunc(func(text string, index int) {
 		app.SetFocus(table)
 		var potentialPullRequestFilter string
-		var getPRsFunc func() ([]azuredevops.PullRequestDetails, error)
 		switch text {
 		case "Mine":
 			potentialPullRequestFilter = "mine"
@@ -429,16 +511,12 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 			potentialPullRequestFilter = "assigned-to-me"
 		case "All":
 			potentialPullRequestFilter = "all"
-			getPRsFunc = client.GetAllPRs
 		case "Active":
 			potentialPullRequestFilter = "active"
-			getPRsFunc = client.GetActivePRs
 		case "Completed":
 			potentialPullRequestFilter = "completed"
-			getPRsFunc = client.GetCompletedPRs
 		case "Abandoned":
 			potentialPullRequestFilter = "abandoned"
-			getPRsFunc = client.GetAbandonedPRs
 		}
 		if potentialPullRequestFilter != pullRequestFilter {
 			pullRequestFilter = potentialPullRequestFilter
@@ -457,22 +535,19 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 		// Refresh the pull requests
 		go func() {
 			dropdown.SetLabel("Fetching ")
-			var err error
-			if pullRequestFilter == "mine" {
-				prs, err = client.GetPRsCreatedByUser(activeUser.Mail, "")
-			} else if pullRequestFilter == "assigned-to-me" {
-				prs, err = client.GetPRsAssignedToUser(activeUser.Mail)
-			} else {
-				prs, err = getPRsFunc()
-			}
+			page, cachedAt, err := fetchPRs()
+			prs = page.Items
 			if err != nil {
 				log.Printf("Error fetching pull requests: %v", err)
+			} else if !cachedAt.IsZero() {
+				AnnounceCached("pull requests", cachedAt)
 			}
 			if len(prs) > 0 {
 				app.QueueUpdateDraw(func() {
 					dropdown.SetLabel("")
+					resetPager(prsPager, page)
 					// currentIndex = 0
-					_redrawTable(table, prs)
+					_redrawTable(table, prs, selection)
 					// closeDetailPanel()
 					app.SetFocus(table)
 					table.Select(0, 0)
@@ -480,6 +555,7 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 			} else {
 				app.QueueUpdateDraw(func() {
 					dropdown.SetLabel("")
+					resetPager(prsPager, page)
 					table.Clear()
 					table.SetCell(0, 0, tview.NewTableCell("No pull requests found. Try other filters (press \\ and Up or Down)").
 						SetTextColor(tcell.ColorRed).
@@ -494,43 +570,36 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 	loadData := func() {
 		select {
 		case isFetching <- true:
-			var err error
-			if pullRequestFilter == "mine" {
-				prs, err = client.GetPRsCreatedByUser(activeUser.Mail, "")
-			} else if pullRequestFilter == "assigned-to-me" {
-				prs, err = client.GetPRsAssignedToUser(activeUser.Mail)
-			} else {
-				switch pullRequestFilter {
-				case "all":
-					prs, err = client.GetAllPRs()
-				case "active":
-					prs, err = client.GetActivePRs()
-				case "completed":
-					prs, err = client.GetCompletedPRs()
-				case "abandoned":
-					prs, err = client.GetAbandonedPRs()
-				}
-			}
+			// Details are fetched again after a refresh
+			client.Invalidate(azuredevops.CachePullRequest)
+			page, cachedAt, err := fetchPRs()
+			prs = page.Items
 			if err != nil {
 				log.Printf("Error fetching pull requests: %v", err)
 				AnnounceError("❌ Error fetching pull requests")
+			} else if !cachedAt.IsZero() {
+				AnnounceCached("pull requests", cachedAt)
 			} else {
 				Announce("✅ Refresh done", 3)
 			}
 			<-isFetching // Release the lock
 			if len(prs) > 0 {
 				app.QueueUpdateDraw(func() {
-					_redrawTable(table, prs)
+					resetPager(prsPager, page)
+					_redrawTable(table, prs, selection)
 					if detailsVisible {
 						displayCurrentPullRequestDetails()
 					}
+					openStartupItem(azuredevops.ItemPullRequest, prs, pullRequestID, fetchPullRequest, openPullRequest)
 				})
 			} else {
 				app.QueueUpdateDraw(func() {
+					resetPager(prsPager, page)
 					table.Clear()
 					table.SetCell(0, 0, tview.NewTableCell("No pull requests found. Try other filters (press \\ and Up or Down)").
 						SetTextColor(tcell.ColorRed).
 						SetAlign(tview.AlignCenter))
+					openStartupItem(azuredevops.ItemPullRequest, prs, pullRequestID, fetchPullRequest, openPullRequest)
 				})
 			}
 		default:
@@ -538,8 +607,60 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 			return
 		}
 	}
+	// Show the cached pull requests while the fresh ones load
+	if cached, savedAt, ok := LoadCache[azuredevops.Page[azuredevops.PullRequestDetails]](cacheName("pullrequests", pullRequestFilter)); ok && len(cached.Items) > 0 {
+		prs = cached.Items
+		resetPager(prsPager, cached)
+		_redrawTable(table, prs, selection)
+		Announce(fmt.Sprintf("⏳ Showing pull requests cached %s while refreshing...", humanize.Time(savedAt)), -1)
+	}
 	go loadData()
 
+	// Refresh in the background, highlighting the pull requests that appeared or changed
+	refreshInBackground := func() {
+		select {
+		case isFetching <- true:
+			latest, cachedAt, err := fetchPRs()
+			<-isFetching
+			if err != nil || !cachedAt.IsZero() {
+				log.Printf("Error refreshing pull requests: %v", err)
+				return
+			}
+			app.QueueUpdateDraw(func() {
+				selectedID := -1
+				if currentIndex >= 0 && currentIndex < len(prs) {
+					selectedID = prs[currentIndex].ID
+				}
+				rowOffset, _ := table.GetOffset()
+				var changed map[int]bool
+				// The further pages loaded are kept as they are
+				refreshed := keepLoadedPages(prsPager, prs, latest, pullRequestID)
+				prs, changed = mergeRefreshed(prs, refreshed, pullRequestID, pullRequestFingerprint)
+				if len(prs) == 0 {
+					return
+				}
+				for index := range changed {
+					client.Invalidate(azuredevops.CachePullRequest, prs[index].ID)
+				}
+				prsPager.show(len(prs))
+				_redrawTable(table, prs, selection)
+				highlightRows(table, changed)
+				currentIndex = max(indexOfID(prs, pullRequestID, selectedID), 0)
+				restoreTableSelection(table, currentIndex, rowOffset)
+				if detailsVisible {
+					displayCurrentPullRequestDetails()
+				}
+				if len(changed) > 0 {
+					Announce(fmt.Sprintf("🔄 %d pull request(s) changed", len(changed)), 3)
+				}
+			})
+		default:
+			// Another fetch is in progress, skip this one
+			return
+		}
+	}
+	StartAutoRefresh("pullrequests", refreshInBackground, func() bool { return searchMode })
+
 	mainWindow.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		// Handle search mode activation with "/"
 		if event.Rune() == '/' {
@@ -578,6 +699,55 @@ func PullRequestsPage(nextSlide func()) (title string, content tview.Primitive)
 			go loadData()
 			return nil
 		}
+
+		// Handle 'c' and 'C' keys to checkout the PR source branch or merge ref locally
+		if (event.Rune() == 'c' || event.Rune() == 'C') && !searchMode {
+			if currentIndex < 0 || currentIndex >= len(prs) {
+				return nil
+			}
+			pr := prs[currentIndex]
+			useMergeRef := event.Rune() == 'C'
+			Announce(fmt.Sprintf("⏳ Checking out PR %d...", pr.ID), -1)
+			go func() {
+				message, err := CheckoutPullRequest(pr, useMergeRef)
+				app.QueueUpdateDraw(func() {
+					if err != nil {
+						log.Printf("Error checking out PR %d: %v", pr.ID, err)
+						AnnounceError(fmt.Sprintf("❌ Checkout failed: %v", err))
+					} else {
+						Announce("✅ "+message, 0)
+					}
+				})
+			}()
+			return nil
+		}
+
+		// Handle 'w' key to watch the selected PR
+		if event.Rune() == 'w' && !searchMode {
+			if currentIndex >= 0 && currentIndex < len(prs) {
+				pr := prs[currentIndex]
+				ToggleWatch(Watch{
+					Kind:  WatchPullRequest,
+					ID:    pr.ID,
+					Title: pr.Title,
+					State: pullRequestWatchState(pr, -1),
+				})
+			}
+			return nil
+		}
+
+		// Handle space to select the highlighted item for the extensions
+		if event.Key() == tcell.KeyRune && event.Rune() == ' ' && !searchMode {
+			toggleSelectedRow(table, prs, pullRequestID, selection, currentIndex)
+			return nil
+		}
+
+		// Handle the keys of the extensions, running against the selected items or the highlighted one
+		if !searchMode && HandleExtensionKey("pullrequests", event, selectedItems(prs, pullRequestID, selection, currentIndex), table, func() {
+			clearSelectedRows(table, prs, pullRequestID, selection)
+		}) {
+			return nil
+		}
 		return event
 	})
 
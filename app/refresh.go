@@ -0,0 +1,149 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Background color of the rows that appeared or changed since the previous refresh
+var RefreshHighlightColor = tcell.ColorDarkSlateGray
+
+const (
+	defaultIdleAfter   = 2 * time.Minute
+	maxRefreshInterval = 15 * time.Minute
+)
+
+// lastInteraction is the time (unix nanoseconds) of the last key press or mouse event
+var lastInteraction atomic.Int64
+
+// MarkInteraction records that the user is active in the terminal
+func MarkInteraction() {
+	lastInteraction.Store(time.Now().UnixNano())
+}
+
+// nextRefreshWait is the interval while the user is active. While the terminal is idle,
+// the interval doubles for every idleAfter period without interaction, up to maxRefreshInterval.
+func nextRefreshWait(interval time.Duration, idleAfter time.Duration, idle time.Duration) time.Duration {
+	if idleAfter <= 0 || idle < idleAfter {
+		return interval
+	}
+	wait := interval
+	for periods := idle / idleAfter; periods > 0 && wait < maxRefreshInterval; periods-- {
+		wait *= 2
+	}
+	return min(wait, maxRefreshInterval)
+}
+
+// Stops of the auto-refreshes started, see StopAutoRefresh
+var (
+	autoRefreshStops   []chan struct{}
+	autoRefreshStopsMu sync.Mutex
+)
+
+// StartAutoRefresh calls refresh in the background every interval of the page, backing off while idle.
+// The refresh is skipped while paused, e.g. while searching, paused being called on the UI thread.
+// Nothing is started when auto-refresh is disabled for the page or while offline.
+func StartAutoRefresh(page string, refresh func(), paused func() bool) {
+	interval := AppSettings.RefreshIntervalFor(page)
+	if interval <= 0 || OfflineMode {
+		return
+	}
+	idleAfter := AppSettings.IdleAfter()
+	stop := make(chan struct{})
+	autoRefreshStopsMu.Lock()
+	autoRefreshStops = append(autoRefreshStops, stop)
+	autoRefreshStopsMu.Unlock()
+	logger.Debug("Auto-refresh enabled", "page", page, "interval", interval)
+	go func() {
+		for {
+			idle := time.Since(time.Unix(0, lastInteraction.Load()))
+			timer := time.NewTimer(nextRefreshWait(interval, idleAfter, idle))
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			skip := false
+			if paused != nil {
+				app.QueueUpdate(func() {
+					skip = paused()
+				})
+			}
+			if skip {
+				logger.Debug("Auto-refresh paused", "page", page)
+				continue
+			}
+			refresh()
+		}
+	}()
+}
+
+// StopAutoRefresh stops the auto-refreshes of all the pages
+func StopAutoRefresh() {
+	autoRefreshStopsMu.Lock()
+	defer autoRefreshStopsMu.Unlock()
+	for _, stop := range autoRefreshStops {
+		close(stop)
+	}
+	autoRefreshStops = nil
+}
+
+// mergeRefreshed compares the refreshed items to the previous ones by ID.
+// Items whose fingerprint did not change are kept from the previous items, along with anything fetched for them.
+// Returns the merged items and the indexes of the items that appeared or changed.
+// Nothing is reported as changed when there were no previous items.
+func mergeRefreshed[T any](previous []T, current []T, id func(T) int, fingerprint func(T) string) ([]T, map[int]bool) {
+	previousByID := make(map[int]T, len(previous))
+	for _, item := range previous {
+		previousByID[id(item)] = item
+	}
+	merged := make([]T, len(current))
+	changed := map[int]bool{}
+	for i, item := range current {
+		old, existed := previousByID[id(item)]
+		if existed && fingerprint(old) == fingerprint(item) {
+			merged[i] = old
+			continue
+		}
+		merged[i] = item
+		if len(previous) > 0 {
+			changed[i] = true
+		}
+	}
+	return merged, changed
+}
+
+// indexOfID returns the index of the item with the ID, or -1 if it is gone
+func indexOfID[T any](items []T, id func(T) int, wanted int) int {
+	for i, item := range items {
+		if id(item) == wanted {
+			return i
+		}
+	}
+	return -1
+}
+
+// highlightRows sets the background of the table rows of the changed items, the header being row 0
+func highlightRows(table *tview.Table, changed map[int]bool) {
+	for index := range changed {
+		for column := 0; column < table.GetColumnCount(); column++ {
+			if cell := table.GetCell(index+1, column); cell != nil {
+				cell.SetBackgroundColor(RefreshHighlightColor)
+			}
+		}
+	}
+}
+
+// restoreTableSelection selects the row of the item again and keeps the table scrolled where it was
+func restoreTableSelection(table *tview.Table, index int, rowOffset int) {
+	if index < 0 {
+		index = 0
+	}
+	table.SetOffset(rowOffset, 0)
+	table.Select(index+1, 0)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNextRefreshWait(t *testing.T) {
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		idleAfter time.Duration
+		idle      time.Duration
+		expected  time.Duration
+	}{
+		{"active", time.Minute, 2 * time.Minute, 30 * time.Second, time.Minute},
+		{"idle for one period", time.Minute, 2 * time.Minute, 2 * time.Minute, 2 * time.Minute},
+		{"idle for three periods", time.Minute, 2 * time.Minute, 7 * time.Minute, 8 * time.Minute},
+		{"idle for long", time.Minute, 2 * time.Minute, 2 * time.Hour, maxRefreshInterval},
+		{"interval above the maximum", 20 * time.Minute, 2 * time.Minute, time.Hour, maxRefreshInterval},
+		{"no back off", time.Minute, 0, time.Hour, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextRefreshWait(tt.interval, tt.idleAfter, tt.idle); got != tt.expected {
+				t.Errorf("nextRefreshWait(%v, %v, %v) = %v, expected %v", tt.interval, tt.idleAfter, tt.idle, got, tt.expected)
+			}
+		})
+	}
+}
+
+type refreshedItem struct {
+	ID      int
+	State   string
+	Fetched bool
+}
+
+func TestMergeRefreshed(t *testing.T) {
+	id := func(item refreshedItem) int { return item.ID }
+	fingerprint := func(item refreshedItem) string { return item.State }
+
+	tests := []struct {
+		name            string
+		previous        []refreshedItem
+		current         []refreshedItem
+		expected        []refreshedItem
+		expectedChanged []int
+	}{
+		{
+			name:     "first load",
+			current:  []refreshedItem{{ID: 1, State: "new"}},
+			expected: []refreshedItem{{ID: 1, State: "new"}},
+		},
+		{
+			name:     "unchanged items keep what was fetched",
+			previous: []refreshedItem{{ID: 1, State: "new", Fetched: true}},
+			current:  []refreshedItem{{ID: 1, State: "new"}},
+			expected: []refreshedItem{{ID: 1, State: "new", Fetched: true}},
+		},
+		{
+			name:            "changed and new items",
+			previous:        []refreshedItem{{ID: 1, State: "new", Fetched: true}, {ID: 2, State: "new"}},
+			current:         []refreshedItem{{ID: 3, State: "new"}, {ID: 1, State: "done"}, {ID: 2, State: "new"}},
+			expected:        []refreshedItem{{ID: 3, State: "new"}, {ID: 1, State: "done"}, {ID: 2, State: "new"}},
+			expectedChanged: []int{0, 1},
+		},
+		{
+			name:     "removed items",
+			previous: []refreshedItem{{ID: 1, State: "new"}, {ID: 2, State: "new"}},
+			current:  []refreshedItem{{ID: 2, State: "new"}},
+			expected: []refreshedItem{{ID: 2, State: "new"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, changed := mergeRefreshed(tt.previous, tt.current, id, fingerprint)
+			if !slices.Equal(merged, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, merged)
+			}
+			if got := slices.Sorted(maps.Keys(changed)); !slices.Equal(got, tt.expectedChanged) {
+				t.Errorf("Expected the changed indexes %v, got %v", tt.expectedChanged, got)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"slices"
+	"sort"
 )
 
 // Registry holds all registered extensions
@@ -18,17 +19,9 @@ func NewRegistry() *Registry {
 }
 
 // InitRegistry initializes the registry with extensions from the configuration
-func InitRegistry() *Registry {
+func InitRegistry(appConfig *AppConfig) *Registry {
 	registry := NewRegistry()
 
-	// Find and load configuration
-	appConfig, configPath, err := FindConfig()
-	if err != nil {
-		logger.Error("Error loading configuration", "error", err)
-		return registry
-	}
-	logger.Debug(fmt.Sprintf("Loaded configuration from %s", configPath))
-
 	// Register extensions from config
 	if appConfig.Extensions != nil {
 		for id, extension := range appConfig.Extensions {
@@ -43,6 +36,7 @@ func InitRegistry() *Registry {
 				logger.Warn(fmt.Sprintf("Skipping extension %s, entry point not found", id))
 				continue
 			}
+			extension.ID = id
 			registry.Extensions[id] = extension
 			logger.Debug(fmt.Sprintf("Registered extension: %s - %s", id, extension.Name))
 		}
@@ -57,7 +51,7 @@ func (r *Registry) Get(id string) (ExtensionConfig, bool) {
 	return extension, exists
 }
 
-// GetFor returns a list of extensions that apply to the specified domain
+// GetFor returns a list of extensions that apply to the specified domain, by ID
 func (r *Registry) GetFor(domain string) []ExtensionConfig {
 	var result []ExtensionConfig
 	var allowedDomains = []string{"workitems", "pullrequests", "pipelines"}
@@ -70,6 +64,34 @@ func (r *Registry) GetFor(domain string) []ExtensionConfig {
 			result = append(result, extension)
 		}
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result
+}
 
+// KeyBindings returns the keys of the extensions that apply to the specified domain: the keys of lazyaz.toml
+// running the extensions, then the keys bound by the scripts. The first of the same keys is used.
+func (r *Registry) KeyBindings(domain string) []ExtensionKeyBinding {
+	var result []ExtensionKeyBinding
+	for _, extension := range r.GetFor(domain) {
+		if extension.Key == "" {
+			continue
+		}
+		binding, err := extension.KeyBinding()
+		if err != nil {
+			continue
+		}
+		result = append(result, ExtensionKeyBinding{Key: binding, Description: extension.Name, Extension: extension})
+	}
+	for _, extension := range r.GetFor(domain) {
+		if extension.KindOf() != ExtensionKindScript {
+			continue
+		}
+		script, err := LoadScriptExtension(extension.ID, extension)
+		if err != nil {
+			continue
+		}
+		result = append(result, script.bindings...)
+	}
 	return result
 }
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// scriptItem is a work item, pull request or pipeline run given to a script. Its attributes are the fields
+// of WorkItem, PullRequestDetails or PipelineRun in snake case, e.g. item.assigned_to, along with item.kind and item.web_url.
+type scriptItem struct {
+	kind   string
+	value  interface{}
+	fields starlark.StringDict
+}
+
+var _ starlark.HasAttrs = (*scriptItem)(nil)
+
+// newScriptItem converts the work item, pull request or pipeline run to a script value
+func newScriptItem(domain interface{}) (*scriptItem, error) {
+	item, err := describeExtensionItem(domain)
+	if err != nil {
+		return nil, err
+	}
+	fields := starlark.StringDict{}
+	structFields(reflect.ValueOf(domain), fields)
+	fields["kind"] = starlark.String(item.Kind)
+	fields["web_url"] = starlark.String(item.URL)
+	return &scriptItem{kind: item.Kind, value: domain, fields: fields}, nil
+}
+
+func (i *scriptItem) String() string {
+	return fmt.Sprintf("<%s %s>", i.kind, i.fields["id"])
+}
+func (i *scriptItem) Type() string          { return i.kind }
+func (i *scriptItem) Freeze()               { i.fields.Freeze() }
+func (i *scriptItem) Truth() starlark.Bool  { return starlark.True }
+func (i *scriptItem) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: %s", i.kind) }
+func (i *scriptItem) Attr(name string) (starlark.Value, error) {
+	return i.fields[name], nil
+}
+func (i *scriptItem) AttrNames() []string {
+	names := i.fields.Keys()
+	sort.Strings(names)
+	return names
+}
+
+// snakeCase converts a field name to snake case, keeping acronyms together, e.g. RepositoryApiURL is repository_api_url
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			previousLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if previousLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// structFields adds the exported fields of the struct, and of its embedded structs, by their snake case names
+func structFields(v reflect.Value, fields starlark.StringDict) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			structFields(v.Field(i), fields)
+			continue
+		}
+		fields[snakeCase(field.Name)] = toStarlark(v.Field(i))
+	}
+}
+
+// toStarlark converts a Go value to a script value: structs are structs of their fields in snake case,
+// times are RFC 3339 strings and nil values are None
+func toStarlark(v reflect.Value) starlark.Value {
+	if !v.IsValid() {
+		return starlark.None
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return starlark.None
+		}
+		return starlark.String(t.Format(time.RFC3339))
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return starlark.None
+		}
+		return toStarlark(v.Elem())
+	case reflect.String:
+		return starlark.String(v.String())
+	case reflect.Bool:
+		return starlark.Bool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return starlark.MakeInt64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return starlark.MakeUint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return starlark.Float(v.Float())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return starlark.NewList(nil)
+		}
+		elems := make([]starlark.Value, v.Len())
+		for i := range elems {
+			elems[i] = toStarlark(v.Index(i))
+		}
+		return starlark.NewList(elems)
+	case reflect.Map:
+		dict := starlark.NewDict(v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			dict.SetKey(starlark.String(fmt.Sprint(iter.Key().Interface())), toStarlark(iter.Value()))
+		}
+		return dict
+	case reflect.Struct:
+		fields := starlark.StringDict{}
+		structFields(v, fields)
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+	default:
+		return starlark.String(fmt.Sprint(v.Interface()))
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Background color of the rows selected for the extensions
+var SelectionColor = tcell.ColorNavy
+
+// ItemSelection is the items of a table selected with space, by ID. Extensions run against the selected items.
+type ItemSelection struct {
+	ids map[int]bool
+}
+
+// NewItemSelection creates an empty selection
+func NewItemSelection() *ItemSelection {
+	return &ItemSelection{ids: map[int]bool{}}
+}
+
+// Toggle selects the item, or unselects it when it was selected. Returns whether it is selected.
+func (s *ItemSelection) Toggle(id int) bool {
+	if s.ids[id] {
+		delete(s.ids, id)
+		return false
+	}
+	s.ids[id] = true
+	return true
+}
+
+// Has reports whether the item is selected
+func (s *ItemSelection) Has(id int) bool {
+	return s.ids[id]
+}
+
+// Len returns the number of selected items
+func (s *ItemSelection) Len() int {
+	return len(s.ids)
+}
+
+// setRowBackground sets the background of the table row, the header being row 0. Without a color the row is reset.
+func setRowBackground(table *tview.Table, row int, color tcell.Color) {
+	for column := 0; column < table.GetColumnCount(); column++ {
+		cell := table.GetCell(row, column)
+		if cell == nil {
+			continue
+		}
+		if color == tcell.ColorDefault {
+			cell.SetBackgroundColor(tview.Styles.PrimitiveBackgroundColor).SetTransparency(true)
+		} else {
+			cell.SetBackgroundColor(color)
+		}
+	}
+}
+
+// toggleSelectedRow selects or unselects the item at the index and moves to the next row
+func toggleSelectedRow[T any](table *tview.Table, items []T, id func(T) int, selection *ItemSelection, index int) {
+	if index < 0 || index >= len(items) {
+		return
+	}
+	if selection.Toggle(id(items[index])) {
+		setRowBackground(table, index+1, SelectionColor)
+	} else {
+		setRowBackground(table, index+1, tcell.ColorDefault)
+	}
+	if index+1 < len(items) {
+		table.Select(index+2, 0)
+	}
+}
+
+// markSelectedRows sets the background of the table rows of the selected items, after the table is redrawn
+func markSelectedRows[T any](table *tview.Table, items []T, id func(T) int, selection *ItemSelection) {
+	if selection == nil {
+		return
+	}
+	for index, item := range items {
+		if selection.Has(id(item)) {
+			setRowBackground(table, index+1, SelectionColor)
+		}
+	}
+}
+
+// clearSelectedRows unselects all the items and resets their rows
+func clearSelectedRows[T any](table *tview.Table, items []T, id func(T) int, selection *ItemSelection) {
+	for index, item := range items {
+		if selection.Has(id(item)) {
+			setRowBackground(table, index+1, tcell.ColorDefault)
+		}
+	}
+	clear(selection.ids)
+}
+
+// selectedItems returns the selected items that are still listed, or the item at the index when none is selected
+func selectedItems[T any](items []T, id func(T) int, selection *ItemSelection, index int) []interface{} {
+	var result []interface{}
+	for _, item := range items {
+		if selection.Has(id(item)) {
+			result = append(result, item)
+		}
+	}
+	if len(result) == 0 && index >= 0 && index < len(items) {
+		result = append(result, items[index])
+	}
+	return result
+}
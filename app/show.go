@@ -0,0 +1,351 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/gdamore/tcell/v2"
+)
+
+const showUsageText = `Usage:
+  lazyaz show wi|pr|run <id-or-url>
+  lazyaz show <url>
+  lazyaz open [wi|pr|run] <id-or-url>
+
+A URL of Azure DevOps tells the kind of item. For open, an ID without a kind is a work item.
+`
+
+// Item opened in the details panel when the application starts, see `lazyaz open`
+var StartupItem *azuredevops.ItemRef
+
+// parseItemArgs parses "<kind> <id-or-url>" or "<url>", using the default kind for an ID alone
+func parseItemArgs(args []string, defaultKind string) (azuredevops.ItemRef, error) {
+	switch len(args) {
+	case 1:
+		return azuredevops.ParseItemRef(defaultKind, args[0])
+	case 2:
+		kind, err := azuredevops.ParseItemKind(args[0])
+		if err != nil {
+			return azuredevops.ItemRef{}, err
+		}
+		return azuredevops.ParseItemRef(kind, args[1])
+	default:
+		fmt.Fprint(os.Stderr, showUsageText)
+		return azuredevops.ItemRef{}, fmt.Errorf("expected an ID or a URL")
+	}
+}
+
+// newItemFlags creates the flags of show and open
+func newItemFlags(name string, opts *Options) *flag.FlagSet {
+	flags := flag.NewFlagSet("lazyaz "+name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), showUsageText)
+		fmt.Fprintln(flags.Output(), "\nFlags:")
+		flags.PrintDefaults()
+	}
+	opts.registerConnectionFlags(flags)
+	return flags
+}
+
+// ParseOpenArgs parses the item and flags of `lazyaz open`
+func ParseOpenArgs(args []string, opts *Options) (*azuredevops.ItemRef, error) {
+	flags := newItemFlags("open", opts)
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	ref, err := parseItemArgs(flags.Args(), azuredevops.ItemWorkItem)
+	if err != nil {
+		return nil, err
+	}
+	return &ref, nil
+}
+
+// RunShowCommand prints the details of the item, as shown in the details panel.
+// Colors are left out when the output is not a terminal.
+func RunShowCommand(args []string, w *os.File, opts *Options) error {
+	flags := newItemFlags("show", opts)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ref, err := parseItemArgs(flags.Args(), "")
+	if err != nil {
+		return err
+	}
+	if err := connect(opts); err != nil {
+		return err
+	}
+	colors := IsTerminal(w) && os.Getenv("NO_COLOR") == ""
+	details, err := fetchItemDetailsData(ref)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(w, tagsToANSI(details, colors))
+	if colors {
+		fmt.Fprint(w, "\033[0m")
+	}
+	return nil
+}
+
+const exportUsageText = `Usage:
+  lazyaz export [--template <name>] [--to clipboard|file|stdout|comment] [--file <path>] wi|pr|run <id-or-url>
+  lazyaz export --list wi|pr|run
+
+Renders the item with a template of the export_to_template extension, read from its templates_directory.
+The output is the one of the template, stdout by default.
+`
+
+// RunExportCommand renders the item with a template, or lists the templates of a kind of item
+func RunExportCommand(args []string, w io.Writer, opts *Options) error {
+	flags := flag.NewFlagSet("lazyaz export", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), exportUsageText)
+		fmt.Fprintln(flags.Output(), "\nFlags:")
+		flags.PrintDefaults()
+	}
+	opts.registerConnectionFlags(flags)
+	name := flags.String("template", "", "Name of the template, the first one by default")
+	to := flags.String("to", "", "Output: "+strings.Join(TemplateOutputs, ", "))
+	file := flags.String("file", "", "File written by the file output, a template of the item such as {{.ID}}.md")
+	list := flags.Bool("list", false, "List the templates of the kind of item")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *to != "" && !validTemplateOutput(*to) {
+		return fmt.Errorf("unknown output %q, available outputs: %s", *to, strings.Join(TemplateOutputs, ", "))
+	}
+
+	if *list {
+		if flags.NArg() != 1 {
+			fmt.Fprint(os.Stderr, exportUsageText)
+			return fmt.Errorf("expected a kind of item")
+		}
+		kind, err := azuredevops.ParseItemKind(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		config, path, _ := loadAppConfig(opts.ConfigPath)
+		ConfigPath = path
+		templates, err := LoadTemplates(exportExtension(config).TemplatesDir(), kind)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, t := range templates {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", t.Name, t.Output, t.Description)
+		}
+		return tw.Flush()
+	}
+
+	ref, err := parseItemArgs(flags.Args(), "")
+	if err != nil {
+		return err
+	}
+	if err := connect(opts); err != nil {
+		return err
+	}
+	extension := exportExtension(AppSettings)
+	templates, err := LoadTemplates(extension.TemplatesDir(), ref.Kind)
+	if err != nil {
+		return err
+	}
+	t, err := FindTemplate(templates, *name)
+	if err != nil {
+		return err
+	}
+	output, outputFile := extension.TemplateOutput(t)
+	switch {
+	case *to != "":
+		output = *to
+	case t.Output == "":
+		output = TemplateOutputStdout
+	}
+	if *file != "" {
+		// Relative to the working directory rather than lazyaz.toml
+		if outputFile, err = filepath.Abs(expandHome(*file)); err != nil {
+			return err
+		}
+	}
+
+	item, err := fetchItem(ref)
+	if err != nil {
+		return err
+	}
+	text, where, err := ExportItems(t, []interface{}{item}, output, outputFile)
+	if err != nil {
+		return err
+	}
+	if output == TemplateOutputStdout {
+		fmt.Fprint(w, text)
+	} else {
+		fmt.Fprintf(os.Stderr, "Exported %d with %s %s\n", ref.ID, t.Name, where)
+	}
+	return nil
+}
+
+// fetchItem fetches the work item, pull request or pipeline run
+func fetchItem(ref azuredevops.ItemRef) (interface{}, error) {
+	switch ref.Kind {
+	case azuredevops.ItemWorkItem:
+		workItem, err := client.GetWorkItem(ref.ID)
+		if err != nil {
+			return nil, err
+		}
+		return *workItem, nil
+	case azuredevops.ItemPullRequest:
+		pr, err := client.GetPRDetails(strconv.Itoa(ref.ID))
+		if err != nil {
+			return nil, err
+		}
+		return *pr, nil
+	case azuredevops.ItemPipelineRun:
+		run, err := client.GetPipelineRun(ref.ID)
+		if err != nil {
+			return nil, err
+		}
+		return *run, nil
+	default:
+		return nil, fmt.Errorf("unknown kind of item: %s", ref.Kind)
+	}
+}
+
+// openStartupItem opens the item of `lazyaz open` once a page of its kind loaded its items.
+// The item is fetched when not listed, then given to open, otherwise open gets its index.
+func openStartupItem[T any](kind string, items []T, id func(T) int, fetch func(id int) (*T, error), open func(item *T, index int)) {
+	if StartupItem == nil || StartupItem.Kind != kind {
+		return
+	}
+	wanted := StartupItem.ID
+	StartupItem = nil
+	if index := indexOfID(items, id, wanted); index >= 0 {
+		open(nil, index)
+		return
+	}
+	go func() {
+		item, err := fetch(wanted)
+		app.QueueUpdateDraw(func() {
+			if err != nil {
+				logger.Error("Error opening the item", "id", wanted, "error", err)
+				AnnounceError(fmt.Sprintf("❌ Error opening %d", wanted))
+				return
+			}
+			open(item, -1)
+		})
+	}()
+}
+
+// fetchItemDetailsData fetches the item with all of its details, and formats it like the details panel
+func fetchItemDetailsData(ref azuredevops.ItemRef) (string, error) {
+	switch ref.Kind {
+	case azuredevops.ItemWorkItem:
+		workItem, err := client.GetWorkItem(ref.ID)
+		if err != nil {
+			return "", err
+		}
+		if _, err := workItem.GetMoreWorkItemDetails(); err != nil {
+			return "", err
+		}
+		if _, err := workItem.GetPRDetails(client); err != nil {
+			logger.Warn("Error fetching the pull requests of the work item", "error", err)
+		}
+		return workItemToDetailsData(workItem), nil
+	case azuredevops.ItemPullRequest:
+		pr, err := client.GetPRDetails(strconv.Itoa(ref.ID))
+		if err != nil {
+			return "", err
+		}
+		return prToDetailsData(pr), nil
+	case azuredevops.ItemPipelineRun:
+		run, err := client.GetPipelineRun(ref.ID)
+		if err != nil {
+			return "", err
+		}
+		_, artifactsErr := run.GetArtifacts(client)
+		return pipelineRunToDetailsData(run, artifactsErr), nil
+	default:
+		return "", fmt.Errorf("unknown kind of item: %s", ref.Kind)
+	}
+}
+
+// IsTerminal reports whether the file is a terminal rather than a pipe or a file
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Style tags of the details panel, e.g. [blue], [-], [white:black] or [::b]. A tag is only a style
+// when its colors are color names of tview, see isStyleTag.
+var colorTagPattern = regexp.MustCompile(`\[([a-zA-Z]+|#[0-9a-fA-F]{6}|-)?(?::([a-zA-Z]+|#[0-9a-fA-F]{6}|-)?)?(?::([a-zA-Z-]*))?\]`)
+
+// Tags escaped with tview.Escape, e.g. [x[] for [x]
+var escapedTagPattern = regexp.MustCompile(`\[([^\[\]]*)\[\]`)
+
+var ansiColors = map[string]string{
+	"black":     "\033[30m",
+	"red":       "\033[31m",
+	"green":     "\033[32m",
+	"limegreen": "\033[92m",
+	"yellow":    "\033[33m",
+	"orange":    "\033[33m",
+	"blue":      "\033[34m",
+	"purple":    "\033[35m",
+	"fuchsia":   "\033[95m",
+	"teal":      "\033[36m",
+	"gray":      "\033[90m",
+	"grey":      "\033[90m",
+}
+
+// isStyleTag reports whether the parts of the tag are colors and attributes, like tview does.
+// Other bracketed text, e.g. [x], is left as is.
+func isStyleTag(foreground, background, attributes string, tag string) bool {
+	isColor := func(color string) bool {
+		if color == "" || color == "-" || strings.HasPrefix(color, "#") {
+			return true
+		}
+		_, ok := tcell.ColorNames[strings.ToLower(color)]
+		return ok
+	}
+	return tag != "[]" && isColor(foreground) && isColor(background) && strings.Trim(attributes, "-lbidrsu") == ""
+}
+
+// tagsToANSI converts the style tags of the details panel to ANSI escape codes, or strips them without colors.
+// Backgrounds are left out.
+func tagsToANSI(text string, colors bool) string {
+	text = colorTagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		parts := colorTagPattern.FindStringSubmatch(tag)
+		foreground, attributes := strings.ToLower(parts[1]), parts[3]
+		if !isStyleTag(foreground, parts[2], attributes, tag) {
+			return tag
+		}
+		if !colors {
+			return ""
+		}
+		var codes strings.Builder
+		switch {
+		case foreground == "":
+		case ansiColors[foreground] != "":
+			codes.WriteString(ansiColors[foreground])
+		case foreground == "-" || foreground == "white":
+			// The default color
+			codes.WriteString("\033[39m")
+		default:
+			r, g, b := tcell.GetColor(foreground).RGB()
+			fmt.Fprintf(&codes, "\033[38;2;%d;%d;%dm", r, g, b)
+		}
+		switch {
+		case attributes == "-":
+			codes.WriteString("\033[22;23;24m")
+		case strings.Contains(attributes, "b"):
+			codes.WriteString("\033[1m")
+		}
+		return codes.String()
+	})
+	return escapedTagPattern.ReplaceAllString(text, "[$1]")
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestTagsToANSI(t *testing.T) {
+	tests := []struct {
+		name           string
+		text           string
+		expectedColors string
+		expectedPlain  string
+	}{
+		{"no tags", "Build 42", "Build 42", "Build 42"},
+		{"basic color", "[red]failed[white]", "\033[31mfailed\033[39m", "failed"},
+		{"color not in the basic colors", "[fuchsia]waiting[-]", "\033[95mwaiting\033[39m", "waiting"},
+		{"named color", "[darkorange]x", "\033[38;2;255;140;0mx", "x"},
+		{"hex color", "[#ff8800]x[-]", "\033[38;2;255;136;0mx\033[39m", "x"},
+		{"upper case color", "[RED]x", "\033[31mx", "x"},
+		{"default color", "[-]x", "\033[39mx", "x"},
+		{"background only", "[:blue]x", "x", "x"},
+		{"bold", "[::b]Title[::-]", "\033[1mTitle\033[22;23;24m", "Title"},
+		{"color and attributes", "[green::b]ok", "\033[32m\033[1mok", "ok"},
+		{"escaped tag", "[x[] done", "[x] done", "[x] done"},
+		{"escaped color", "[red[]", "[red]", "[red]"},
+		{"not a style", "[x] done", "[x] done", "[x] done"},
+		{"unknown color", "[notacolor]x", "[notacolor]x", "[notacolor]x"},
+		{"empty brackets", "list[]", "list[]", "list[]"},
+		{"unknown attribute", "[::z]x", "[::z]x", "[::z]x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tagsToANSI(tt.text, true); got != tt.expectedColors {
+				t.Errorf("tagsToANSI(%q, true) = %q, expected %q", tt.text, got, tt.expectedColors)
+			}
+			if got := tagsToANSI(tt.text, false); got != tt.expectedPlain {
+				t.Errorf("tagsToANSI(%q, false) = %q, expected %q", tt.text, got, tt.expectedPlain)
+			}
+		})
+	}
+}
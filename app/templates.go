@@ -0,0 +1,547 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
+)
+
+// Where the rendered templates go
+const (
+	TemplateOutputClipboard = "clipboard"
+	TemplateOutputFile      = "file"
+	TemplateOutputStdout    = "stdout"
+	TemplateOutputComment   = "comment"
+)
+
+var TemplateOutputs = []string{TemplateOutputClipboard, TemplateOutputFile, TemplateOutputStdout, TemplateOutputComment}
+
+// Name of the template of <kind>.md, and of the built-in template when there is no template for the kind
+const defaultTemplateName = "default"
+
+// ItemTemplate is a template of an item kind, read from the templates directory.
+// The front matter of the file, between "---" lines, may set its description, output and file.
+type ItemTemplate struct {
+	Name        string `yaml:"-"`
+	Kind        string `yaml:"-"`
+	Path        string `yaml:"-"`
+	Description string `yaml:"description"`
+	Output      string `yaml:"output"`
+	// File written by the file output, itself a template of the item, e.g. "~/notes/{{.ID}}.md"
+	File string `yaml:"file"`
+	Body string `yaml:"-"`
+}
+
+// TemplatesDir returns the directory of the templates of the extension, ~/.lazyaz/templates by default
+func (e ExtensionConfig) TemplatesDir() string {
+	if e.TemplatesDirectory != "" {
+		return resolveConfigPath(e.TemplatesDirectory)
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".lazyaz", "templates")
+}
+
+// LoadTemplates returns the templates of the kind in the directory: <kind>.md, named "default",
+// then the files of the <kind> directory by name. The built-in template is returned when there are none.
+func LoadTemplates(dir string, kind string) ([]ItemTemplate, error) {
+	var templates []ItemTemplate
+	if content, err := os.ReadFile(filepath.Join(dir, kind+".md")); err == nil {
+		t, err := parseItemTemplate(defaultTemplateName, kind, filepath.Join(dir, kind+".md"), string(content))
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, kind))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading the templates: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, kind, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the template: %v", err)
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		t, err := parseItemTemplate(name, kind, path, string(content))
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	if len(templates) == 0 {
+		body, ok := defaultTemplates[kind]
+		if !ok {
+			return nil, fmt.Errorf("unsupported domain type: %s", kind)
+		}
+		templates = append(templates, ItemTemplate{Name: defaultTemplateName, Kind: kind, Description: "Built-in template", Body: body})
+	}
+	return templates, nil
+}
+
+// FindTemplate returns the template of the name, the first template when no name is given
+func FindTemplate(templates []ItemTemplate, name string) (ItemTemplate, error) {
+	if name == "" {
+		return templates[0], nil
+	}
+	var names []string
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+		names = append(names, t.Name)
+	}
+	return ItemTemplate{}, fmt.Errorf("unknown template %q, available templates: %s", name, strings.Join(names, ", "))
+}
+
+// parseItemTemplate separates the front matter from the body and checks that the body parses
+func parseItemTemplate(name, kind, path, content string) (ItemTemplate, error) {
+	t := ItemTemplate{}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if rest, ok := strings.CutPrefix(content, "---\n"); ok {
+		frontMatter, body, found := strings.Cut(rest, "\n---\n")
+		if !found {
+			return t, fmt.Errorf("template %s: the front matter is not closed with ---", path)
+		}
+		if err := yaml.Unmarshal([]byte(frontMatter), &t); err != nil {
+			return t, fmt.Errorf("template %s: error parsing the front matter: %v", path, err)
+		}
+		content = body
+	}
+	t.Name, t.Kind, t.Path, t.Body = name, kind, path, content
+	if t.Output != "" && !validTemplateOutput(t.Output) {
+		return t, fmt.Errorf("template %s: unknown output %q, available outputs: %s", path, t.Output, strings.Join(TemplateOutputs, ", "))
+	}
+	if _, err := template.New(name).Funcs(templateFuncs).Parse(t.Body); err != nil {
+		return t, fmt.Errorf("template %s: %v", path, err)
+	}
+	return t, nil
+}
+
+func validTemplateOutput(output string) bool {
+	return slices.Contains(TemplateOutputs, output)
+}
+
+// Render applies the template to the item, a pointer to a WorkItem, PullRequestDetails or PipelineRun
+// so that the details fetched by the template are kept
+func (t ItemTemplate) Render(item interface{}) (string, error) {
+	return renderTemplate(t.Name, t.Body, item)
+}
+
+func renderTemplate(name string, body string, item interface{}) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %s", err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, item); err != nil {
+		return "", fmt.Errorf("failed to execute template: %s", err)
+	}
+	return rendered.String(), nil
+}
+
+// Functions of the templates. Details, pull requests, attachments and artifacts are fetched when used.
+var templateFuncs = template.FuncMap{
+	"date":           templateDate,
+	"stripHTML":      normalizeDataString,
+	"escapeMarkdown": escapeMarkdown,
+	"join":           templateJoin,
+	"default":        templateDefault,
+	"lower":          strings.ToLower,
+	"upper":          strings.ToUpper,
+	"trim":           strings.TrimSpace,
+	"replace":        func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"url":            templateURL,
+	"details":        templateDetails,
+	"prs":            templatePRs,
+	"attachments":    templateAttachments,
+	"artifacts":      templateArtifacts,
+}
+
+// templateDate formats the time in the local time zone, e.g. {{date "2006-01-02" .CreatedDate}}. Unset times are empty.
+func templateDate(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	location := localTzLocation
+	if location == nil {
+		location = time.Local
+	}
+	return t.In(location).Format(layout)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "{", `\{`, "}", `\}`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "(", `\(`, ")", `\)`, "#", `\#`, "+", `\+`, "-", `\-`, "!", `\!`, "|", `\|`,
+)
+
+// escapeMarkdown escapes the characters with a meaning in markdown
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// templateJoin joins the elements of a list, e.g. {{join ", " .Reviewers}}
+func templateJoin(sep string, list interface{}) (string, error) {
+	v := reflect.ValueOf(list)
+	if !v.IsValid() {
+		return "", nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: expected a list, got %s", v.Type())
+	}
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// templateDefault returns the value, or the default when the value is empty, e.g. {{.AssignedTo | default "Unassigned"}}
+func templateDefault(fallback interface{}, value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.IsZero() || ((v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0) {
+		return fallback
+	}
+	return value
+}
+
+// templateURL returns the web URL of the item
+func templateURL(item interface{}) (string, error) {
+	described, err := describeExtensionItem(derefItem(item))
+	return described.URL, err
+}
+
+// derefItem returns the item a pointer points to
+func derefItem(item interface{}) interface{} {
+	if v := reflect.ValueOf(item); v.Kind() == reflect.Pointer && !v.IsNil() {
+		return v.Elem().Interface()
+	}
+	return item
+}
+
+// templateDetails fetches the details of a work item, or of a pull request
+func templateDetails(item interface{}) (interface{}, error) {
+	switch v := item.(type) {
+	case *azuredevops.WorkItem:
+		if v.Details == nil {
+			if _, err := v.GetMoreWorkItemDetails(); err != nil {
+				return nil, err
+			}
+		}
+		return v.Details, nil
+	case *azuredevops.PullRequestDetails:
+		if !v.IsDetailFetched {
+			if _, err := v.GetMorePRDetails(); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("details: expected a work item or a pull request, got %T", item)
+	}
+}
+
+// templatePRs fetches the pull requests of a work item
+func templatePRs(item interface{}) ([]azuredevops.PullRequestDetails, error) {
+	workItem, ok := item.(*azuredevops.WorkItem)
+	if !ok {
+		return nil, fmt.Errorf("prs: expected a work item, got %T", item)
+	}
+	if _, err := templateDetails(workItem); err != nil {
+		return nil, err
+	}
+	return workItem.GetPRDetails(client)
+}
+
+// templateAttachments fetches the attachments of a work item, by name
+func templateAttachments(item interface{}) ([]azuredevops.Attachment, error) {
+	workItem, ok := item.(*azuredevops.WorkItem)
+	if !ok {
+		return nil, fmt.Errorf("attachments: expected a work item, got %T", item)
+	}
+	if _, err := templateDetails(workItem); err != nil {
+		return nil, err
+	}
+	attachments := slices.Clone(workItem.Details.Attachments)
+	sort.SliceStable(attachments, func(i, j int) bool { return attachments[i].Attributes.Name < attachments[j].Attributes.Name })
+	return attachments, nil
+}
+
+// templateArtifacts fetches the artifacts of a pipeline run
+func templateArtifacts(item interface{}) ([]azuredevops.PipelineArtifact, error) {
+	run, ok := item.(*azuredevops.PipelineRun)
+	if !ok {
+		return nil, fmt.Errorf("artifacts: expected a pipeline run, got %T", item)
+	}
+	return run.GetArtifacts(client)
+}
+
+// TemplateOutput returns where the template goes and the file of the file output: those of the front matter
+// of the template, otherwise those of the extension, the clipboard and <kind>-<id>.md by default
+func (e ExtensionConfig) TemplateOutput(t ItemTemplate) (output string, file string) {
+	output, file = t.Output, t.File
+	if output == "" {
+		output = e.Output
+	}
+	if output == "" {
+		output = TemplateOutputClipboard
+	}
+	if file == "" {
+		file = e.OutputFile
+	}
+	if file == "" {
+		file = t.Kind + "-{{.ID}}.md"
+	}
+	return output, file
+}
+
+// itemPointer copies the work item, pull request or pipeline run so the template can keep what it fetches
+func itemPointer(item interface{}) (interface{}, error) {
+	switch v := item.(type) {
+	case azuredevops.WorkItem:
+		return &v, nil
+	case azuredevops.PullRequestDetails:
+		return &v, nil
+	case azuredevops.PipelineRun:
+		return &v, nil
+	default:
+		return nil, fmt.Errorf("unsupported domain type: %s", reflect.TypeOf(item))
+	}
+}
+
+// ExportItems renders the template for each of the items and sends the text to the output. Items written to
+// the same file are written together. Returns the text and where it went, e.g. "to the clipboard".
+func ExportItems(t ItemTemplate, items []interface{}, output string, file string) (string, string, error) {
+	var texts []string
+	var pointers []interface{}
+	for _, item := range items {
+		pointer, err := itemPointer(item)
+		if err != nil {
+			return "", "", err
+		}
+		text, err := t.Render(pointer)
+		if err != nil {
+			return "", "", err
+		}
+		texts = append(texts, text)
+		pointers = append(pointers, pointer)
+	}
+	joined := strings.Join(texts, "\n")
+
+	switch output {
+	case TemplateOutputClipboard:
+		if err := copyToClipboard(joined); err != nil {
+			return joined, "", err
+		}
+		return joined, "to the clipboard", nil
+	case TemplateOutputStdout:
+		return joined, "", nil
+	case TemplateOutputFile:
+		paths, contents, err := templateFiles(file, pointers, texts)
+		if err != nil {
+			return joined, "", err
+		}
+		for _, path := range paths {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return joined, "", fmt.Errorf("error creating the directory of %s: %v", path, err)
+			}
+			if err := os.WriteFile(path, []byte(contents[path]), 0644); err != nil {
+				return joined, "", fmt.Errorf("error writing %s: %v", path, err)
+			}
+		}
+		if len(paths) == 1 {
+			return joined, "to " + paths[0], nil
+		}
+		return joined, fmt.Sprintf("to %d files", len(paths)), nil
+	case TemplateOutputComment:
+		for i, pointer := range pointers {
+			var err error
+			switch v := pointer.(type) {
+			case *azuredevops.WorkItem:
+				err = client.AddWorkItemComment(v.ID, texts[i])
+			case *azuredevops.PullRequestDetails:
+				err = client.AddPullRequestComment(v, texts[i])
+			default:
+				err = fmt.Errorf("comments are added to work items and pull requests")
+			}
+			if err != nil {
+				return joined, "", err
+			}
+		}
+		return joined, "as a comment", nil
+	default:
+		return joined, "", fmt.Errorf("unknown output %q, available outputs: %s", output, strings.Join(TemplateOutputs, ", "))
+	}
+}
+
+// templateFiles renders the path of the file output for each item, ~ being the home directory and relative paths
+// being relative to lazyaz.toml like templates_directory. Returns the paths in order along with the texts of
+// their items, joined as in the other outputs.
+func templateFiles(file string, items []interface{}, texts []string) ([]string, map[string]string, error) {
+	var paths []string
+	contents := map[string]string{}
+	for i, item := range items {
+		path, err := renderTemplate("file", file, item)
+		if err != nil {
+			return nil, nil, fmt.Errorf("file: %v", err)
+		}
+		path = resolveConfigPath(strings.TrimSpace(path))
+		if previous, ok := contents[path]; ok {
+			contents[path] = previous + "\n" + texts[i]
+		} else {
+			paths = append(paths, path)
+			contents[path] = texts[i]
+		}
+	}
+	return paths, contents, nil
+}
+
+const templatePickerOverlay = "template-picker"
+
+// RunExportToTemplate exports the items with the template picked by the user, in the background.
+// The picker is skipped when there is a single template. The stdout output is shown in a panel.
+func RunExportToTemplate(extension ExtensionConfig, items []interface{}, focus tview.Primitive, onDone func(err error)) {
+	fail := func(err error) {
+		logger.Error("Export to template failed", "error", err)
+		AnnounceError(tview.Escape(fmt.Sprintf("❌ %s failed: %s", extension.Name, firstLine(err.Error()))))
+		onDone(err)
+	}
+	item, err := describeExtensionItem(items[0])
+	if err != nil {
+		fail(err)
+		return
+	}
+	templates, err := LoadTemplates(extension.TemplatesDir(), item.Kind)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	exportInBackground := func(t ItemTemplate, output, file string) {
+		Announce(fmt.Sprintf("⏳ Exporting with %s...", t.Name), -1)
+		go func() {
+			text, where, err := ExportItems(t, items, output, file)
+			app.QueueUpdateDraw(func() {
+				switch {
+				case err != nil:
+					fail(err)
+					return
+				case output == TemplateOutputStdout:
+					Announce("", 0)
+					showTemplateOutput(t, text, focus)
+				case len(items) == 1:
+					Announce(fmt.Sprintf("✅ [green]Exported %d with %s %s[white]", item.ID, t.Name, tview.Escape(where)), 0)
+				default:
+					Announce(fmt.Sprintf("✅ [green]Exported %d items with %s %s[white]", len(items), t.Name, tview.Escape(where)), 0)
+				}
+				onDone(nil)
+			})
+		}()
+	}
+	export := func(t ItemTemplate) {
+		output, file := extension.TemplateOutput(t)
+		run := func() { exportInBackground(t, output, file) }
+		if !extension.Confirm {
+			run()
+			return
+		}
+		message := fmt.Sprintf("Export %d %s with %s to %s?", item.ID, item.Title, t.Name, output)
+		if len(items) > 1 {
+			message = fmt.Sprintf("Export %d items with %s to %s?", len(items), t.Name, output)
+		}
+		ShowConfirm(message, focus, run)
+	}
+	if len(templates) == 1 {
+		export(templates[0])
+		return
+	}
+	showTemplatePicker(templates, focus, export)
+}
+
+// showTemplatePicker lists the templates, the picked one being given to onPick
+func showTemplatePicker(templates []ItemTemplate, focus tview.Primitive, onPick func(t ItemTemplate)) {
+	list := tview.NewList().
+		SetHighlightFullLine(true).
+		SetSelectedBackgroundColor(tcell.ColorLimeGreen).
+		SetSelectedTextColor(tcell.ColorBlack).
+		SetSecondaryTextColor(tcell.ColorGray)
+	list.SetBorder(true).
+		SetTitle(" Templates ")
+	for i, t := range templates {
+		var shortcut rune
+		if i < 9 {
+			shortcut = rune('1' + i)
+		}
+		description := t.Description
+		if t.Output != "" {
+			description = strings.TrimSpace(description + " → " + t.Output)
+		}
+		list.AddItem(tview.Escape(t.Name), tview.Escape(description), shortcut, func() {
+			CloseOverlay(templatePickerOverlay, focus)
+			onPick(t)
+		})
+	}
+	list.SetDoneFunc(func() {
+		CloseOverlay(templatePickerOverlay, focus)
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'q' {
+			CloseOverlay(templatePickerOverlay, focus)
+			return nil
+		}
+		return event
+	})
+	ShowOverlay(templatePickerOverlay, list, 60, min(2*len(templates)+2, 20))
+}
+
+// showTemplateOutput shows the rendered template of the stdout output, the terminal being used by the application
+func showTemplateOutput(t ItemTemplate, text string, focus tview.Primitive) {
+	textView := tview.NewTextView().
+		SetScrollable(true).
+		SetText(text)
+	textView.SetBorder(true).
+		SetTitle(fmt.Sprintf(" %s ", t.Name))
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]↑/↓[white] scroll  [yellow]c[white] copy  [yellow]q[white] close")
+
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(textView, 0, 1, true).
+		AddItem(statusBar, 1, 0, false)
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			CloseOverlay(extensionResultOverlay, focus)
+			return nil
+		case event.Rune() == 'c':
+			if err := copyToClipboard(text); err != nil {
+				AnnounceError(fmt.Sprintf("❌ %v", err))
+			} else {
+				Announce("✅ Copied to the clipboard", 0)
+			}
+			return nil
+		}
+		return event
+	})
+
+	Overlays.AddPage(extensionResultOverlay, layout, true, true)
+	app.SetFocus(textView)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+)
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain text", "plain text"},
+		{"*bold* and _italic_", `\*bold\* and \_italic\_`},
+		{"[link](url)", `\[link\]\(url\)`},
+		{"# title", `\# title`},
+		{"a | b", `a \| b`},
+		{"`code`", "\\`code\\`"},
+		{`C:\path`, `C:\\path`},
+		{"<tag>", `\<tag\>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := escapeMarkdown(tt.input); got != tt.expected {
+				t.Errorf("escapeMarkdown(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTemplateDefault(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+	}{
+		{"nil", nil, "fallback"},
+		{"empty string", "", "fallback"},
+		{"zero", 0, "fallback"},
+		{"empty list", []string{}, "fallback"},
+		{"empty map", map[string]string{}, "fallback"},
+		{"string", "value", "value"},
+		{"number", 7, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := templateDefault("fallback", tt.value); got != tt.expected {
+				t.Errorf("templateDefault(%v) = %v, expected %v", tt.value, got, tt.expected)
+			}
+		})
+	}
+
+	if got := templateDefault("fallback", []string{"a"}); !slices.Equal(got.([]string), []string{"a"}) {
+		t.Errorf("Expected the list, got %v", got)
+	}
+}
+
+func TestTemplateJoin(t *testing.T) {
+	tests := []struct {
+		name        string
+		list        interface{}
+		expected    string
+		expectedErr bool
+	}{
+		{"nil", nil, "", false},
+		{"empty list", []string{}, "", false},
+		{"strings", []string{"a", "b", "c"}, "a, b, c", false},
+		{"numbers", []int{1, 2}, "1, 2", false},
+		{"array", [2]string{"a", "b"}, "a, b", false},
+		{"not a list", "a", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := templateJoin(", ", tt.list)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("Expected an error: %v, got %v", tt.expectedErr, err)
+			}
+			if got != tt.expected {
+				t.Errorf("templateJoin(%v) = %q, expected %q", tt.list, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTemplateDate(t *testing.T) {
+	previous := localTzLocation
+	localTzLocation = time.FixedZone("UTC+2", 2*60*60)
+	defer func() { localTzLocation = previous }()
+
+	tests := []struct {
+		name     string
+		layout   string
+		time     time.Time
+		expected string
+	}{
+		{"unset", "2006-01-02", time.Time{}, ""},
+		{"date", "2006-01-02", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), "2024-03-01"},
+		{"local time zone", "2006-01-02 15:04", time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC), "2024-03-02 01:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := templateDate(tt.layout, tt.time); got != tt.expected {
+				t.Errorf("templateDate(%q, %v) = %q, expected %q", tt.layout, tt.time, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseItemTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    ItemTemplate
+		expectedErr string
+	}{
+		{
+			name:     "no front matter",
+			content:  "# {{.Title}}\n",
+			expected: ItemTemplate{Body: "# {{.Title}}\n"},
+		},
+		{
+			name:    "front matter",
+			content: "---\ndescription: Standup notes\noutput: file\nfile: \"~/notes/{{.ID}}.md\"\n---\n- {{.Title}}\n",
+			expected: ItemTemplate{
+				Description: "Standup notes",
+				Output:      TemplateOutputFile,
+				File:        "~/notes/{{.ID}}.md",
+				Body:        "- {{.Title}}\n",
+			},
+		},
+		{
+			name:     "windows line endings",
+			content:  "---\r\noutput: stdout\r\n---\r\n{{.Title}}\r\n",
+			expected: ItemTemplate{Output: TemplateOutputStdout, Body: "{{.Title}}\n"},
+		},
+		{
+			name:        "front matter not closed",
+			content:     "---\noutput: file\n{{.Title}}\n",
+			expectedErr: "the front matter is not closed",
+		},
+		{
+			name:        "invalid front matter",
+			content:     "---\noutput: [file\n---\n{{.Title}}\n",
+			expectedErr: "error parsing the front matter",
+		},
+		{
+			name:        "unknown output",
+			content:     "---\noutput: printer\n---\n{{.Title}}\n",
+			expectedErr: `unknown output "printer"`,
+		},
+		{
+			name:        "unknown function",
+			content:     "{{.Title | shout}}\n",
+			expectedErr: `function "shout" not defined`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseItemTemplate("standup", "wi", "wi/standup.md", tt.content)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected an error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			tt.expected.Name, tt.expected.Kind, tt.expected.Path = "standup", "wi", "wi/standup.md"
+			if got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTemplateFiles(t *testing.T) {
+	previous := ConfigPath
+	ConfigPath = "/home/user/.lazyaz/lazyaz.toml"
+	defer func() { ConfigPath = previous }()
+
+	items := []interface{}{
+		&azuredevops.WorkItem{ID: 1, State: "Active"},
+		&azuredevops.WorkItem{ID: 2, State: "Closed"},
+		&azuredevops.WorkItem{ID: 3, State: "Active"},
+	}
+	texts := []string{"one", "two", "three"}
+
+	tests := []struct {
+		name             string
+		file             string
+		expectedPaths    []string
+		expectedContents map[string]string
+		expectedErr      bool
+	}{
+		{
+			name:          "a file per item",
+			file:          "/notes/{{.ID}}.md",
+			expectedPaths: []string{"/notes/1.md", "/notes/2.md", "/notes/3.md"},
+			expectedContents: map[string]string{
+				"/notes/1.md": "one",
+				"/notes/2.md": "two",
+				"/notes/3.md": "three",
+			},
+		},
+		{
+			name:          "items with the same path",
+			file:          "/notes/{{.State | lower}}.md",
+			expectedPaths: []string{"/notes/active.md", "/notes/closed.md"},
+			expectedContents: map[string]string{
+				"/notes/active.md": "one\nthree",
+				"/notes/closed.md": "two",
+			},
+		},
+		{
+			name:             "relative to the configuration file",
+			file:             " notes/all.md\n",
+			expectedPaths:    []string{"/home/user/.lazyaz/notes/all.md"},
+			expectedContents: map[string]string{"/home/user/.lazyaz/notes/all.md": "one\ntwo\nthree"},
+		},
+		{
+			name:        "invalid path template",
+			file:        "{{.ID",
+			expectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, contents, err := templateFiles(tt.file, items, texts)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("Expected an error: %v, got %v", tt.expectedErr, err)
+			}
+			if !slices.Equal(paths, tt.expectedPaths) {
+				t.Errorf("Expected the paths %v, got %v", tt.expectedPaths, paths)
+			}
+			for path, expected := range tt.expectedContents {
+				if contents[path] != expected {
+					t.Errorf("Expected %s to contain %q, got %q", path, expected, contents[path])
+				}
+			}
+		})
+	}
+}
+
+func TestExportItemsToFiles(t *testing.T) {
+	dir := t.TempDir()
+	previous := ConfigPath
+	ConfigPath = filepath.Join(dir, "lazyaz.toml")
+	defer func() { ConfigPath = previous }()
+
+	tmpl := ItemTemplate{Name: "default", Kind: "wi", Body: "- {{.ID}} {{.Title}}"}
+	items := []interface{}{
+		azuredevops.WorkItem{ID: 1, Title: "First", State: "Active"},
+		azuredevops.WorkItem{ID: 2, Title: "Second", State: "Active"},
+		azuredevops.WorkItem{ID: 3, Title: "Third", State: "Closed"},
+	}
+
+	text, destination, err := ExportItems(tmpl, items, TemplateOutputFile, "notes/{{.State}}.md")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if expected := "- 1 First\n- 2 Second\n- 3 Third"; text != expected {
+		t.Errorf("Expected the text %q, got %q", expected, text)
+	}
+	if destination != "to 2 files" {
+		t.Errorf("Expected the destination %q, got %q", "to 2 files", destination)
+	}
+
+	for name, expected := range map[string]string{
+		"Active.md": "- 1 First\n- 2 Second",
+		"Closed.md": "- 3 Third",
+	} {
+		content, err := os.ReadFile(filepath.Join(dir, "notes", name))
+		if err != nil {
+			t.Fatalf("Expected %s to be written, got %v", name, err)
+		}
+		if string(content) != expected {
+			t.Errorf("Expected %s to contain %q, got %q", name, expected, content)
+		}
+	}
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/aldnav/lazyaz/pkg/azuredevops"
+	"github.com/dustin/go-humanize"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Kinds of the items that can be watched
+const (
+	WatchWorkItem    = "workitem"
+	WatchPullRequest = "pullrequest"
+	WatchPipelineRun = "pipelinerun"
+)
+
+const (
+	defaultWatchInterval = time.Minute
+	maxNotifications     = 100
+	notificationsOverlay = "notifications"
+)
+
+// Watch is an item the user is notified about when it changes.
+// State is what the item looked like when it was last checked.
+type Watch struct {
+	Kind  string            `toml:"kind"`
+	ID    int               `toml:"id"`
+	Title string            `toml:"title"`
+	State map[string]string `toml:"state,omitempty"`
+}
+
+// Describe the watched item, e.g. "PR 42 Fix the login"
+func (w Watch) String() string {
+	switch w.Kind {
+	case WatchWorkItem:
+		return fmt.Sprintf("Work item %d %s", w.ID, w.Title)
+	case WatchPullRequest:
+		return fmt.Sprintf("PR %d %s", w.ID, w.Title)
+	default:
+		return fmt.Sprintf("Pipeline run %s", w.Title)
+	}
+}
+
+// Notification is a change of a watched item the user was notified about
+type Notification struct {
+	Time    time.Time
+	Message string
+}
+
+// Watcher checks the watched items in the background and keeps the history of the notifications
+type Watcher struct {
+	mu            sync.Mutex
+	watches       []Watch
+	notifications []Notification
+}
+
+// Watches are the items watched by the user, persisted across sessions
+var Watches = &Watcher{}
+
+// GetWatchesPath returns the file where the watched items are kept
+func GetWatchesPath() string {
+	return filepath.Join(filepath.Dir(GetDefaultConfigPath()), "watches.toml")
+}
+
+// watchesFile is the layout of the watches file, one [[watch]] table per item
+type watchesFile struct {
+	Watches []Watch `toml:"watch"`
+}
+
+// Load reads the watched items of the previous sessions
+func (w *Watcher) Load() error {
+	var saved watchesFile
+	if _, err := toml.DecodeFile(GetWatchesPath(), &saved); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading watches: %v", err)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watches = saved.Watches
+	return nil
+}
+
+// save writes the watched items, the lock must be held
+func (w *Watcher) save() error {
+	path := GetWatchesPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating config directory: %v", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error writing watches: %v", err)
+	}
+	defer file.Close()
+	if err := toml.NewEncoder(file).Encode(watchesFile{Watches: w.watches}); err != nil {
+		return fmt.Errorf("error encoding watches: %v", err)
+	}
+	return nil
+}
+
+func (w *Watcher) indexOf(kind string, id int) int {
+	return slices.IndexFunc(w.watches, func(watch Watch) bool {
+		return watch.Kind == kind && watch.ID == id
+	})
+}
+
+// IsWatched reports whether the item is watched
+func (w *Watcher) IsWatched(kind string, id int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.indexOf(kind, id) >= 0
+}
+
+// Toggle starts watching the item, or stops watching it when it was watched.
+// Returns whether the item is now watched.
+func (w *Watcher) Toggle(watch Watch) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if index := w.indexOf(watch.Kind, watch.ID); index >= 0 {
+		w.watches = slices.Delete(w.watches, index, index+1)
+		return false, w.save()
+	}
+	w.watches = append(w.watches, watch)
+	return true, w.save()
+}
+
+// List returns a copy of the watched items
+func (w *Watcher) List() []Watch {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.watches)
+}
+
+// Notifications returns the notifications of the session, the latest first
+func (w *Watcher) Notifications() []Notification {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	notifications := slices.Clone(w.notifications)
+	slices.Reverse(notifications)
+	return notifications
+}
+
+// Start checks the watched items for changes in the background
+func (w *Watcher) Start() {
+	interval := AppSettings.WatchInterval()
+	logger.Debug("Watching items", "interval", interval)
+	go func() {
+		for {
+			time.Sleep(interval)
+			w.check()
+		}
+	}()
+}
+
+// check fetches the watched items and notifies about the changes.
+// Finished pipeline runs are not watched anymore.
+func (w *Watcher) check() {
+	watches := w.List()
+	if len(watches) == 0 {
+		return
+	}
+	var messages []string
+	states := map[int]map[string]string{}
+	for i, watch := range watches {
+		state, err := fetchWatchState(watch)
+		if err != nil {
+			log.Printf("Error checking watched %s %d: %v", watch.Kind, watch.ID, err)
+			continue
+		}
+		states[i] = state
+		messages = append(messages, watchChanges(watch, state)...)
+	}
+
+	w.mu.Lock()
+	for i, state := range states {
+		// The item may have been unwatched while it was fetched
+		index := w.indexOf(watches[i].Kind, watches[i].ID)
+		if index < 0 {
+			continue
+		}
+		if watches[i].Kind == WatchPipelineRun && state["status"] == "completed" {
+			w.watches = slices.Delete(w.watches, index, index+1)
+			continue
+		}
+		w.watches[index].State = state
+	}
+	if err := w.save(); err != nil {
+		log.Printf("Error saving watches: %v", err)
+	}
+	w.mu.Unlock()
+
+	for _, message := range messages {
+		w.notify(message)
+	}
+}
+
+// notify adds the message to the history and shows it in the status bar,
+// and as a desktop or terminal notification when enabled
+func (w *Watcher) notify(message string) {
+	w.mu.Lock()
+	w.notifications = append(w.notifications, Notification{Time: time.Now(), Message: message})
+	if len(w.notifications) > maxNotifications {
+		w.notifications = w.notifications[len(w.notifications)-maxNotifications:]
+	}
+	w.mu.Unlock()
+
+	app.QueueUpdateDraw(func() {
+		Announce("🔔 "+tview.Escape(message), 10)
+	})
+	if AppSettings == nil {
+		return
+	}
+	if AppSettings.Notifications.Desktop {
+		if _, err := exec.LookPath("notify-send"); err == nil {
+			if err := exec.Command("notify-send", "lazyaz", message).Run(); err != nil {
+				log.Printf("Error sending desktop notification: %v", err)
+			}
+		}
+	}
+	if AppSettings.Notifications.Terminal {
+		select {
+		case terminalNotifications <- message:
+		default:
+			log.Printf("Dropping terminal notification: %s", message)
+		}
+	}
+}
+
+// Terminal notifications wait for the end of the next draw, see writeTerminalNotifications
+var terminalNotifications = make(chan string, maxNotifications)
+
+// writeTerminalNotifications sends the pending notifications to the terminal of the screen after a draw,
+// so that they do not end up in the middle of the output of tcell
+func writeTerminalNotifications(screen tcell.Screen) {
+	tty, ok := screen.Tty()
+	for {
+		select {
+		case message := <-terminalNotifications:
+			if ok {
+				// OSC 9, shown as a notification by terminals such as iTerm2, kitty and Windows Terminal
+				fmt.Fprintf(tty, "\x1b]9;%s\x07", strings.ReplaceAll(message, "\x07", ""))
+			}
+		default:
+			return
+		}
+	}
+}
+
+// workItemWatchState is the state of the work item compared between checks
+func workItemWatchState(workItem azuredevops.WorkItem) map[string]string {
+	return map[string]string{
+		"state":              workItem.State,
+		"assigned_to":        workItem.AssignedTo,
+		"assigned_to_unique": workItem.AssignedToUniqueName,
+	}
+}
+
+// pullRequestWatchState is the state of the PR compared between checks.
+// The comments are left out when they are unknown, i.e. comments is negative.
+func pullRequestWatchState(pr azuredevops.PullRequestDetails, comments int) map[string]string {
+	state := map[string]string{"status": pr.Status}
+	for _, vote := range pr.GetVotesInfo() {
+		state["vote:"+vote.Reviewer] = vote.Description
+	}
+	if comments >= 0 {
+		state["comments"] = strconv.Itoa(comments)
+	}
+	return state
+}
+
+// pipelineRunWatchState is the state of the pipeline run compared between checks
+func pipelineRunWatchState(run azuredevops.PipelineRun) map[string]string {
+	return map[string]string{
+		"status": run.Status,
+		"result": run.Result,
+	}
+}
+
+// fetchWatchState fetches the current state of the watched item
+func fetchWatchState(watch Watch) (map[string]string, error) {
+	switch watch.Kind {
+	case WatchWorkItem:
+		client.Invalidate(azuredevops.CacheWorkItem, watch.ID)
+		workItem, err := client.GetWorkItem(watch.ID)
+		if err != nil {
+			return nil, err
+		}
+		return workItemWatchState(*workItem), nil
+	case WatchPullRequest:
+		client.Invalidate(azuredevops.CachePullRequest, watch.ID)
+		pr, err := client.GetPRDetails(strconv.Itoa(watch.ID))
+		if err != nil {
+			return nil, err
+		}
+		comments := -1
+		if threads, err := client.GetPullRequestThreads(pr); err != nil {
+			log.Printf("Error fetching comments of PR %d: %v", pr.ID, err)
+		} else {
+			comments = azuredevops.CountPullRequestComments(threads)
+		}
+		return pullRequestWatchState(*pr, comments), nil
+	case WatchPipelineRun:
+		run, err := client.GetPipelineRun(watch.ID)
+		if err != nil {
+			return nil, err
+		}
+		return pipelineRunWatchState(*run), nil
+	}
+	return nil, fmt.Errorf("unknown kind of watch %q", watch.Kind)
+}
+
+// watchChanges describes what changed in the watched item since it was last checked
+func watchChanges(watch Watch, current map[string]string) []string {
+	previous := watch.State
+	if previous == nil {
+		return nil
+	}
+	changed := func(key string) bool {
+		return previous[key] != current[key]
+	}
+
+	var messages []string
+	switch watch.Kind {
+	case WatchWorkItem:
+		if changed("assigned_to") {
+			if activeUser != nil && (isSameAsUser(current["assigned_to"], activeUser) ||
+				strings.EqualFold(current["assigned_to_unique"], activeUser.Mail)) {
+				messages = append(messages, fmt.Sprintf("%s was assigned to you", watch))
+			} else if current["assigned_to"] == "" {
+				messages = append(messages, fmt.Sprintf("%s was unassigned", watch))
+			} else {
+				messages = append(messages, fmt.Sprintf("%s was reassigned to %s", watch, current["assigned_to"]))
+			}
+		}
+		if changed("state") {
+			messages = append(messages, fmt.Sprintf("%s moved to %s", watch, current["state"]))
+		}
+	case WatchPullRequest:
+		if changed("status") {
+			messages = append(messages, fmt.Sprintf("%s is %s", watch, current["status"]))
+		}
+		var reviewers []string
+		for key := range current {
+			if reviewer, ok := strings.CutPrefix(key, "vote:"); ok {
+				reviewers = append(reviewers, reviewer)
+			}
+		}
+		slices.Sort(reviewers)
+		for _, reviewer := range reviewers {
+			vote := current["vote:"+reviewer]
+			if changed("vote:"+reviewer) && vote != "no vote" {
+				messages = append(messages, fmt.Sprintf("%s: %s voted %s", watch, reviewer, vote))
+			}
+		}
+		before, errBefore := strconv.Atoi(previous["comments"])
+		after, errAfter := strconv.Atoi(current["comments"])
+		if errBefore == nil && errAfter == nil && after > before {
+			messages = append(messages, fmt.Sprintf("%s has %d new comment(s)", watch, after-before))
+		}
+	case WatchPipelineRun:
+		if changed("status") && current["status"] == "completed" {
+			messages = append(messages, fmt.Sprintf("%s finished: %s", watch, current["result"]))
+		}
+	}
+	return messages
+}
+
+// ToggleWatch watches the item or stops watching it, and tells the user
+func ToggleWatch(watch Watch) {
+	watched, err := Watches.Toggle(watch)
+	if err != nil {
+		log.Printf("Error saving watches: %v", err)
+		AnnounceError("❌ Error saving watches")
+		return
+	}
+	if watched {
+		Announce(fmt.Sprintf("👀 Watching %s", tview.Escape(watch.String())), 0)
+	} else {
+		Announce(fmt.Sprintf("Stopped watching %s", tview.Escape(watch.String())), 0)
+	}
+}
+
+// ShowNotifications shows the notifications of the session and the watched items.
+// Items can be unwatched from the list.
+func ShowNotifications(focus tview.Primitive) {
+	notificationsTextView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true)
+	notificationsTextView.SetBorder(true).
+		SetTitle(" Notifications ")
+
+	list := tview.NewList().
+		ShowSecondaryText(false).
+		SetHighlightFullLine(true).
+		SetSelectedBackgroundColor(tcell.ColorLimeGreen).
+		SetSelectedTextColor(tcell.ColorBlack)
+	list.SetBorder(true).
+		SetTitle(" Watching ")
+
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]x[white] unwatch  [yellow]Tab[white] switch focus  [yellow]q[white] close")
+
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(notificationsTextView, 0, 2, false).
+		AddItem(list, 0, 1, true).
+		AddItem(statusBar, 1, 0, false)
+
+	var watches []Watch
+	redraw := func() {
+		notifications := Watches.Notifications()
+		var text strings.Builder
+		for _, notification := range notifications {
+			fmt.Fprintf(&text, "[gray]%s[white]  %s\n", humanize.Time(notification.Time), tview.Escape(notification.Message))
+		}
+		if len(notifications) == 0 {
+			text.WriteString("[gray]No notifications yet. Press w on a work item, PR or pipeline run to watch it[white]")
+		}
+		notificationsTextView.SetText(text.String())
+
+		watches = Watches.List()
+		current := list.GetCurrentItem()
+		list.Clear()
+		for _, watch := range watches {
+			list.AddItem(tview.Escape(watch.String()), "", 0, nil)
+		}
+		if current < len(watches) {
+			list.SetCurrentItem(current)
+		}
+	}
+
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			CloseOverlay(notificationsOverlay, focus)
+			return nil
+		case event.Key() == tcell.KeyTab:
+			if list.HasFocus() {
+				app.SetFocus(notificationsTextView)
+			} else {
+				app.SetFocus(list)
+			}
+			return nil
+		case event.Rune() == 'x':
+			index := list.GetCurrentItem()
+			if index >= 0 && index < len(watches) {
+				ToggleWatch(watches[index])
+				redraw()
+			}
+			return nil
+		}
+		return event
+	})
+
+	Overlays.AddPage(notificationsOverlay, layout, true, true)
+	app.SetFocus(list)
+	redraw()
+}
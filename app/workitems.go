@@ -4,9 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"log"
-	"os"
+	"slices"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/aldnav/lazyaz/pkg/azuredevops"
 	"github.com/dustin/go-humanize"
@@ -66,7 +67,12 @@ var _stateColors = map[string]tcell.Color{
 	"Test":              tcell.ColorRed,
 }
 
-func redrawTable(table *tview.Table, workItems []azuredevops.WorkItem) {
+// workItemFingerprint changes whenever the work item changes in a way shown in the table
+func workItemFingerprint(workItem azuredevops.WorkItem) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s", workItem.ChangedDate, workItem.State, workItem.AssignedTo, workItem.Title, workItem.Tags)
+}
+
+func redrawTable(table *tview.Table, workItems []azuredevops.WorkItem, selection *ItemSelection) {
 	table.Clear()
 	tableData := workItemsToTableData(workItems)
 	for row, line := range strings.Split(tableData, "\n") {
@@ -121,6 +127,7 @@ func redrawTable(table *tview.Table, workItems []azuredevops.WorkItem) {
 			table.SetCell(row, column, tableCell)
 		}
 	}
+	markSelectedRows(table, workItems, workItemID, selection)
 }
 
 // Replaces common html tags, then strip other tags, and sanitize
@@ -284,8 +291,35 @@ func workItemToDetailsData(workItem *azuredevops.WorkItem) string {
 	return buf.String()
 }
 
+// fetchWorkItems fetches the first page of the work items of the filter, falling back to the cached one
+func fetchWorkItems(filter string) (azuredevops.Page[azuredevops.WorkItem], time.Time, error) {
+	return fetchCached(cacheName("workitems", filter), func() (azuredevops.Page[azuredevops.WorkItem], error) {
+		return client.GetWorkItemsPage(filter, "")
+	})
+}
+
+func workItemID(workItem azuredevops.WorkItem) int { return workItem.ID }
+
+// cachedWorkItemDetails are the details of a work item kept in the cache
+type cachedWorkItemDetails struct {
+	Details   *azuredevops.WorkItemDetails
+	PRDetails []azuredevops.PullRequestDetails
+}
+
+// cacheWorkItemDetails saves the fetched details of the work item,
+// or fills them in from the cache when fetching them failed
+func cacheWorkItemDetails(workItem *azuredevops.WorkItem) {
+	if workItem.Details != nil {
+		SaveCacheItem("workitem-details", workItem.ID, cachedWorkItemDetails{workItem.Details, workItem.PRDetails})
+		return
+	}
+	if cached, ok := LoadCacheItem[cachedWorkItemDetails]("workitem-details", workItem.ID); ok {
+		workItem.Details = cached.Details
+		workItem.PRDetails = cached.PRDetails
+	}
+}
+
 func WorkItemsPage(nextSlide func()) (title string, content tview.Primitive) {
-	log.SetOutput(os.Stderr)
 	log.SetPrefix("[lazyaz] ")
 	var workItems []azuredevops.WorkItem
 	var currentIndex int
@@ -294,6 +328,9 @@ func WorkItemsPage(nextSlide func()) (title string, content tview.Primitive) {
 	var searchText string
 	var previousSearchText string
 	workItemFilter := "me"
+	if filter := startupFilter("workitems"); filter != "" {
+		workItemFilter = filter
+	}
 	isFetching := make(chan bool, 1) // Make it buffered
 
 	// Add search-related variables
@@ -307,6 +344,8 @@ func WorkItemsPage(nextSlide func()) (title string, content tview.Primitive) {
 		SetBorders(false).
 		SetSelectable(true, false).
 		SetSeparator(' ')
+	// Items selected with space for the extensions
+	selection := NewItemSelection()
 
 	// Set custom selection style - red text on black background
 	table.SetSelectedStyle(tcell.StyleDefault.
@@ -376,16 +415,18 @@ func WorkItemsPage(nextSlide func()) (title string, content tview.Primitive) {
 		// TODO "@Follows" and "@Mentions" are only working for web portal
 		// https://learn.microsoft.com/en-us/azure/devops/boards/queries/query-operators-variables?view=azure-devops#query-macros-or-variables
 		SetOptions([]string{"Assigned to me", "Was ever assigned to me", "All"}, nil)
-	dropdown.SetCurrentOption(0)
+	dropdown.SetCurrentOption(slices.Index(workItemFilters, workItemFilter))
 	actionsPanel.AddItem(dropdown, 0, 1, false)
 	searchStatus := tview.NewTextView().SetText("").SetTextAlign(tview.AlignRight)
 	actionsPanel.AddItem(searchStatus, 0, 1, false)
+	workItemsPager := newPager()
+	actionsPanel.AddItem(workItemsPager.Indicator, 28, 0, false)
 
 	// Add buttons to the extensions
 	detailActionsPanel := tview.NewFlex().
 		SetDirection(tview.FlexColumn)
 	detailsPanel.AddItem(detailActionsPanel, 1, 1, false)
-	AttachWorkItemExtensions(detailActionsPanel, table, &workItems)
+	AttachWorkItemExtensions(detailActionsPanel, table, &workItems, selection)
 
 	// Variable to track if details are visible
 	detailsVisible := false
@@ -496,6 +537,7 @@ func WorkItemsPage(nextSlide func()) (title string, content tview.Primitive) {
 					requestedID := currentWorkItem.ID
 					workItems[index].GetMoreWorkItemDetails()
 					workItems[index].GetPRDetails(client)
+					cacheWorkItemDetails(&workItems[index])
 					// Update UI on the main thread when done
 					app.QueueUpdateDraw(func() {
 						// Refresh with complete details
@@ -512,12 +554,85 @@ func WorkItemsPage(nextSlide func()) (title string, content tview.Primitive) {
 		displayWorkItemDetails(workItems, currentIndex)
 	}
 
+	// Prefetch the details of the visible work items in the background, when enabled
+	isPrefetching := make(chan bool, 1)
+	prefetchVisibleDetails := func() {
+		if AppSettings == nil || !AppSettings.WorkItems.PrefetchDetails {
+			return
+		}
+		rowOffset, _ := table.GetOffset()
+		_, _, _, height := table.GetInnerRect()
+		if height <= 0 {
+			height = 50
+		}
+		var ids []int
+		for index := rowOffset; index < min(rowOffset+height, len(workItems)); index++ {
+			if workItems[index].Details == nil {
+				ids = append(ids, workItems[index].ID)
+			}
+		}
+		if len(ids) == 0 {
+			return
+		}
+		select {
+		case isPrefetching <- true:
+			go func() {
+				defer func() { <-isPrefetching }()
+				details, err := client.GetWorkItemsDetails(ids)
+				if err != nil {
+					log.Printf("Error prefetching work items details: %v", err)
+					return
+				}
+				prefetched := make(map[int]azuredevops.WorkItem, len(details))
+				for id, detail := range details {
+					workItem := azuredevops.WorkItem{ID: id, Details: detail}
+					if _, err := workItem.GetPRDetails(client); err != nil {
+						log.Printf("Error prefetching PRs of work item %d: %v", id, err)
+					}
+					cacheWorkItemDetails(&workItem)
+					prefetched[id] = workItem
+				}
+				app.QueueUpdateDraw(func() {
+					for index := range workItems {
+						if workItem, ok := prefetched[workItems[index].ID]; ok && workItems[index].Details == nil {
+							workItems[index].Details = workItem.Details
+							workItems[index].PRDetails = workItem.PRDetails
+						}
+					}
+					if detailsVisible {
+						displayCurrentWorkItemDetails()
+					}
+				})
+			}()
+		default:
+			// Another prefetch is in progress, the next scroll picks up what it missed
+		}
+	}
+
+	// Load the next page of work items when the selection gets near the end of the table
+	loadMoreWorkItems := func() {
+		loadNextPage(workItemsPager, len(workItems), func(continuationToken string) (azuredevops.Page[azuredevops.WorkItem], error) {
+			return client.GetWorkItemsPage(workItemFilter, continuationToken)
+		}, func(more []azuredevops.WorkItem) {
+			rowOffset, _ := table.GetOffset()
+			workItems = appendMissing(workItems, more, workItemID)
+			workItemsPager.show(len(workItems))
+			redrawTable(table, workItems, selection)
+			restoreTableSelection(table, currentIndex, rowOffset)
+			prefetchVisibleDetails()
+		})
+	}
+
 	// When the table highlight is changed
 	table.SetSelectionChangedFunc(func(row, column int) {
 		currentIndex = row - 1
 		if currentIndex < 0 {
 			currentIndex = 0
 		}
+		prefetchVisibleDetails()
+		if workItemsPager.shouldLoadMore(currentIndex, len(workItems)) {
+			loadMoreWorkItems()
+		}
 		if detailsVisible {
 			loadingWorkItemID = workItems[currentIndex].ID
 			detailsTextView.SetText("")
@@ -587,6 +702,21 @@ func WorkItemsPage(nextSlide func()) (title string, content tview.Primitive) {
 		toggleDetailsPanel()
 	})
 
+	// Select the work item of `lazyaz open` and show its details, listing it first when it is not listed
+	openWorkItem := func(workItem *azuredevops.WorkItem, index int) {
+		if workItem != nil {
+			workItems = append([]azuredevops.WorkItem{*workItem}, workItems...)
+			redrawTable(table, workItems, selection)
+			index = 0
+		}
+		currentIndex = index
+		table.Select(index+1, 0)
+		if !detailsVisible {
+			toggleDetailsPanel()
+		}
+		app.SetFocus(table)
+	}
+
 	// Handle work item filter dropdown selection
 	dropdown.SetSelectedFunc(func(text string, index int) {
 		app.SetFocus(table)
@@ -618,26 +748,32 @@ func WorkItemsPage(nextSlide func()) (title string, content tview.Primitive) {
 			select {
 			case isFetching <- true:
 				dropdown.SetLabel("Fetching ")
-				var err error
-				workItems, err = client.GetWorkItemsForFilter(workItemFilter)
+				page, cachedAt, err := fetchWorkItems(workItemFilter)
+				workItems = page.Items
 				<-isFetching
+				if !cachedAt.IsZero() {
+					AnnounceCached("work items", cachedAt)
+				}
 				if err != nil {
 					log.Printf("Error fetching work items: %v", err)
 				}
 				if len(workItems) > 0 {
 					app.QueueUpdateDraw(func() {
 						dropdown.SetLabel("")
+						resetPager(workItemsPager, page)
 						// Reset the index
 						currentIndex = 0
-						redrawTable(table, workItems)
+						redrawTable(table, workItems, selection)
 						// Close the details panel
 						closeDetailPanel()
 						app.SetFocus(table)
 						table.Select(0, 0)
+						prefetchVisibleDetails()
 					})
 				} else {
 					app.QueueUpdateDraw(func() {
 						dropdown.SetLabel("")
+						resetPager(workItemsPager, page)
 						table.SetCell(0, 0, tview.NewTableCell("No work items found. Try other filters (press \\ and Up or Down)").
 							SetTextColor(tcell.ColorRed).
 							SetAlign(tview.AlignCenter))
@@ -658,30 +794,40 @@ func WorkItemsPage(nextSlide func()) (title string, content tview.Primitive) {
 	loadData := func() {
 		select {
 		case isFetching <- true: // Try to write to channel
-			var err error
-			workItems, err = client.GetWorkItemsForFilter(workItemFilter)
+			// Details are fetched again after a refresh
+			client.Invalidate(azuredevops.CacheWorkItemDetails)
+			client.Invalidate(azuredevops.CachePullRequest)
+			page, cachedAt, err := fetchWorkItems(workItemFilter)
+			workItems = page.Items
 			<-isFetching // Release the lock
 			if err != nil {
 				log.Printf("Error fetching work items: %v", err)
 				AnnounceError("❌ Error fetching work items")
+			} else if !cachedAt.IsZero() {
+				AnnounceCached("work items", cachedAt)
 			} else {
 				Announce("✅ Refresh done", 3)
 			}
 			if len(workItems) > 0 {
 				app.QueueUpdateDraw(func() {
-					redrawTable(table, workItems)
+					resetPager(workItemsPager, page)
+					redrawTable(table, workItems, selection)
 					app.SetFocus(table)
 					if detailsVisible {
 						displayCurrentWorkItemDetails()
 					}
+					prefetchVisibleDetails()
+					openStartupItem(azuredevops.ItemWorkItem, workItems, workItemID, client.GetWorkItem, openWorkItem)
 				})
 			} else {
 				app.QueueUpdateDraw(func() {
+					resetPager(workItemsPager, page)
 					table.Clear()
 					table.SetCell(0, 0, tview.NewTableCell("No work items found. Try other filters (press \\ and Up or Down)").
 						SetTextColor(tcell.ColorRed).
 						SetAlign(tview.AlignCenter))
 					app.SetFocus(table)
+					openStartupItem(azuredevops.ItemWorkItem, workItems, workItemID, client.GetWorkItem, openWorkItem)
 				})
 			}
 		default:
@@ -690,6 +836,52 @@ func WorkItemsPage(nextSlide func()) (title string, content tview.Primitive) {
 		}
 	}
 
+	// Refresh in the background, highlighting the work items that appeared or changed
+	refreshInBackground := func() {
+		select {
+		case isFetching <- true:
+			latest, cachedAt, err := fetchWorkItems(workItemFilter)
+			<-isFetching
+			if err != nil || !cachedAt.IsZero() {
+				log.Printf("Error refreshing work items: %v", err)
+				return
+			}
+			app.QueueUpdateDraw(func() {
+				selectedID := -1
+				if currentIndex >= 0 && currentIndex < len(workItems) {
+					selectedID = workItems[currentIndex].ID
+				}
+				rowOffset, _ := table.GetOffset()
+				var changed map[int]bool
+				// The further pages loaded are kept as they are
+				refreshed := keepLoadedPages(workItemsPager, workItems, latest, workItemID)
+				workItems, changed = mergeRefreshed(workItems, refreshed, workItemID, workItemFingerprint)
+				if len(workItems) == 0 {
+					return
+				}
+				for index := range changed {
+					client.Invalidate(azuredevops.CacheWorkItemDetails, workItems[index].ID)
+				}
+				workItemsPager.show(len(workItems))
+				redrawTable(table, workItems, selection)
+				highlightRows(table, changed)
+				currentIndex = max(indexOfID(workItems, workItemID, selectedID), 0)
+				restoreTableSelection(table, currentIndex, rowOffset)
+				if detailsVisible {
+					displayCurrentWorkItemDetails()
+				}
+				prefetchVisibleDetails()
+				if len(changed) > 0 {
+					Announce(fmt.Sprintf("🔄 %d work item(s) changed", len(changed)), 3)
+				}
+			})
+		default:
+			// Another fetch is in progress, skip this one
+			return
+		}
+	}
+	StartAutoRefresh("workitems", refreshInBackground, func() bool { return searchMode })
+
 	// Add input capture for toggling details panel
 	mainWindow.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		// Handle search mode activation with "/"
@@ -729,9 +921,43 @@ func WorkItemsPage(nextSlide func()) (title string, content tview.Primitive) {
 			go loadData()
 			return nil
 		}
+
+		// Handle 'w' key to watch the selected work item
+		if event.Rune() == 'w' && !searchMode {
+			if currentIndex >= 0 && currentIndex < len(workItems) {
+				workItem := workItems[currentIndex]
+				ToggleWatch(Watch{
+					Kind:  WatchWorkItem,
+					ID:    workItem.ID,
+					Title: workItem.Title,
+					State: workItemWatchState(workItem),
+				})
+			}
+			return nil
+		}
+
+		// Handle space to select the highlighted item for the extensions
+		if event.Key() == tcell.KeyRune && event.Rune() == ' ' && !searchMode {
+			toggleSelectedRow(table, workItems, workItemID, selection, currentIndex)
+			return nil
+		}
+
+		// Handle the keys of the extensions, running against the selected items or the highlighted one
+		if !searchMode && HandleExtensionKey("workitems", event, selectedItems(workItems, workItemID, selection, currentIndex), table, func() {
+			clearSelectedRows(table, workItems, workItemID, selection)
+		}) {
+			return nil
+		}
 		return event
 	})
 
+	// Show the cached work items while the fresh ones load
+	if cached, savedAt, ok := LoadCache[azuredevops.Page[azuredevops.WorkItem]](cacheName("workitems", workItemFilter)); ok && len(cached.Items) > 0 {
+		workItems = cached.Items
+		resetPager(workItemsPager, cached)
+		redrawTable(table, workItems, selection)
+		Announce(fmt.Sprintf("⏳ Showing work items cached %s while refreshing...", humanize.Time(savedAt)), -1)
+	}
 	go loadData()
 
 	return "Work Items", mainWindow
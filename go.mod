@@ -8,7 +8,9 @@ require (
 	github.com/gdamore/tcell/v2 v2.8.1
 	github.com/grokify/html-strip-tags-go v0.1.0
 	github.com/rivo/tview v0.0.0-20250325173046-7b72abf45814
+	go.starlark.net v0.0.0-20231121155337-90ade8b19d09
 	golang.org/x/text v0.23.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,136 @@
+package azuredevops
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resources whose responses are cached in memory, to be given to Invalidate
+const (
+	CacheWorkItem        = "workitem"
+	CacheWorkItemDetails = "workitem-details"
+	CachePullRequest     = "pullrequest"
+)
+
+// How long the responses of the resources are reused
+var cacheTTLs = map[string]time.Duration{
+	CacheWorkItem:        time.Minute,
+	CacheWorkItemDetails: 5 * time.Minute,
+	CachePullRequest:     2 * time.Minute,
+}
+
+type cachedResponse struct {
+	output  []byte
+	expires time.Time
+}
+
+// pendingRequest is a request in flight that identical requests wait for
+type pendingRequest struct {
+	done        chan struct{}
+	output      []byte
+	err         error
+	invalidated bool
+}
+
+// requestCache keeps the output of az commands by resource and ID, and makes
+// concurrent identical requests share a single az command
+type requestCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+	pending map[string]*pendingRequest
+	now     func() time.Time
+}
+
+func newRequestCache() *requestCache {
+	return &requestCache{
+		entries: map[string]cachedResponse{},
+		pending: map[string]*pendingRequest{},
+		now:     time.Now,
+	}
+}
+
+// requests is the cache shared by the clients and the methods of the items
+var requests = newRequestCache()
+
+func cacheKey(resource string, id string) string {
+	return resource + ":" + id
+}
+
+// get returns the cached output of the resource and ID, or runs fetch once for all the callers asking for it.
+// Errors are not cached.
+func (c *requestCache) get(resource string, id string, fetch func() ([]byte, error)) ([]byte, error) {
+	key := cacheKey(resource, id)
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && c.now().Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.output, nil
+	}
+	if request, ok := c.pending[key]; ok {
+		c.mu.Unlock()
+		<-request.done
+		return request.output, request.err
+	}
+	request := &pendingRequest{done: make(chan struct{})}
+	c.pending[key] = request
+	c.mu.Unlock()
+
+	request.output, request.err = fetch()
+
+	c.mu.Lock()
+	delete(c.pending, key)
+	if request.err == nil && !request.invalidated && cacheTTLs[resource] > 0 {
+		c.entries[key] = cachedResponse{output: request.output, expires: c.now().Add(cacheTTLs[resource])}
+	}
+	c.mu.Unlock()
+	close(request.done)
+	return request.output, request.err
+}
+
+// set caches the output of the resource and ID fetched by other means, such as a batch request
+func (c *requestCache) set(resource string, id string, output []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ttl := cacheTTLs[resource]; ttl > 0 {
+		c.entries[cacheKey(resource, id)] = cachedResponse{output: output, expires: c.now().Add(ttl)}
+	}
+}
+
+// invalidate drops the cached outputs of the resource, only those of the IDs when given.
+// Requests in flight are still shared but their output is not cached.
+func (c *requestCache) invalidate(resource string, ids ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	matches := func(key string) bool {
+		if len(ids) == 0 {
+			return strings.HasPrefix(key, resource+":")
+		}
+		for _, id := range ids {
+			if key == cacheKey(resource, id) {
+				return true
+			}
+		}
+		return false
+	}
+	for key := range c.entries {
+		if matches(key) {
+			delete(c.entries, key)
+		}
+	}
+	for key, request := range c.pending {
+		if matches(key) {
+			request.invalidated = true
+		}
+	}
+}
+
+// Invalidate drops the cached responses of the resource, e.g. CachePullRequest, so the next requests fetch them again.
+// Only the responses of the IDs are dropped when given, all of them otherwise.
+func (c *Client) Invalidate(resource string, ids ...int) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, strconv.Itoa(id))
+	}
+	requests.invalidate(resource, keys...)
+}
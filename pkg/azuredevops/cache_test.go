@@ -0,0 +1,129 @@
+package azuredevops
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestCache_Get(t *testing.T) {
+	cache := newRequestCache()
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	var calls int
+	fetch := func() ([]byte, error) {
+		calls++
+		return []byte("output"), nil
+	}
+
+	if output, err := cache.get(CachePullRequest, "1", fetch); err != nil || string(output) != "output" {
+		t.Fatalf("Expected output, got %q and %v", output, err)
+	}
+	cache.get(CachePullRequest, "1", fetch)
+	if calls != 1 {
+		t.Errorf("Expected the cached output to be reused, got %d calls", calls)
+	}
+
+	cache.get(CachePullRequest, "2", fetch)
+	if calls != 2 {
+		t.Errorf("Expected another ID to be fetched, got %d calls", calls)
+	}
+
+	now = now.Add(cacheTTLs[CachePullRequest])
+	cache.get(CachePullRequest, "1", fetch)
+	if calls != 3 {
+		t.Errorf("Expected an expired output to be fetched again, got %d calls", calls)
+	}
+}
+
+func TestRequestCache_GetError(t *testing.T) {
+	cache := newRequestCache()
+	var calls int
+	fetch := func() ([]byte, error) {
+		calls++
+		return nil, errors.New("az command failed")
+	}
+
+	cache.get(CacheWorkItem, "1", fetch)
+	if _, err := cache.get(CacheWorkItem, "1", fetch); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	if calls != 2 {
+		t.Errorf("Expected errors not to be cached, got %d calls", calls)
+	}
+}
+
+func TestRequestCache_Invalidate(t *testing.T) {
+	cache := newRequestCache()
+	var calls int
+	fetch := func() ([]byte, error) {
+		calls++
+		return []byte("output"), nil
+	}
+
+	cache.get(CacheWorkItemDetails, "1", fetch)
+	cache.get(CacheWorkItemDetails, "2", fetch)
+	cache.get(CachePullRequest, "1", fetch)
+
+	cache.invalidate(CacheWorkItemDetails, "1")
+	cache.get(CacheWorkItemDetails, "1", fetch)
+	cache.get(CacheWorkItemDetails, "2", fetch)
+	if calls != 4 {
+		t.Errorf("Expected only the invalidated ID to be fetched again, got %d calls", calls)
+	}
+
+	cache.invalidate(CacheWorkItemDetails)
+	cache.get(CacheWorkItemDetails, "1", fetch)
+	cache.get(CacheWorkItemDetails, "2", fetch)
+	cache.get(CachePullRequest, "1", fetch)
+	if calls != 6 {
+		t.Errorf("Expected the whole resource to be fetched again, got %d calls", calls)
+	}
+}
+
+func TestRequestCache_SharesConcurrentRequests(t *testing.T) {
+	cache := newRequestCache()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fetch := func() ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return []byte("output"), nil
+	}
+
+	var wg sync.WaitGroup
+	outputs := make([]string, 5)
+	for i := range outputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			output, _ := cache.get(CachePullRequest, "1", fetch)
+			outputs[i] = string(output)
+		}()
+	}
+	// Let the requests line up behind the first one
+	for {
+		cache.mu.Lock()
+		_, inFlight := cache.pending[cacheKey(CachePullRequest, "1")]
+		cache.mu.Unlock()
+		if inFlight {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected a single request, got %d", calls.Load())
+	}
+	for i, output := range outputs {
+		if output != "output" {
+			t.Errorf("Expected request %d to get the output, got %q", i, output)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,13 +26,44 @@ var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 	}(),
 }))
 
+// SetLogger replaces the logger of the client, e.g. to write to the log file of the application
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
+
 // execCommand is a variable that allows for mocking exec.Command in tests
 var execCommand = exec.Command
 
+// ErrOffline is returned by every Azure DevOps call while offline
+var ErrOffline = errors.New("offline mode, Azure DevOps is not called")
+
+// offline is set when Azure DevOps must not be called
+var offline bool
+
+// SetOffline stops or resumes calling Azure DevOps
+func SetOffline(enabled bool) {
+	offline = enabled
+}
+
+// IsOffline reports whether Azure DevOps calls are disabled
+func IsOffline() bool {
+	return offline
+}
+
 // Config holds the Azure DevOps connection settings
 type Config struct {
 	Organization string
 	Project      string
+	// Where the organization and project come from, e.g. "az defaults"
+	OrganizationSource string
+	ProjectSource      string
+}
+
+// ConfigLayer is a source of the organization and project, such as the command line flags
+type ConfigLayer struct {
+	Source       string `json:"source"`
+	Organization string `json:"organization"`
+	Project      string `json:"project"`
 }
 
 // AzCliProjectsResponse represents the Azure CLI response for projects
@@ -83,59 +116,46 @@ func checkAzureCLI() {
 	}
 }
 
-func Doctor() {
-	recommendations := []string{}
-
-	if _, err := exec.LookPath("az"); err != nil {
-		fmt.Println("\033[31mX\033[0m Azure CLI")
-		recommendations = append(recommendations, "Azure CLI is not installed. To install, please visit https://learn.microsoft.com/en-us/cli/azure/install-azure-cli")
-	} else {
-		fmt.Println("\033[32m✓\033[0m Azure CLI")
-	}
-
-	if _, azureDevopsErr := runAzCommand("extension", "show", "--name", "azure-devops"); azureDevopsErr != nil {
-		fmt.Println("\033[31mX\033[0m Azure DevOps extension")
-		recommendations = append(recommendations, "Azure DevOps extension is not installed. Please install it using 'az extension add -n azure-devops'.")
-	} else {
-		fmt.Println("\033[32m✓\033[0m Azure DevOps extension")
-	}
-
-	if _, loginErr := runAzCommand("account", "show"); loginErr != nil {
-		fmt.Println("\033[31mX\033[0m Azure CLI login")
-		recommendations = append(recommendations, "Azure CLI is not logged in. Please run 'az login' to setup account.")
-		recommendations = append(recommendations, "Also while here, you can run 'az devops configure --defaults project=my-project-name organization=https://dev.azure.com/organizationName' to setup your default organization and project.")
-	} else {
-		fmt.Println("\033[32m✓\033[0m Azure CLI login")
-	}
-
-	if len(recommendations) > 0 {
-		fmt.Println("Recommendations:")
-		for _, recommendation := range recommendations {
-			fmt.Println("  - " + recommendation)
-		}
-	}
-
-}
-
 // NewConfig creates a new Config, first trying to read from config file, then falling back to environment variables
 func NewConfig() (*Config, error) {
-	checkAzureCLI()
+	return ResolveConfig()
+}
 
-	// Try to read organization and project from config file first
+// ConfigLayers returns the sources of the organization and project by precedence: the given layers,
+// then the defaults of the Azure CLI config file and finally the environment variables
+func ConfigLayers(layers ...ConfigLayer) []ConfigLayer {
 	org, project := readConfigFromFile()
+	return append(slices.Clone(layers),
+		ConfigLayer{Source: "az defaults", Organization: org, Project: project},
+		ConfigLayer{Source: "environment", Organization: os.Getenv("AZURE_DEVOPS_ORG"), Project: os.Getenv("AZURE_DEVOPS_PROJECT")},
+	)
+}
 
-	// If organization not found in config file, fall back to environment variable
-	if org == "" {
-		org = os.Getenv("AZURE_DEVOPS_ORG")
-	}
+// ResolveConfig creates a new Config, taking the organization and the project each from the first layer
+// that has them, see ConfigLayers
+func ResolveConfig(layers ...ConfigLayer) (*Config, error) {
+	checkAzureCLI()
 
-	// If project not found in config file, fall back to environment variable
-	if project == "" {
-		project = os.Getenv("AZURE_DEVOPS_PROJECT")
+	return MergeConfigLayers(ConfigLayers(layers...)...)
+}
+
+// MergeConfigLayers creates a Config of the layers alone, without checking the Azure CLI,
+// taking the organization and the project each from the first layer that has them
+func MergeConfigLayers(layers ...ConfigLayer) (*Config, error) {
+	config := &Config{}
+	for _, layer := range layers {
+		if config.Organization == "" && layer.Organization != "" {
+			config.Organization = layer.Organization
+			config.OrganizationSource = layer.Source
+		}
+		if config.Project == "" && layer.Project != "" {
+			config.Project = layer.Project
+			config.ProjectSource = layer.Source
+		}
 	}
 
 	var missingVars []string
-	if org == "" {
+	if config.Organization == "" {
 		missingVars = append(missingVars, "AZURE_DEVOPS_ORG")
 	}
 
@@ -143,10 +163,7 @@ func NewConfig() (*Config, error) {
 		return nil, fmt.Errorf("missing required configuration: %s", strings.Join(missingVars, ", "))
 	}
 
-	return &Config{
-		Organization: org,
-		Project:      project,
-	}, nil
+	return config, nil
 }
 
 // readConfigFromFile attempts to read the organization and project from ~/.azure/azuredevops/config
@@ -220,16 +237,58 @@ func readConfigFromFile() (string, string) {
 	return organization, project
 }
 
-// NewClient creates a new Azure DevOps client
+// NewClient creates a new Azure DevOps client. Its organization and project are given to every
+// az devops command, rather than the defaults of the Azure CLI.
 func NewClient(config *Config) *Client {
+	if config != nil {
+		scope = *config
+	}
 	return &Client{
 		Config: config,
 	}
 }
 
-// runAzCommand executes an Azure CLI command and returns the output
+// scope is the organization and project of the client in use, see scopeArgs
+var scope Config
+
+// Commands of the Azure DevOps extension taking a --project, the others only take an --org
+var projectCommands = []string{"boards query", "boards work-item create", "repos pr list", "repos pr create", "pipelines"}
+
+// scopeArgs adds the organization and the project in use to a command of the Azure DevOps extension,
+// unless the command gives them itself
+func scopeArgs(args []string) []string {
+	if len(args) == 0 || !slices.Contains([]string{"boards", "repos", "pipelines", "devops"}, args[0]) {
+		return args
+	}
+	has := func(flags ...string) bool {
+		return slices.ContainsFunc(args, func(arg string) bool { return slices.Contains(flags, arg) })
+	}
+	// The flags go after the command words, before the variadic flags such as --fields
+	words := slices.IndexFunc(args, func(arg string) bool { return strings.HasPrefix(arg, "-") })
+	if words < 0 {
+		words = len(args)
+	}
+	command := strings.Join(args[:words], " ")
+	var flags []string
+	if scope.Organization != "" && !has("--org", "--organization") {
+		flags = append(flags, "--org", scope.Organization)
+	}
+	takesProject := slices.ContainsFunc(projectCommands, func(prefix string) bool {
+		return command == prefix || strings.HasPrefix(command, prefix+" ")
+	})
+	if scope.Project != "" && takesProject && !has("--project", "-p") {
+		flags = append(flags, "--project", scope.Project)
+	}
+	return slices.Concat(args[:words], flags, args[words:])
+}
+
+// runAzCommand executes an Azure CLI command and returns the output. Commands of the Azure DevOps
+// extension run against the organization and project of the client, see scopeArgs.
 func runAzCommand(args ...string) ([]byte, error) {
-	cmd := execCommand("az", args...)
+	if offline {
+		return nil, ErrOffline
+	}
+	cmd := execCommand("az", scopeArgs(args)...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -243,6 +302,87 @@ func runAzCommand(args ...string) ([]byte, error) {
 	return stdout.Bytes(), nil
 }
 
+// invokeRequest describes a REST call made through `az devops invoke`
+type invokeRequest struct {
+	Area            string
+	Resource        string
+	RouteParameters map[string]string
+	QueryParameters map[string]string
+	HTTPMethod      string
+	Body            interface{}
+	APIVersion      string
+	Query           string // JMESPath query to shape the response
+}
+
+// invoke calls Azure DevOps REST APIs that have no dedicated az command.
+// The project route parameter is filled in from the client configuration when not given.
+func (c *Client) invoke(req invokeRequest) ([]byte, error) {
+	routeParameters := map[string]string{}
+	if c.Config != nil && c.Config.Project != "" {
+		routeParameters["project"] = c.Config.Project
+	}
+	for key, value := range req.RouteParameters {
+		routeParameters[key] = value
+	}
+	apiVersion := req.APIVersion
+	if apiVersion == "" {
+		apiVersion = "7.1"
+	}
+
+	cmdParams := []string{"devops", "invoke", "--area", req.Area, "--resource", req.Resource, "--api-version", apiVersion, "--output", "json"}
+	if c.Config != nil && c.Config.Organization != "" {
+		cmdParams = append(cmdParams, "--org", c.Config.Organization)
+	}
+	if len(routeParameters) > 0 {
+		cmdParams = append(cmdParams, "--route-parameters")
+		cmdParams = append(cmdParams, sortedKeyValues(routeParameters)...)
+	}
+	if len(req.QueryParameters) > 0 {
+		cmdParams = append(cmdParams, "--query-parameters")
+		cmdParams = append(cmdParams, sortedKeyValues(req.QueryParameters)...)
+	}
+	if req.HTTPMethod != "" {
+		cmdParams = append(cmdParams, "--http-method", req.HTTPMethod)
+	}
+	if req.Query != "" {
+		cmdParams = append(cmdParams, "--query", req.Query)
+	}
+	if req.Body != nil {
+		body, err := json.Marshal(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding request body: %v", err)
+		}
+		bodyFile, err := os.CreateTemp("", "lazyaz-*.json")
+		if err != nil {
+			return nil, fmt.Errorf("error creating request body file: %v", err)
+		}
+		defer os.Remove(bodyFile.Name())
+		if _, err := bodyFile.Write(body); err != nil {
+			bodyFile.Close()
+			return nil, fmt.Errorf("error writing request body file: %v", err)
+		}
+		bodyFile.Close()
+		cmdParams = append(cmdParams, "--in-file", bodyFile.Name())
+	}
+
+	logger.Debug("Invoking Azure DevOps API", "area", req.Area, "resource", req.Resource)
+	return runAzCommand(cmdParams...)
+}
+
+// sortedKeyValues formats a map as key=value pairs in a stable order
+func sortedKeyValues(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+values[key])
+	}
+	return pairs
+}
+
 // FetchProjects retrieves projects using Azure CLI
 func (c *Client) FetchProjects() ([]Project, error) {
 	// Run the az devops project list command
@@ -304,31 +444,9 @@ func (c *Client) GetProject(projectName string) (*Project, error) {
 
 // GetWorkItemsForFilter retrieves work items for a given filter
 func (c *Client) GetWorkItemsForFilter(filter string) ([]WorkItem, error) {
-	// Get the work items for the filter
-	var wiql string
-	switch filter {
-	case "me":
-		wiql = workItemQueryMeSincePastMonth
-	case "was-ever-me":
-		wiql = workItemQueryWasEverMeSincePastMonth
-	case "all":
-		wiql = workItemsQueryAll
-	default:
-		wiql = workItemQueryMeSincePastMonth
-	}
-
-	output, err := runAzCommand("boards", "query", "--wiql", wiql, "--query", jmespathWorkItemQuery, "--output", "json")
-	if err != nil {
-		return nil, fmt.Errorf("error fetching work items: %v", err)
-	}
-
-	// Parse the output
-	var workItems []WorkItem
-	if err := json.Unmarshal(output, &workItems); err != nil {
-		return nil, fmt.Errorf("error parsing work items: %v", err)
-	}
-
-	return workItems, nil
+	// Work items of the latest days, the first page
+	page, err := c.GetWorkItemsPage(filter, "")
+	return page.Items, err
 }
 
 // GetWorkItemsAssignedToUser retrieves work items assigned to the current user
@@ -356,7 +474,9 @@ func (c *Client) GetWorkItemsAssignedToUser() ([]WorkItem, error) {
 }
 
 func _fetchPRDetails(prID string) (*PullRequestDetails, error) {
-	output, err := runAzCommand("repos", "pr", "show", "--id", prID, "--query", jmespathPRDetailsQuery, "--output", "json")
+	output, err := requests.get(CachePullRequest, prID, func() ([]byte, error) {
+		return runAzCommand("repos", "pr", "show", "--id", prID, "--query", jmespathPRDetailsQuery, "--output", "json")
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error fetching PR details: %v", err)
 	}
@@ -375,6 +495,41 @@ func (c *Client) GetPRDetails(prID string) (*PullRequestDetails, error) {
 	return _fetchPRDetails(prID)
 }
 
+// Number of PRs fetched at the same time
+const prFetchWorkers = 4
+
+// GetPRsDetails retrieves the PRs by ID in parallel, in the order of the IDs
+func (c *Client) GetPRsDetails(prIDs []string) ([]PullRequestDetails, error) {
+	prs := make([]PullRequestDetails, len(prIDs))
+	errs := make([]error, len(prIDs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(prFetchWorkers, len(prIDs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				pr, err := c.GetPRDetails(prIDs[i])
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				prs[i] = *pr
+			}
+		}()
+	}
+	for i := range prIDs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("error fetching PR details: %v", err)
+	}
+	return prs, nil
+}
+
 // Retrieve current user profile
 func (c *Client) GetUserProfile() (*UserProfile, error) {
 	output, err := runAzCommand("ad", "signed-in-user", "show", "--query", jmespathUserProfileQuery, "--output", "json")
@@ -420,22 +575,12 @@ func (c *Client) GetPRsCreatedByUser(user string, status string) ([]PullRequestD
 	if user == "" {
 		return nil, fmt.Errorf("user is required")
 	}
-	cmdParams := []string{"repos", "pr", "list", "--include-links", "--creator", user, "--query", jmespathPRListsQuery, "--output", "json"}
+	filter := PRsFilter{Creator: user}
 	if status != "" && slices.Contains(PRStatuses, status) {
-		cmdParams = append(cmdParams, "--status", status)
-	}
-	output, err := runAzCommand(cmdParams...)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching PRs: %v", err)
+		filter.Status = status
 	}
-
-	// Parse the output
-	var prs []PullRequestDetails
-	if err := json.Unmarshal(output, &prs); err != nil {
-		return nil, fmt.Errorf("error parsing PRs: %v", err)
-	}
-
-	return prs, nil
+	page, err := c.GetPRsPage(filter, "")
+	return page.Items, err
 }
 
 // Get PRs assigned to the current user
@@ -443,37 +588,13 @@ func (c *Client) GetPRsAssignedToUser(user string) ([]PullRequestDetails, error)
 	if user == "" {
 		return nil, fmt.Errorf("user is required")
 	}
-	output, err := runAzCommand("repos", "pr", "list", "--include-links", "--reviewer", user, "--status", "active", "--top", "100", "--query", jmespathPRListsQuery, "--output", "json")
-	if err != nil {
-		return nil, fmt.Errorf("error fetching PRs: %v", err)
-	}
-
-	// Parse the output
-	var prs []PullRequestDetails
-	if err := json.Unmarshal(output, &prs); err != nil {
-		return nil, fmt.Errorf("error parsing PRs: %v", err)
-	}
-
-	return prs, nil
+	page, err := c.GetPRsPage(PRsFilter{Reviewer: user, Status: "active"}, "")
+	return page.Items, err
 }
 
 func (c *Client) FetchPullRequestsByStatus(status string) ([]PullRequestDetails, error) {
-	if status != "" && !slices.Contains(PRStatuses, status) {
-		return nil, fmt.Errorf("invalid status: %s", status)
-	}
-	cmdParams := []string{"repos", "pr", "list", "--include-links", "--status", status, "--query", jmespathPRListsQuery, "--output", "json", "--top", "100"}
-	output, err := runAzCommand(cmdParams...)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching PRs: %v", err)
-	}
-
-	// Parse the output
-	var prs []PullRequestDetails
-	if err := json.Unmarshal(output, &prs); err != nil {
-		return nil, fmt.Errorf("error parsing PRs: %v", err)
-	}
-
-	return prs, nil
+	page, err := c.GetPRsPage(PRsFilter{Status: status}, "")
+	return page.Items, err
 }
 
 func (c *Client) GetAllPRs() ([]PullRequestDetails, error) {
@@ -508,17 +629,7 @@ func (c *Client) GetPipelineDefinitions() ([]Pipeline, error) {
 }
 
 func (c *Client) GetPipelineRuns() ([]PipelineRun, error) {
-	output, err := runAzCommand("pipelines", "runs", "list", "--query", jmespathPipelineRunsQuery, "--output", "json", "--top", "40")
-	if err != nil {
-		return nil, fmt.Errorf("error fetching pipeline runs: %v", err)
-	}
-
-	// Parse the output
-	var runs []PipelineRun
-	if err := json.Unmarshal(output, &runs); err != nil {
-		return nil, fmt.Errorf("error parsing pipeline runs: %v", err)
-	}
-	return runs, nil
+	return c.GetPipelineRunsFiltered(PipelineRunsFilter{})
 }
 
 var pipelineRunsAllowedReasons = []string{
@@ -554,43 +665,75 @@ var pipelineRunsAllowedStatuses = []string{
 	"postponed",
 }
 
-func (c *Client) GetPipelineRunsFiltered(
-	pipelineID int,
-	branch string,
-	reason string,
-	result string,
-	status string,
-	requestedFor string,
-) ([]PipelineRun, error) {
-	cmdParams := []string{"pipelines", "runs", "list", "--query", jmespathPipelineRunsQuery, "--output", "json", "--top", "40"}
-	if pipelineID != 0 {
-		cmdParams = append(cmdParams, "--pipeline-ids", strconv.Itoa(pipelineID))
-	}
-	if branch != "" {
-		cmdParams = append(cmdParams, "--branch", branch)
-	}
-	if reason != "" {
-		if !slices.Contains(pipelineRunsAllowedReasons, reason) {
-			return nil, fmt.Errorf("invalid reason: %s", reason)
-		}
-		cmdParams = append(cmdParams, "--reason", reason)
+// DefaultPipelineRunsTop is the number of runs fetched when the filter does not say otherwise
+const DefaultPipelineRunsTop = 40
+
+// PipelineRunsFilter narrows down the pipeline runs. Empty fields are not filtered on.
+type PipelineRunsFilter struct {
+	PipelineID   int
+	Branch       string
+	Reason       string
+	Result       string
+	Status       string
+	RequestedFor string
+	Top          int
+}
+
+// Get the reasons pipeline runs can be filtered by
+func PipelineRunReasons() []string {
+	return slices.Clone(pipelineRunsAllowedReasons)
+}
+
+// Get the results pipeline runs can be filtered by
+func PipelineRunResults() []string {
+	return slices.Clone(pipelineRunsAllowedResults)
+}
+
+// Get the statuses pipeline runs can be filtered by
+func PipelineRunStatuses() []string {
+	return slices.Clone(pipelineRunsAllowedStatuses)
+}
+
+// validate checks the reason, result and status of the filter are known to Azure DevOps
+func (f PipelineRunsFilter) validate() error {
+	if f.Reason != "" && !slices.Contains(pipelineRunsAllowedReasons, f.Reason) {
+		return fmt.Errorf("invalid reason: %s", f.Reason)
 	}
-	if result != "" {
-		if !slices.Contains(pipelineRunsAllowedResults, result) {
-			return nil, fmt.Errorf("invalid result: %s", result)
-		}
-		if result != "all" {
-			cmdParams = append(cmdParams, "--result", result)
-		}
+	if f.Result != "" && !slices.Contains(pipelineRunsAllowedResults, f.Result) {
+		return fmt.Errorf("invalid result: %s", f.Result)
 	}
-	if status != "" {
-		if !slices.Contains(pipelineRunsAllowedStatuses, status) {
-			return nil, fmt.Errorf("invalid status: %s", status)
-		}
-		cmdParams = append(cmdParams, "--status", status)
+	if f.Status != "" && !slices.Contains(pipelineRunsAllowedStatuses, f.Status) {
+		return fmt.Errorf("invalid status: %s", f.Status)
+	}
+	return nil
+}
+
+func (c *Client) GetPipelineRunsFiltered(filter PipelineRunsFilter) ([]PipelineRun, error) {
+	top := filter.Top
+	if top <= 0 {
+		top = DefaultPipelineRunsTop
+	}
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+	cmdParams := []string{"pipelines", "runs", "list", "--query", jmespathPipelineRunsQuery, "--output", "json", "--top", strconv.Itoa(top), "--query-order", "QueueTimeDesc"}
+	if filter.PipelineID != 0 {
+		cmdParams = append(cmdParams, "--pipeline-ids", strconv.Itoa(filter.PipelineID))
+	}
+	if filter.Branch != "" {
+		cmdParams = append(cmdParams, "--branch", filter.Branch)
+	}
+	if filter.Reason != "" {
+		cmdParams = append(cmdParams, "--reason", filter.Reason)
+	}
+	if filter.Result != "" && filter.Result != "all" {
+		cmdParams = append(cmdParams, "--result", filter.Result)
+	}
+	if filter.Status != "" {
+		cmdParams = append(cmdParams, "--status", filter.Status)
 	}
-	if requestedFor != "" {
-		cmdParams = append(cmdParams, "--requested-for", requestedFor)
+	if filter.RequestedFor != "" {
+		cmdParams = append(cmdParams, "--requested-for", filter.RequestedFor)
 	}
 	output, err := runAzCommand(cmdParams...)
 	if err != nil {
@@ -20,8 +20,8 @@ func mockExecCommand(mockOutput string, mockError error) func(command string, ar
 func mockExecCommandError(mockError error) func(command string, args ...string) *exec.Cmd {
 	return func(command string, args ...string) *exec.Cmd {
 		cmd := exec.Command("test")
-	// This will cause the command to fail with the specified error
-	cmd.Stderr = exec.Command("echo", mockError.Error()).Stdout
+		// This will cause the command to fail with the specified error
+		cmd.Stderr = exec.Command("echo", mockError.Error()).Stdout
 		return cmd
 	}
 }
@@ -195,43 +195,43 @@ func TestNewConfig(t *testing.T) {
 	// Test with missing environment variables and no config file
 	t.Run("Missing variables", func(t *testing.T) {
 		os.Clearenv()
-		
+
 		config, err := NewConfig()
-		
+
 		if err == nil {
 			t.Error("Expected error for missing organization configuration, got nil")
 		}
-		
+
 		if config != nil {
 			t.Errorf("Expected nil config, got %+v", config)
 		}
 	})
-	
+
 	// Test with valid environment variables
 	t.Run("Valid variables", func(t *testing.T) {
 		os.Clearenv()
 		os.Setenv("AZURE_DEVOPS_ORG", "testorg")
 		os.Setenv("AZURE_DEVOPS_PROJECT", "testproject")
-		
+
 		config, err := NewConfig()
-		
+
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
-		
+
 		if config == nil {
 			t.Fatal("Expected config, got nil")
 		}
-		
+
 		if config.Organization != "testorg" {
 			t.Errorf("Expected organization 'testorg', got '%s'", config.Organization)
 		}
-		
+
 		if config.Project != "testproject" {
 			t.Errorf("Expected project 'testproject', got '%s'", config.Project)
 		}
 	})
-	
+
 	// Helper function to set up and clean up config file for tests
 	setupConfigTest := func(t *testing.T, configContent string) (string, func()) {
 		// Get home directory
@@ -240,16 +240,16 @@ func TestNewConfig(t *testing.T) {
 			t.Skipf("Unable to determine home directory: %v - skipping test", err)
 			return "", func() {}
 		}
-		
+
 		// Create config directory
 		configDir := filepath.Join(home, ".azure", "azuredevops")
 		if err = os.MkdirAll(configDir, 0755); err != nil {
 			t.Skipf("Unable to create config directory: %v - skipping test", err)
 			return "", func() {}
 		}
-		
+
 		configPath := filepath.Join(configDir, "config")
-		
+
 		// Create backup of existing file if it exists
 		existingConfig := ""
 		if _, err := os.Stat(configPath); err == nil {
@@ -260,13 +260,13 @@ func TestNewConfig(t *testing.T) {
 				t.Logf("Warning: could not read existing config for backup: %v", readErr)
 			}
 		}
-		
+
 		// Write test config content
 		if err = os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
 			t.Skipf("Unable to write config file: %v - skipping test", err)
 			return "", func() {}
 		}
-		
+
 		// Return cleanup function
 		cleanup := func() {
 			if existingConfig != "" {
@@ -275,7 +275,7 @@ func TestNewConfig(t *testing.T) {
 				_ = os.Remove(configPath)
 			}
 		}
-		
+
 		return configPath, cleanup
 	}
 
@@ -288,30 +288,29 @@ project = configproject
 `
 		_, cleanup := setupConfigTest(t, configContent)
 		defer cleanup()
-		
-		
+
 		// Run the test with token from env variable but org from config
 		os.Clearenv()
-		
+
 		config, err := NewConfig()
-		
+
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
-		
+
 		if config == nil {
 			t.Fatal("Expected config, got nil")
 		}
-		
+
 		if config.Organization != "configorg" {
 			t.Errorf("Expected organization 'configorg' from config file, got '%s'", config.Organization)
 		}
-		
+
 		if config.Project != "configproject" {
 			t.Errorf("Expected project 'configproject' from config file, got '%s'", config.Project)
 		}
 	})
-	
+
 	// Test reading only organization from config file (no project)
 	t.Run("Read only organization from config file", func(t *testing.T) {
 		// Create mock config file with only organization
@@ -320,30 +319,30 @@ organization = configorg
 `
 		_, cleanup := setupConfigTest(t, configContent)
 		defer cleanup()
-		
+
 		// Run the test with token and project from env variable
 		os.Clearenv()
 		os.Setenv("AZURE_DEVOPS_PROJECT", "envproject")
-		
+
 		config, err := NewConfig()
-		
+
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
-		
+
 		if config == nil {
 			t.Fatal("Expected config, got nil")
 		}
-		
+
 		if config.Organization != "configorg" {
 			t.Errorf("Expected organization 'configorg' from config file, got '%s'", config.Organization)
 		}
-		
+
 		if config.Project != "envproject" {
 			t.Errorf("Expected project 'envproject' from env, got '%s'", config.Project)
 		}
 	})
-	
+
 	// Test fallback to environment variable when config file doesn't have organization
 	t.Run("Fallback to env variable", func(t *testing.T) {
 		// Create mock config file without organization but with project
@@ -353,31 +352,31 @@ project = configproject
 `
 		_, cleanup := setupConfigTest(t, configContent)
 		defer cleanup()
-		
-		// Run the test with both from env variable 
+
+		// Run the test with both from env variable
 		os.Clearenv()
 		os.Setenv("AZURE_DEVOPS_ORG", "fallbackorg")
 		os.Setenv("AZURE_DEVOPS_PROJECT", "fallbackproject")
-		
+
 		config, err := NewConfig()
-		
+
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
-		
+
 		if config == nil {
 			t.Fatal("Expected config, got nil")
 		}
-		
+
 		if config.Organization != "fallbackorg" {
 			t.Errorf("Expected fallback to organization 'fallbackorg' from env, got '%s'", config.Organization)
 		}
-		
+
 		if config.Project != "configproject" {
 			t.Errorf("Expected project 'configproject' from config file, got '%s'", config.Project)
 		}
 	})
-	
+
 	// Test using both org and project from environment variables when neither is in config
 	t.Run("Both org and project from env variables", func(t *testing.T) {
 		// Create mock config file without organization or project
@@ -386,29 +385,93 @@ someotherkey = somevalue
 `
 		_, cleanup := setupConfigTest(t, configContent)
 		defer cleanup()
-		
+
 		// Run the test with all values from env variables
 		os.Clearenv()
 		os.Setenv("AZURE_DEVOPS_ORG", "envorg")
 		os.Setenv("AZURE_DEVOPS_PROJECT", "envproject")
-		
+
 		config, err := NewConfig()
-		
+
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
-		
+
 		if config == nil {
 			t.Fatal("Expected config, got nil")
 		}
-		
+
 		if config.Organization != "envorg" {
 			t.Errorf("Expected organization 'envorg' from env, got '%s'", config.Organization)
 		}
-		
+
 		if config.Project != "envproject" {
 			t.Errorf("Expected project 'envproject' from env, got '%s'", config.Project)
 		}
 	})
 }
 
+func TestClient_Offline(t *testing.T) {
+	origExecCommand := execCommand
+	defer func() { execCommand = origExecCommand }()
+	defer SetOffline(false)
+
+	called := false
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		called = true
+		return exec.Command("echo", "[]")
+	}
+
+	SetOffline(true)
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	if _, err := client.GetPipelineRuns(); err == nil {
+		t.Error("Expected an error while offline, got nil")
+	}
+	if called {
+		t.Error("Expected az not to be called while offline")
+	}
+
+	SetOffline(false)
+	if _, err := client.GetPipelineRuns(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if !called {
+		t.Error("Expected az to be called after going back online")
+	}
+}
+
+func TestConfigLayers(t *testing.T) {
+	t.Setenv("AZURE_DEVOPS_ORG", "envorg")
+	t.Setenv("AZURE_DEVOPS_PROJECT", "")
+
+	layers := ConfigLayers(ConfigLayer{Source: "flags", Project: "flagproject"})
+	if len(layers) != 3 {
+		t.Fatalf("Expected the flags, the az defaults and the environment, got %+v", layers)
+	}
+	if layers[0].Source != "flags" || layers[1].Source != "az defaults" || layers[2].Source != "environment" {
+		t.Errorf("Unexpected precedence %+v", layers)
+	}
+	if layers[2].Organization != "envorg" {
+		t.Errorf("Expected the organization of the environment, got %q", layers[2].Organization)
+	}
+}
+
+func TestMergeConfigLayers(t *testing.T) {
+	config, err := MergeConfigLayers(
+		ConfigLayer{Source: "flags", Project: "flagproject"},
+		ConfigLayer{Source: "lazyaz.toml", Organization: "https://dev.azure.com/tomlorg", Project: "tomlproject"},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if config.Organization != "https://dev.azure.com/tomlorg" || config.OrganizationSource != "lazyaz.toml" {
+		t.Errorf("Expected the organization of lazyaz.toml, got %q from %q", config.Organization, config.OrganizationSource)
+	}
+	if config.Project != "flagproject" || config.ProjectSource != "flags" {
+		t.Errorf("Expected the project of the flags, got %q from %q", config.Project, config.ProjectSource)
+	}
+
+	if _, err := MergeConfigLayers(ConfigLayer{Source: "flags", Project: "flagproject"}); err == nil {
+		t.Error("Expected an error without an organization")
+	}
+}
@@ -0,0 +1,166 @@
+package azuredevops
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Groups of the checks of the doctor
+const (
+	CheckGroupInstallation  = "installation"
+	CheckGroupConnection    = "connection"
+	CheckGroupConfiguration = "configuration"
+	CheckGroupHelpers       = "helpers"
+)
+
+// Check is the outcome of a check of the doctor, with a recommendation when it failed
+type Check struct {
+	Group          string `json:"group"`
+	Name           string `json:"name"`
+	OK             bool   `json:"ok"`
+	Detail         string `json:"detail,omitempty"`
+	Recommendation string `json:"recommendation,omitempty"`
+}
+
+// String formats the check as a line of the doctor, e.g. "✓ Azure CLI: /usr/bin/az"
+func (c Check) String() string {
+	mark := "\033[32m✓\033[0m"
+	if !c.OK {
+		mark = "\033[31mX\033[0m"
+	}
+	if c.Detail == "" {
+		return mark + " " + c.Name
+	}
+	return mark + " " + c.Name + ": " + c.Detail
+}
+
+// passed and failed make the checks of a group
+func passed(group, name, detail string) Check {
+	return Check{Group: group, Name: name, OK: true, Detail: detail}
+}
+
+func failed(group, name string, err error, recommendation string) Check {
+	detail := ""
+	if err != nil {
+		// The errors of the Azure CLI span several lines
+		detail = strings.Join(strings.Fields(err.Error()), " ")
+	}
+	return Check{Group: group, Name: name, Detail: detail, Recommendation: recommendation}
+}
+
+// InstallationChecks checks the Azure CLI, its Azure DevOps extension and the login
+func InstallationChecks() []Check {
+	var checks []Check
+
+	if path, err := exec.LookPath("az"); err != nil {
+		checks = append(checks, failed(CheckGroupInstallation, "Azure CLI", err,
+			"Azure CLI is not installed. To install, please visit https://learn.microsoft.com/en-us/cli/azure/install-azure-cli"))
+	} else {
+		checks = append(checks, passed(CheckGroupInstallation, "Azure CLI", path))
+	}
+
+	if output, err := runAzCommand("extension", "show", "--name", "azure-devops", "--output", "json"); err != nil {
+		checks = append(checks, failed(CheckGroupInstallation, "Azure DevOps extension", err,
+			"Azure DevOps extension is not installed. Please install it using 'az extension add -n azure-devops'."))
+	} else {
+		var extension struct {
+			Version string `json:"version"`
+		}
+		json.Unmarshal(output, &extension)
+		checks = append(checks, passed(CheckGroupInstallation, "Azure DevOps extension", extension.Version))
+	}
+
+	if output, err := runAzCommand("account", "show", "--query", "user.name", "--output", "tsv"); err != nil {
+		checks = append(checks, failed(CheckGroupInstallation, "Azure CLI login", err,
+			"Azure CLI is not logged in. Please run 'az login' to setup account. "+
+				"Also while here, you can run 'az devops configure --defaults project=my-project-name organization=https://dev.azure.com/organizationName' to setup your default organization and project."))
+	} else {
+		checks = append(checks, passed(CheckGroupInstallation, "Azure CLI login", strings.TrimSpace(string(output))))
+	}
+
+	return checks
+}
+
+// ConnectionChecks checks that the organization and the project of the configuration are reachable,
+// that the user profile resolves and that the boards, repos and pipelines of that project each return data
+func (c *Client) ConnectionChecks() []Check {
+	var checks []Check
+	organization, project := "", ""
+	if c.Config != nil {
+		organization, project = c.Config.Organization, c.Config.Project
+	}
+
+	switch {
+	case organization == "":
+		checks = append(checks, failed(CheckGroupConnection, "Organization", fmt.Errorf("not set"),
+			"Set the organization with --org, lazyaz.toml, 'az devops configure --defaults organization=...' or AZURE_DEVOPS_ORG."))
+	default:
+		if _, err := runAzCommand("devops", "project", "list", "--organization", organization, "--top", "1", "--output", "json"); err != nil {
+			checks = append(checks, failed(CheckGroupConnection, "Organization", err,
+				fmt.Sprintf("Check that %s is the URL of the organization and that your account can access it.", organization)))
+		} else {
+			checks = append(checks, passed(CheckGroupConnection, "Organization", organization))
+		}
+	}
+
+	switch {
+	case project == "":
+		checks = append(checks, failed(CheckGroupConnection, "Project", fmt.Errorf("not set"),
+			"Set the project with --project, lazyaz.toml, 'az devops configure --defaults project=...' or AZURE_DEVOPS_PROJECT."))
+	case organization != "":
+		if _, err := runAzCommand("devops", "project", "show", "--project", project, "--organization", organization, "--output", "json"); err != nil {
+			checks = append(checks, failed(CheckGroupConnection, "Project", err,
+				fmt.Sprintf("Check that the project %s exists in the organization and that your account can access it.", project)))
+		} else {
+			checks = append(checks, passed(CheckGroupConnection, "Project", project))
+		}
+	}
+
+	if profile, err := c.GetUserProfile(); err != nil {
+		checks = append(checks, failed(CheckGroupConnection, "User profile", err,
+			"Neither the Microsoft Entra profile nor 'az account show' tell the mail of the user, which most filters need. Please contact the administrator to setup your profile."))
+	} else {
+		checks = append(checks, passed(CheckGroupConnection, "User profile", profile.Mail))
+	}
+
+	if page, err := c.GetWorkItemsPage("me", ""); err != nil {
+		checks = append(checks, failed(CheckGroupConnection, "Boards", err, "Check that your account can access the boards of the project."))
+	} else {
+		checks = append(checks, passed(CheckGroupConnection, "Boards", fmt.Sprintf("%d work items assigned to you", len(page.Items))))
+	}
+
+	if page, err := c.GetPRsPage(PRsFilter{Status: "active"}, ""); err != nil {
+		checks = append(checks, failed(CheckGroupConnection, "Repos", err, "Check that your account can access the repositories of the project."))
+	} else {
+		checks = append(checks, passed(CheckGroupConnection, "Repos", fmt.Sprintf("%d active pull requests", len(page.Items))))
+	}
+
+	if pipelines, err := c.GetPipelineDefinitions(); err != nil {
+		checks = append(checks, failed(CheckGroupConnection, "Pipelines", err, "Check that your account can access the pipelines of the project."))
+	} else {
+		checks = append(checks, passed(CheckGroupConnection, "Pipelines", fmt.Sprintf("%d pipelines", len(pipelines))))
+	}
+
+	return checks
+}
+
+// Doctor prints the checks of the installation, along with recommendations to fix the failed ones
+func Doctor() {
+	checks := InstallationChecks()
+	recommendations := []string{}
+	for _, check := range checks {
+		fmt.Println(check)
+		if !check.OK && check.Recommendation != "" {
+			recommendations = append(recommendations, check.Recommendation)
+		}
+	}
+
+	if len(recommendations) > 0 {
+		fmt.Println("Recommendations:")
+		for _, recommendation := range recommendations {
+			fmt.Println("  - " + recommendation)
+		}
+	}
+}
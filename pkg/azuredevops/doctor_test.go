@@ -0,0 +1,84 @@
+package azuredevops
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCheck_String(t *testing.T) {
+	check := Check{Name: "Azure CLI", OK: true, Detail: "/usr/bin/az"}
+	if got := check.String(); !strings.Contains(got, "✓") || !strings.HasSuffix(got, "Azure CLI: /usr/bin/az") {
+		t.Errorf("Unexpected check line %q", got)
+	}
+	check = Check{Name: "Boards"}
+	if got := check.String(); !strings.Contains(got, "X") || !strings.HasSuffix(got, "Boards") {
+		t.Errorf("Unexpected failed check line %q", got)
+	}
+}
+
+func TestClient_ConnectionChecks(t *testing.T) {
+	origExecCommand := execCommand
+	defer func() { execCommand = origExecCommand }()
+
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		if len(args) > 1 && args[0] == "devops" && args[1] == "project" {
+			return exec.Command("echo", "{}")
+		}
+		return exec.Command("false")
+	}
+
+	client := NewClient(&Config{Organization: "https://dev.azure.com/testorg"})
+	checks := client.ConnectionChecks()
+	byName := map[string]Check{}
+	for _, check := range checks {
+		if check.Group != CheckGroupConnection {
+			t.Errorf("Expected the connection group, got %q", check.Group)
+		}
+		byName[check.Name] = check
+	}
+
+	if !byName["Organization"].OK {
+		t.Errorf("Expected the organization to be reachable, got %+v", byName["Organization"])
+	}
+	if project := byName["Project"]; project.OK || project.Detail != "not set" || project.Recommendation == "" {
+		t.Errorf("Expected the project to be reported as not set, got %+v", project)
+	}
+	for _, name := range []string{"User profile", "Boards", "Repos", "Pipelines"} {
+		check, ok := byName[name]
+		if !ok {
+			t.Errorf("Expected a %s check", name)
+			continue
+		}
+		if check.OK || check.Recommendation == "" || strings.Contains(check.Detail, "\n") {
+			t.Errorf("Expected %s to fail on one line with a recommendation, got %+v", name, check)
+		}
+	}
+}
+
+func TestClient_ConnectionChecksUseOrganizationAndProject(t *testing.T) {
+	origExecCommand := execCommand
+	defer func() { execCommand = origExecCommand }()
+
+	probes := map[string]string{}
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		joined := strings.Join(args, " ")
+		for _, prefix := range []string{"boards query", "repos pr list", "pipelines list"} {
+			if strings.HasPrefix(joined, prefix) {
+				probes[prefix] = joined
+			}
+		}
+		return exec.Command("echo", "[]")
+	}
+
+	client := NewClient(&Config{Organization: "https://dev.azure.com/testorg", Project: "testproject"})
+	client.ConnectionChecks()
+	if len(probes) != 3 {
+		t.Fatalf("Expected the boards, repos and pipelines to be probed, got %v", probes)
+	}
+	for _, probe := range probes {
+		if !strings.Contains(probe, "--org https://dev.azure.com/testorg --project testproject") {
+			t.Errorf("Expected the probe to use the organization and project checked, got %s", probe)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package azuredevops
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Page is a page of results. The continuation token fetches the next page, it is empty on the last page.
+type Page[T any] struct {
+	Items             []T
+	ContinuationToken string
+}
+
+// HasMore reports whether there are more results after the page
+func (p Page[T]) HasMore() bool {
+	return p.ContinuationToken != ""
+}
+
+const (
+	// Work items are paged by windows of creation date, the latest first
+	workItemsWindowDays = 90
+	// How far back work items are paged, about five years
+	maxWorkItemsWindows = 20
+	// Number of PRs of a page when not given
+	DefaultPRsTop = 100
+)
+
+// parsePageNumber parses continuation tokens that are numbers, the empty token being 0
+func parsePageNumber(continuationToken string) (int, error) {
+	if continuationToken == "" {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(continuationToken)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid continuation token: %s", continuationToken)
+	}
+	return number, nil
+}
+
+// workItemsWindowCondition is the WIQL condition on the creation date of the window, 0 being the latest days
+func workItemsWindowCondition(window int) string {
+	if window == 0 {
+		return fmt.Sprintf("[System.CreatedDate] >= @Today - %d", workItemsWindowDays)
+	}
+	return fmt.Sprintf("[System.CreatedDate] >= @Today - %d AND [System.CreatedDate] < @Today - %d",
+		workItemsWindowDays*(window+1), workItemsWindowDays*window)
+}
+
+// GetWorkItemsPage retrieves a page of the work items of the filter ("me", "was-ever-me" or "all").
+// Pages are windows of 90 days of creation date, the latest first. Windows without work items are skipped,
+// paging ends after about five years.
+func (c *Client) GetWorkItemsPage(filter string, continuationToken string) (Page[WorkItem], error) {
+	window, err := parsePageNumber(continuationToken)
+	if err != nil {
+		return Page[WorkItem]{}, err
+	}
+	var wiql string
+	switch filter {
+	case "was-ever-me":
+		wiql = workItemPageQueryWasEverMe
+	case "all":
+		wiql = workItemsPageQueryAll
+	default:
+		wiql = workItemPageQueryMe
+	}
+
+	var page Page[WorkItem]
+	for ; window < maxWorkItemsWindows && len(page.Items) == 0; window++ {
+		output, err := runAzCommand("boards", "query", "--wiql", fmt.Sprintf(wiql, workItemsWindowCondition(window)), "--query", jmespathWorkItemQuery, "--output", "json")
+		if err != nil {
+			return Page[WorkItem]{}, fmt.Errorf("error fetching work items: %v", err)
+		}
+		if err := json.Unmarshal(output, &page.Items); err != nil {
+			return Page[WorkItem]{}, fmt.Errorf("error parsing work items: %v", err)
+		}
+	}
+	if len(page.Items) > 0 && window < maxWorkItemsWindows {
+		page.ContinuationToken = strconv.Itoa(window)
+	}
+	return page, nil
+}
+
+// PRsFilter narrows down the PRs. Empty fields are not filtered on.
+type PRsFilter struct {
+	Creator  string
+	Reviewer string
+	Status   string
+	Top      int
+}
+
+// GetPRsPage retrieves a page of the PRs of the filter, the continuation token being the number of PRs to skip
+func (c *Client) GetPRsPage(filter PRsFilter, continuationToken string) (Page[PullRequestDetails], error) {
+	skip, err := parsePageNumber(continuationToken)
+	if err != nil {
+		return Page[PullRequestDetails]{}, err
+	}
+	top := filter.Top
+	if top <= 0 {
+		top = DefaultPRsTop
+	}
+	cmdParams := []string{"repos", "pr", "list", "--include-links", "--query", jmespathPRListsQuery, "--output", "json", "--top", strconv.Itoa(top)}
+	if skip > 0 {
+		cmdParams = append(cmdParams, "--skip", strconv.Itoa(skip))
+	}
+	if filter.Creator != "" {
+		cmdParams = append(cmdParams, "--creator", filter.Creator)
+	}
+	if filter.Reviewer != "" {
+		cmdParams = append(cmdParams, "--reviewer", filter.Reviewer)
+	}
+	if filter.Status != "" {
+		if !slices.Contains(PRStatuses, filter.Status) {
+			return Page[PullRequestDetails]{}, fmt.Errorf("invalid status: %s", filter.Status)
+		}
+		cmdParams = append(cmdParams, "--status", filter.Status)
+	}
+	output, err := runAzCommand(cmdParams...)
+	if err != nil {
+		return Page[PullRequestDetails]{}, fmt.Errorf("error fetching PRs: %v", err)
+	}
+
+	var page Page[PullRequestDetails]
+	if err := json.Unmarshal(output, &page.Items); err != nil {
+		return Page[PullRequestDetails]{}, fmt.Errorf("error parsing PRs: %v", err)
+	}
+	if len(page.Items) == top {
+		page.ContinuationToken = strconv.Itoa(skip + top)
+	}
+	return page, nil
+}
+
+// GetPipelineRunsPage retrieves a page of the pipeline runs of the filter, the latest queued first.
+// The continuation token is the queue time of the last run of the previous page.
+func (c *Client) GetPipelineRunsPage(filter PipelineRunsFilter, continuationToken string) (Page[PipelineRun], error) {
+	top := filter.Top
+	if top <= 0 {
+		top = DefaultPipelineRunsTop
+	}
+	var runs []PipelineRun
+	var err error
+	if continuationToken == "" {
+		runs, err = c.GetPipelineRunsFiltered(filter)
+	} else {
+		runs, err = c.getPipelineRunsQueuedBefore(filter, top, continuationToken)
+	}
+	if err != nil {
+		return Page[PipelineRun]{}, err
+	}
+
+	page := Page[PipelineRun]{Items: runs}
+	if len(runs) == top {
+		page.ContinuationToken = runs[len(runs)-1].QueueTime.Format(time.RFC3339Nano)
+	}
+	return page, nil
+}
+
+// getPipelineRunsQueuedBefore retrieves the runs of the filter queued before the time,
+// which `az pipelines runs list` does not support
+func (c *Client) getPipelineRunsQueuedBefore(filter PipelineRunsFilter, top int, queuedBefore string) ([]PipelineRun, error) {
+	if _, err := time.Parse(time.RFC3339Nano, queuedBefore); err != nil {
+		return nil, fmt.Errorf("invalid continuation token: %s", queuedBefore)
+	}
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+	queryParameters := map[string]string{
+		"$top":       strconv.Itoa(top),
+		"maxTime":    queuedBefore,
+		"queryOrder": "queueTimeDescending",
+	}
+	if filter.PipelineID != 0 {
+		queryParameters["definitions"] = strconv.Itoa(filter.PipelineID)
+	}
+	if filter.Branch != "" {
+		// Unlike az pipelines runs list, the builds API needs the full ref
+		queryParameters["branchName"] = branchRef(filter.Branch)
+	}
+	if filter.Reason != "" {
+		queryParameters["reasonFilter"] = filter.Reason
+	}
+	if filter.Result != "" && filter.Result != "all" {
+		queryParameters["resultFilter"] = filter.Result
+	}
+	if filter.Status != "" {
+		queryParameters["statusFilter"] = filter.Status
+	}
+	if filter.RequestedFor != "" {
+		queryParameters["requestedFor"] = filter.RequestedFor
+	}
+	output, err := c.invoke(invokeRequest{
+		Area:            "build",
+		Resource:        "builds",
+		QueryParameters: queryParameters,
+		Query:           `value[].` + jmespathPipelineRunQuery,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pipeline runs: %v", err)
+	}
+
+	var runs []PipelineRun
+	if err := json.Unmarshal(output, &runs); err != nil {
+		return nil, fmt.Errorf("error parsing pipeline runs: %v", err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,234 @@
+package azuredevops
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkItemsWindowCondition(t *testing.T) {
+	if got := workItemsWindowCondition(0); got != "[System.CreatedDate] >= @Today - 90" {
+		t.Errorf("Unexpected condition of the first window: %s", got)
+	}
+	if got := workItemsWindowCondition(2); got != "[System.CreatedDate] >= @Today - 270 AND [System.CreatedDate] < @Today - 180" {
+		t.Errorf("Unexpected condition of the third window: %s", got)
+	}
+}
+
+func TestClient_GetWorkItemsPage(t *testing.T) {
+	origExecCommand := execCommand
+	defer func() { execCommand = origExecCommand }()
+
+	var calledArgs []string
+	output := `[{"Id": 1, "Title": "First"}]`
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		calledArgs = args
+		return exec.Command("echo", output)
+	}
+
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	page, err := client.GetWorkItemsPage("all", "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(page.Items) != 1 || page.ContinuationToken != "1" || !page.HasMore() {
+		t.Errorf("Unexpected page %+v", page)
+	}
+
+	page, err = client.GetWorkItemsPage("all", page.ContinuationToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	wiql := calledArgs[slices.Index(calledArgs, "--wiql")+1]
+	if !strings.Contains(wiql, "@Today - 180 AND [System.CreatedDate] < @Today - 90") {
+		t.Errorf("Expected the second window in the query, got %s", wiql)
+	}
+
+	// Windows without work items are skipped
+	var windows []string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		wiql := args[slices.Index(args, "--wiql")+1]
+		windows = append(windows, wiql)
+		if strings.Contains(wiql, "@Today - 720 AND") {
+			return exec.Command("echo", `[{"Id": 2, "Title": "Older"}]`)
+		}
+		return exec.Command("echo", `[]`)
+	}
+	page, err = client.GetWorkItemsPage("me", "5")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(page.Items) != 1 || len(windows) != 3 || page.ContinuationToken != "8" {
+		t.Errorf("Expected the work items of the eighth window after two empty ones, got %+v after %d windows", page, len(windows))
+	}
+
+	windows = nil
+	page, err = client.GetWorkItemsPage("me", "8")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if page.HasMore() || len(page.Items) != 0 || len(windows) != maxWorkItemsWindows-8 {
+		t.Errorf("Expected the empty windows up to the limit to end the paging, got %+v after %d windows", page, len(windows))
+	}
+
+	if _, err := client.GetWorkItemsPage("me", "next"); err == nil {
+		t.Error("Expected an error for an invalid continuation token, got nil")
+	}
+}
+
+func TestClient_GetPRsPage(t *testing.T) {
+	origExecCommand := execCommand
+	defer func() { execCommand = origExecCommand }()
+
+	var calledArgs []string
+	count := 2
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		calledArgs = args
+		prs := make([]map[string]int, count)
+		for i := range prs {
+			prs[i] = map[string]int{"ID": i + 1}
+		}
+		output, _ := json.Marshal(prs)
+		return exec.Command("echo", string(output))
+	}
+
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	page, err := client.GetPRsPage(PRsFilter{Reviewer: "jane@example.com", Status: "active", Top: 2}, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	args := strings.Join(calledArgs, " ")
+	for _, expected := range []string{"--top 2", "--reviewer jane@example.com", "--status active"} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in the arguments, got %s", expected, args)
+		}
+	}
+	if slices.Contains(calledArgs, "--skip") || slices.Contains(calledArgs, "--creator") {
+		t.Errorf("Expected no skip nor creator on the first page, got %s", args)
+	}
+	if page.ContinuationToken != "2" {
+		t.Errorf("Expected a full page to continue after 2 PRs, got %q", page.ContinuationToken)
+	}
+
+	count = 1
+	page, err = client.GetPRsPage(PRsFilter{Top: 2}, page.ContinuationToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(strings.Join(calledArgs, " "), "--skip 2") {
+		t.Errorf("Expected the PRs of the first page to be skipped, got %v", calledArgs)
+	}
+	if page.HasMore() {
+		t.Errorf("Expected a partial page to be the last, got %+v", page)
+	}
+
+	if _, err := client.GetPRsPage(PRsFilter{Status: "nope"}, ""); err == nil {
+		t.Error("Expected an error for an invalid status, got nil")
+	}
+}
+
+func TestClient_PagesUseOrganizationAndProject(t *testing.T) {
+	origExecCommand := execCommand
+	defer func() { execCommand = origExecCommand }()
+
+	var calledArgs []string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		calledArgs = args
+		return exec.Command("echo", "[]")
+	}
+
+	client := NewClient(&Config{Organization: "https://dev.azure.com/other", Project: "Other Project"})
+	if _, err := client.GetWorkItemsPage("all", ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	args := strings.Join(calledArgs, " ")
+	if !strings.HasPrefix(args, "boards query --org https://dev.azure.com/other --project Other Project --wiql") {
+		t.Errorf("Expected the organization and project of the client, got %s", args)
+	}
+
+	if _, err := client.GetPRsPage(PRsFilter{}, ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	args = strings.Join(calledArgs, " ")
+	if !strings.HasPrefix(args, "repos pr list --org https://dev.azure.com/other --project Other Project --") {
+		t.Errorf("Expected the organization and project of the client, got %s", args)
+	}
+}
+
+func TestScopeArgs(t *testing.T) {
+	defer func(previous Config) { scope = previous }(scope)
+	scope = Config{Organization: "testorg", Project: "testproject"}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"pipelines", "runs", "show", "--id", "1"}, "pipelines runs show --org testorg --project testproject --id 1"},
+		{[]string{"boards", "work-item", "show", "--id", "1"}, "boards work-item show --org testorg --id 1"},
+		{[]string{"repos", "pr", "show", "--id", "1"}, "repos pr show --org testorg --id 1"},
+		{[]string{"devops", "project", "list", "--organization", "other"}, "devops project list --organization other"},
+		{[]string{"devops", "project", "show", "--project", "p"}, "devops project show --org testorg --project p"},
+		{[]string{"account", "show"}, "account show"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(scopeArgs(tt.args), " "); got != tt.expected {
+			t.Errorf("scopeArgs(%v) = %q, expected %q", tt.args, got, tt.expected)
+		}
+	}
+}
+
+func TestClient_GetPipelineRunsPage(t *testing.T) {
+	origExecCommand := execCommand
+	defer func() { execCommand = origExecCommand }()
+
+	queueTime := time.Date(2025, 5, 1, 12, 30, 0, 0, time.UTC)
+	var calledArgs []string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		calledArgs = args
+		return exec.Command("echo", fmt.Sprintf(`[{"id": 2}, {"id": 1, "queueTime": "%s"}]`, queueTime.Format(time.RFC3339)))
+	}
+
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	page, err := client.GetPipelineRunsPage(PipelineRunsFilter{PipelineID: 7, Result: "failed", Top: 2}, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if calledArgs[0] != "pipelines" {
+		t.Errorf("Expected the first page from az pipelines runs list, got %v", calledArgs)
+	}
+	if page.ContinuationToken != queueTime.Format(time.RFC3339Nano) {
+		t.Errorf("Expected the queue time of the last run as continuation token, got %q", page.ContinuationToken)
+	}
+
+	page, err = client.GetPipelineRunsPage(PipelineRunsFilter{PipelineID: 7, Result: "failed", Top: 2}, page.ContinuationToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	args := strings.Join(calledArgs, " ")
+	for _, expected := range []string{"--resource builds", "$top=2", "definitions=7", "resultFilter=failed", "maxTime=2025-05-01T12:30:00Z", "queryOrder=queueTimeDescending"} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in the arguments, got %s", expected, args)
+		}
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("Expected 2 runs, got %d", len(page.Items))
+	}
+
+	// The builds API needs the full ref of the branch
+	for _, branch := range []string{"main", "refs/heads/main"} {
+		if _, err := client.GetPipelineRunsPage(PipelineRunsFilter{Branch: branch, Top: 2}, page.ContinuationToken); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if args := strings.Join(calledArgs, " "); !strings.Contains(args, "branchName=refs/heads/main") {
+			t.Errorf("Expected the full ref of %s in the arguments, got %s", branch, args)
+		}
+	}
+
+	if _, err := client.GetPipelineRunsPage(PipelineRunsFilter{}, "yesterday"); err == nil {
+		t.Error("Expected an error for an invalid continuation token, got nil")
+	}
+}
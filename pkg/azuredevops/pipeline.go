@@ -1,9 +1,15 @@
 package azuredevops
 
 import (
+	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 type Pipeline struct {
@@ -16,6 +22,17 @@ type Pipeline struct {
 	Author           string `json:"author"`
 	AuthorUniqueName string `json:"authorUniqueName"`
 	PipelineType     string `json:"pipelineType"`
+	// Latest run, only set by GetPipelineDefinitionsWithLatestRun
+	LatestRunID          int       `json:"latestRunId"`
+	LatestRunBuildNumber string    `json:"latestRunBuildNumber"`
+	LatestRunStatus      string    `json:"latestRunStatus"`
+	LatestRunResult      string    `json:"latestRunResult"`
+	LatestRunFinishTime  time.Time `json:"latestRunFinishTime"`
+}
+
+// Determines if new runs of the pipeline are queued, as opposed to paused or disabled
+func (p *Pipeline) IsEnabled() bool {
+	return p.Status == "" || p.Status == "enabled"
 }
 
 type PipelineRun struct {
@@ -51,9 +68,1036 @@ type PipelineRun struct {
 	SourceVersion          string    `json:"sourceVersion"`
 	StartTime              time.Time `json:"startTime"`
 	Status                 string    `json:"status"`
+	// Artifacts are fetched separately, see GetArtifacts
+	Artifacts          []PipelineArtifact `json:"-"`
+	IsArtifactsFetched bool               `json:"-"`
+	// Checks the run is waiting on, see PendingCheckpoints
+	PendingChecks []string `json:"-"`
 }
 
 func (r *PipelineRun) GetWebURL() string {
 	baseURL := strings.Split(r.ProjectURL, "_apis")[0] + r.ProjectID
 	return fmt.Sprintf("%s/_build/results?buildId=%d", baseURL, r.ID)
 }
+
+// PipelineDefinition holds the details of a single pipeline definition, including its repository
+type PipelineDefinition struct {
+	Pipeline
+	DefaultBranch  string `json:"defaultBranch"`
+	RepositoryID   string `json:"repositoryId"`
+	RepositoryName string `json:"repositoryName"`
+	RepositoryType string `json:"repositoryType"`
+	YAMLFilename   string `json:"yamlFilename"`
+}
+
+// PipelineParameter is a runtime parameter declared in the pipeline YAML
+type PipelineParameter struct {
+	Name        string      `yaml:"name"`
+	DisplayName string      `yaml:"displayName"`
+	Type        string      `yaml:"type"`
+	Default     interface{} `yaml:"default"`
+	Values      []string    `yaml:"values"`
+}
+
+// Get the label shown to the user for the parameter
+func (p PipelineParameter) Label() string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return p.Name
+}
+
+// Get the default value of the parameter as a string
+func (p PipelineParameter) DefaultString() string {
+	if p.Default == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", p.Default)
+}
+
+// Determines if the parameter can be set when queueing a run.
+// Object, step, job and stage parameters can only be set from templates.
+func (p PipelineParameter) IsQueueable() bool {
+	return slices.Contains([]string{"", "string", "number", "boolean"}, p.Type)
+}
+
+// PipelineYAML is the subset of a pipeline YAML file needed to queue a run
+type PipelineYAML struct {
+	Parameters []PipelineParameter
+	Stages     []string
+}
+
+// ParsePipelineYAML extracts runtime parameters and top level stage names from a pipeline YAML file
+func ParsePipelineYAML(content string) (*PipelineYAML, error) {
+	var document struct {
+		Parameters yaml.Node `yaml:"parameters"`
+		Stages     []struct {
+			Stage string `yaml:"stage"`
+		} `yaml:"stages"`
+	}
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		return nil, fmt.Errorf("error parsing pipeline YAML: %v", err)
+	}
+
+	parsed := &PipelineYAML{}
+	switch document.Parameters.Kind {
+	case yaml.SequenceNode:
+		if err := document.Parameters.Decode(&parsed.Parameters); err != nil {
+			return nil, fmt.Errorf("error parsing pipeline parameters: %v", err)
+		}
+	case yaml.MappingNode:
+		// Legacy syntax: parameters as a map of name to default value
+		var legacy map[string]interface{}
+		if err := document.Parameters.Decode(&legacy); err != nil {
+			return nil, fmt.Errorf("error parsing pipeline parameters: %v", err)
+		}
+		for name, value := range legacy {
+			parsed.Parameters = append(parsed.Parameters, PipelineParameter{Name: name, Default: value})
+		}
+		slices.SortFunc(parsed.Parameters, func(a, b PipelineParameter) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+	}
+	for _, stage := range document.Stages {
+		if stage.Stage != "" {
+			parsed.Stages = append(parsed.Stages, stage.Stage)
+		}
+	}
+	return parsed, nil
+}
+
+// PipelineVariable is a variable defined on the pipeline definition
+type PipelineVariable struct {
+	Name          string
+	Value         string `json:"value"`
+	AllowOverride bool   `json:"allowOverride"`
+	IsSecret      bool   `json:"isSecret"`
+}
+
+// PipelineRunRequest holds the options used to queue a new pipeline run
+type PipelineRunRequest struct {
+	PipelineID         int
+	Branch             string
+	TemplateParameters map[string]string
+	Variables          map[string]string
+	StagesToSkip       []string
+}
+
+// GetPipelineDefinition retrieves a single pipeline definition
+func (c *Client) GetPipelineDefinition(pipelineID int) (*PipelineDefinition, error) {
+	output, err := runAzCommand("pipelines", "show", "--id", strconv.Itoa(pipelineID), "--query", jmespathPipelineDefinitionQuery, "--output", "json")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pipeline definition: %v", err)
+	}
+
+	var definition PipelineDefinition
+	if err := json.Unmarshal(output, &definition); err != nil {
+		return nil, fmt.Errorf("error parsing pipeline definition: %v", err)
+	}
+	return &definition, nil
+}
+
+// GetPipelineDefinitionYAML retrieves the YAML file of the pipeline definition from its repository
+// Only definitions stored in Azure Repos are supported
+func (c *Client) GetPipelineDefinitionYAML(definition *PipelineDefinition, branch string) (string, error) {
+	if definition.YAMLFilename == "" {
+		return "", fmt.Errorf("pipeline %s is not a YAML pipeline", definition.Name)
+	}
+	if definition.RepositoryType != "TfsGit" {
+		return "", fmt.Errorf("pipeline YAML is only available for Azure Repos, not %s", definition.RepositoryType)
+	}
+	if branch == "" {
+		branch = definition.DefaultBranch
+	}
+
+	output, err := c.invoke(invokeRequest{
+		Area:     "git",
+		Resource: "items",
+		RouteParameters: map[string]string{
+			"repositoryId": definition.RepositoryID,
+		},
+		QueryParameters: map[string]string{
+			"path":                          definition.YAMLFilename,
+			"includeContent":                "true",
+			"versionDescriptor.version":     strings.TrimPrefix(branch, "refs/heads/"),
+			"versionDescriptor.versionType": "branch",
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error fetching pipeline YAML: %v", err)
+	}
+
+	var item struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(output, &item); err != nil {
+		return "", fmt.Errorf("error parsing pipeline YAML: %v", err)
+	}
+	return item.Content, nil
+}
+
+// GetPipelineVariables retrieves the variables defined on the pipeline definition
+func (c *Client) GetPipelineVariables(pipelineID int) ([]PipelineVariable, error) {
+	output, err := runAzCommand("pipelines", "variable", "list", "--pipeline-id", strconv.Itoa(pipelineID), "--output", "json")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pipeline variables: %v", err)
+	}
+
+	var variablesByName map[string]PipelineVariable
+	if err := json.Unmarshal(output, &variablesByName); err != nil {
+		return nil, fmt.Errorf("error parsing pipeline variables: %v", err)
+	}
+	variables := make([]PipelineVariable, 0, len(variablesByName))
+	for name, variable := range variablesByName {
+		variable.Name = name
+		variables = append(variables, variable)
+	}
+	slices.SortFunc(variables, func(a, b PipelineVariable) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return variables, nil
+}
+
+// GetPipelineRun retrieves a single pipeline run
+func (c *Client) GetPipelineRun(runID int) (*PipelineRun, error) {
+	output, err := runAzCommand("pipelines", "runs", "show", "--id", strconv.Itoa(runID), "--query", jmespathPipelineRunQuery, "--output", "json")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pipeline run: %v", err)
+	}
+
+	var run PipelineRun
+	if err := json.Unmarshal(output, &run); err != nil {
+		return nil, fmt.Errorf("error parsing pipeline run: %v", err)
+	}
+	return &run, nil
+}
+
+// branchRef returns the full ref of the branch, e.g. refs/heads/main for main
+func branchRef(branch string) string {
+	if strings.HasPrefix(branch, "refs/") {
+		return branch
+	}
+	return "refs/heads/" + branch
+}
+
+// QueuePipelineRun queues a new run of the pipeline and returns it
+func (c *Client) QueuePipelineRun(request PipelineRunRequest) (*PipelineRun, error) {
+	body := map[string]interface{}{}
+	if request.Branch != "" {
+		body["resources"] = map[string]interface{}{
+			"repositories": map[string]interface{}{
+				"self": map[string]string{"refName": branchRef(request.Branch)},
+			},
+		}
+	}
+	if len(request.TemplateParameters) > 0 {
+		body["templateParameters"] = request.TemplateParameters
+	}
+	if len(request.Variables) > 0 {
+		variables := map[string]interface{}{}
+		for name, value := range request.Variables {
+			variables[name] = map[string]string{"value": value}
+		}
+		body["variables"] = variables
+	}
+	if len(request.StagesToSkip) > 0 {
+		body["stagesToSkip"] = request.StagesToSkip
+	}
+
+	output, err := c.invoke(invokeRequest{
+		Area:     "pipelines",
+		Resource: "runs",
+		RouteParameters: map[string]string{
+			"pipelineId": strconv.Itoa(request.PipelineID),
+		},
+		HTTPMethod: "POST",
+		Body:       body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error queueing pipeline run: %v", err)
+	}
+
+	var queued struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(output, &queued); err != nil {
+		return nil, fmt.Errorf("error parsing queued pipeline run: %v", err)
+	}
+	// The pipelines API returns a different shape than the builds API used elsewhere
+	return c.GetPipelineRun(queued.ID)
+}
+
+// Determines if the run is still queued or running
+func (r *PipelineRun) IsInProgress() bool {
+	return slices.Contains([]string{"inProgress", "notStarted", "postponed"}, r.Status)
+}
+
+// Determines if the run completed with failed or canceled jobs that can be retried
+func (r *PipelineRun) CanRetry() bool {
+	return r.Status == "completed" && slices.Contains([]string{"failed", "partiallySucceeded", "canceled"}, r.Result)
+}
+
+// CancelPipelineRun requests cancellation of an in-progress run
+func (c *Client) CancelPipelineRun(runID int) error {
+	_, err := c.invoke(invokeRequest{
+		Area:     "build",
+		Resource: "builds",
+		RouteParameters: map[string]string{
+			"buildId": strconv.Itoa(runID),
+		},
+		HTTPMethod: "PATCH",
+		Body:       map[string]string{"status": "cancelling"},
+	})
+	if err != nil {
+		return fmt.Errorf("error canceling pipeline run: %v", err)
+	}
+	return nil
+}
+
+// RetryPipelineRun retries the failed and canceled jobs of a completed run, keeping the same run
+func (c *Client) RetryPipelineRun(runID int) error {
+	_, err := c.invoke(invokeRequest{
+		Area:     "build",
+		Resource: "builds",
+		RouteParameters: map[string]string{
+			"buildId": strconv.Itoa(runID),
+		},
+		QueryParameters: map[string]string{
+			"retry": "true",
+		},
+		HTTPMethod: "PATCH",
+		Body:       map[string]string{},
+	})
+	if err != nil {
+		return fmt.Errorf("error retrying pipeline run: %v", err)
+	}
+	return nil
+}
+
+// RerunPipelineRun queues a new run of the same pipeline, branch and commit, with the template parameters
+// and the queue time variables of the run
+func (c *Client) RerunPipelineRun(run *PipelineRun) (*PipelineRun, error) {
+	output, err := c.invoke(invokeRequest{
+		Area:     "build",
+		Resource: "builds",
+		RouteParameters: map[string]string{
+			"buildId": strconv.Itoa(run.ID),
+		},
+		Query: "{templateParameters: templateParameters, parameters: parameters}",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the parameters of pipeline run: %v", err)
+	}
+	var original struct {
+		TemplateParameters map[string]interface{} `json:"templateParameters"`
+		// Queue time variables, as a JSON object in a string
+		Parameters string `json:"parameters"`
+	}
+	if err := json.Unmarshal(output, &original); err != nil {
+		return nil, fmt.Errorf("error parsing the parameters of pipeline run: %v", err)
+	}
+
+	body := map[string]interface{}{
+		"definition":    map[string]int{"id": run.DefinitionID},
+		"sourceBranch":  run.SourceBranch,
+		"sourceVersion": run.SourceVersion,
+	}
+	if len(original.TemplateParameters) > 0 {
+		body["templateParameters"] = original.TemplateParameters
+	}
+	if original.Parameters != "" {
+		body["parameters"] = original.Parameters
+	}
+	output, err = c.invoke(invokeRequest{
+		Area:       "build",
+		Resource:   "builds",
+		HTTPMethod: "POST",
+		Body:       body,
+		Query:      jmespathPipelineRunQuery,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error re-running pipeline run: %v", err)
+	}
+
+	var queued PipelineRun
+	if err := json.Unmarshal(output, &queued); err != nil {
+		return nil, fmt.Errorf("error parsing pipeline run: %v", err)
+	}
+	return &queued, nil
+}
+
+// TimelineRecord is a stage, job or task of a pipeline run
+type TimelineRecord struct {
+	ID           string          `json:"id"`
+	ParentID     string          `json:"parentId"`
+	Type         string          `json:"type"`
+	Name         string          `json:"name"`
+	Order        int             `json:"order"`
+	State        string          `json:"state"`
+	Result       string          `json:"result"`
+	StartTime    time.Time       `json:"startTime"`
+	FinishTime   time.Time       `json:"finishTime"`
+	WorkerName   string          `json:"workerName"`
+	ErrorCount   int             `json:"errorCount"`
+	WarningCount int             `json:"warningCount"`
+	Issues       []TimelineIssue `json:"issues"`
+	Log          *TimelineLog    `json:"log"`
+}
+
+// TimelineIssue is an error or warning reported by a timeline record
+type TimelineIssue struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// TimelineLog references the log of a timeline record
+type TimelineLog struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+}
+
+// PipelineLog is a log file produced by a pipeline run
+type PipelineLog struct {
+	ID            int       `json:"id"`
+	Type          string    `json:"type"`
+	URL           string    `json:"url"`
+	LineCount     int       `json:"lineCount"`
+	CreatedOn     time.Time `json:"createdOn"`
+	LastChangedOn time.Time `json:"lastChangedOn"`
+}
+
+// GetPipelineRunTimeline retrieves the stages, jobs and tasks of a run
+func (c *Client) GetPipelineRunTimeline(runID int) ([]TimelineRecord, error) {
+	output, err := c.invoke(invokeRequest{
+		Area:     "build",
+		Resource: "timeline",
+		RouteParameters: map[string]string{
+			"buildId": strconv.Itoa(runID),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pipeline run timeline: %v", err)
+	}
+
+	var timeline struct {
+		Records []TimelineRecord `json:"records"`
+	}
+	if err := json.Unmarshal(output, &timeline); err != nil {
+		return nil, fmt.Errorf("error parsing pipeline run timeline: %v", err)
+	}
+	return timeline.Records, nil
+}
+
+// GetPipelineRunLogs retrieves the list of logs of a run
+func (c *Client) GetPipelineRunLogs(runID int) ([]PipelineLog, error) {
+	output, err := c.invoke(invokeRequest{
+		Area:     "build",
+		Resource: "logs",
+		RouteParameters: map[string]string{
+			"buildId": strconv.Itoa(runID),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pipeline run logs: %v", err)
+	}
+
+	var logs struct {
+		Value []PipelineLog `json:"value"`
+	}
+	if err := json.Unmarshal(output, &logs); err != nil {
+		return nil, fmt.Errorf("error parsing pipeline run logs: %v", err)
+	}
+	slices.SortFunc(logs.Value, func(a, b PipelineLog) int {
+		return a.ID - b.ID
+	})
+	return logs.Value, nil
+}
+
+// GetPipelineRunLogLines retrieves the lines of a log starting at the given line (1-based)
+func (c *Client) GetPipelineRunLogLines(runID int, logID int, startLine int) ([]string, error) {
+	req := invokeRequest{
+		Area:     "build",
+		Resource: "logs",
+		RouteParameters: map[string]string{
+			"buildId": strconv.Itoa(runID),
+			"logId":   strconv.Itoa(logID),
+		},
+	}
+	if startLine > 1 {
+		req.QueryParameters = map[string]string{"startLine": strconv.Itoa(startLine)}
+	}
+	output, err := c.invoke(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pipeline run log: %v", err)
+	}
+
+	var lines struct {
+		Value []string `json:"value"`
+	}
+	if err := json.Unmarshal(output, &lines); err != nil {
+		return nil, fmt.Errorf("error parsing pipeline run log: %v", err)
+	}
+	return lines.Value, nil
+}
+
+// PipelineLogNames maps each log ID to the path of the timeline record that produced it,
+// e.g. "Build / Compile / Run tests". Phases are skipped as they repeat the job name.
+func PipelineLogNames(records []TimelineRecord) map[int]string {
+	byID := make(map[string]TimelineRecord, len(records))
+	for _, record := range records {
+		byID[record.ID] = record
+	}
+
+	names := map[int]string{}
+	for _, record := range records {
+		if record.Log == nil {
+			continue
+		}
+		path := []string{record.Name}
+		for parent, ok := byID[record.ParentID]; ok; parent, ok = byID[parent.ParentID] {
+			if parent.Type != "Phase" {
+				path = append([]string{parent.Name}, path...)
+			}
+		}
+		names[record.Log.ID] = strings.Join(path, " / ")
+	}
+	return names
+}
+
+// Get how long the record ran, or has been running if it is not finished yet
+func (r TimelineRecord) Duration() time.Duration {
+	if r.StartTime.IsZero() {
+		return 0
+	}
+	if r.FinishTime.IsZero() {
+		return time.Since(r.StartTime).Truncate(time.Second)
+	}
+	return r.FinishTime.Sub(r.StartTime).Truncate(time.Second)
+}
+
+// TimelineNode is a timeline record with its children, ordered as they run
+type TimelineNode struct {
+	Record   TimelineRecord
+	Children []*TimelineNode
+}
+
+// BuildTimelineTree arranges the records of a run as stages, jobs and tasks.
+// Phases are left out and their jobs attached to the stage, as shown in the web portal.
+func BuildTimelineTree(records []TimelineRecord) []*TimelineNode {
+	byID := make(map[string]TimelineRecord, len(records))
+	for _, record := range records {
+		byID[record.ID] = record
+	}
+	parentOf := func(record TimelineRecord) string {
+		parentID := record.ParentID
+		for parent, ok := byID[parentID]; ok && parent.Type == "Phase"; parent, ok = byID[parentID] {
+			parentID = parent.ParentID
+		}
+		return parentID
+	}
+
+	nodes := map[string]*TimelineNode{}
+	for _, record := range records {
+		if record.Type != "Phase" {
+			nodes[record.ID] = &TimelineNode{Record: record}
+		}
+	}
+	var roots []*TimelineNode
+	for _, record := range records {
+		node, ok := nodes[record.ID]
+		if !ok {
+			continue
+		}
+		if parent, ok := nodes[parentOf(record)]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	var sortNodes func(nodes []*TimelineNode)
+	sortNodes = func(nodes []*TimelineNode) {
+		slices.SortStableFunc(nodes, func(a, b *TimelineNode) int {
+			return a.Record.Order - b.Record.Order
+		})
+		for _, node := range nodes {
+			sortNodes(node.Children)
+		}
+	}
+	sortNodes(roots)
+	return roots
+}
+
+// Resource ID of Azure DevOps, used to get a token for `az rest`
+const azureDevOpsResourceID = "499b84ac-1321-427f-aa17-267ca6975798"
+
+// PipelineArtifact is an artifact published by a pipeline run
+type PipelineArtifact struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Data        string `json:"data"`
+	DownloadURL string `json:"downloadUrl"`
+	Size        int64  `json:"size"`
+}
+
+// Determines if the artifact is stored in a file container, whose files can be listed
+func (a *PipelineArtifact) IsContainer() bool {
+	return a.Type == "Container"
+}
+
+// Get the file container ID and the path of the artifact within it.
+// Container artifacts reference their location as "#/<container id>/<path>".
+func (a *PipelineArtifact) GetContainerLocation() (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(a.Data, "#/"), "/", 2)
+	if !a.IsContainer() || len(parts) != 2 || parts[0] == "" {
+		return "", "", fmt.Errorf("artifact %s is not stored in a file container", a.Name)
+	}
+	return parts[0], parts[1], nil
+}
+
+// ArtifactItem is a file or folder within a container artifact
+type ArtifactItem struct {
+	Path            string `json:"path"`
+	ItemType        string `json:"itemType"`
+	FileLength      int64  `json:"fileLength"`
+	ContentLocation string `json:"contentLocation"`
+}
+
+// Determines if the item is a file, as opposed to a folder
+func (i *ArtifactItem) IsFile() bool {
+	return i.ItemType == "file"
+}
+
+// GetArtifacts retrieves the artifacts published by the run
+func (r *PipelineRun) GetArtifacts(c *Client) ([]PipelineArtifact, error) {
+	if r.IsArtifactsFetched {
+		return r.Artifacts, nil
+	}
+	output, err := runAzCommand("pipelines", "runs", "artifact", "list", "--run-id", strconv.Itoa(r.ID), "--query", jmespathPipelineArtifactsQuery, "--output", "json")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pipeline artifacts: %v", err)
+	}
+
+	var artifacts []PipelineArtifact
+	if err := json.Unmarshal(output, &artifacts); err != nil {
+		return nil, fmt.Errorf("error parsing pipeline artifacts: %v", err)
+	}
+	r.Artifacts = artifacts
+	r.IsArtifactsFetched = true
+	return artifacts, nil
+}
+
+// GetArtifactItems lists the files and folders of a container artifact
+func (c *Client) GetArtifactItems(artifact *PipelineArtifact, projectID string) ([]ArtifactItem, error) {
+	containerID, itemPath, err := artifact.GetContainerLocation()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.invoke(invokeRequest{
+		Area:     "Container",
+		Resource: "Items",
+		RouteParameters: map[string]string{
+			"containerId": containerID,
+		},
+		QueryParameters: map[string]string{
+			"itemPath": itemPath,
+			"scope":    projectID,
+		},
+		APIVersion: "7.1-preview.4",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching artifact files: %v", err)
+	}
+
+	var items struct {
+		Value []ArtifactItem `json:"value"`
+	}
+	if err := json.Unmarshal(output, &items); err != nil {
+		return nil, fmt.Errorf("error parsing artifact files: %v", err)
+	}
+	slices.SortFunc(items.Value, func(a, b ArtifactItem) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return items.Value, nil
+}
+
+// DownloadPipelineArtifact downloads the whole artifact into the directory
+func (c *Client) DownloadPipelineArtifact(runID int, artifactName string, directory string) error {
+	_, err := runAzCommand("pipelines", "runs", "artifact", "download", "--run-id", strconv.Itoa(runID), "--artifact-name", artifactName, "--path", directory)
+	if err != nil {
+		return fmt.Errorf("error downloading artifact %s: %v", artifactName, err)
+	}
+	return nil
+}
+
+// DownloadArtifactItem downloads a single file of a container artifact into the directory
+// Returns the path of the downloaded file
+func (c *Client) DownloadArtifactItem(item *ArtifactItem, directory string) (string, error) {
+	if !item.IsFile() {
+		return "", fmt.Errorf("%s is not a file", item.Path)
+	}
+	target := filepath.Join(directory, filepath.Base(item.Path))
+	_, err := runAzCommand("rest", "--method", "get", "--url", item.ContentLocation,
+		"--resource", azureDevOpsResourceID, "--headers", "Accept=application/octet-stream", "--output-file", target)
+	if err != nil {
+		return "", fmt.Errorf("error downloading %s: %v", item.Path, err)
+	}
+	return target, nil
+}
+
+// PendingCheckpoints returns the checks (approvals, business hours, exclusive locks, ...)
+// that are still holding back a stage of the run
+func PendingCheckpoints(records []TimelineRecord) []string {
+	var checks []string
+	for _, record := range records {
+		if !strings.HasPrefix(record.Type, "Checkpoint.") || record.State != "inProgress" {
+			continue
+		}
+		name := record.Name
+		if name == "" || name == record.Type {
+			name = strings.TrimPrefix(record.Type, "Checkpoint.")
+		}
+		if !slices.Contains(checks, name) {
+			checks = append(checks, name)
+		}
+	}
+	return checks
+}
+
+// Approval is a manual approval a run waits on before deploying to an environment
+type Approval struct {
+	ID                   string         `json:"id"`
+	Status               string         `json:"status"`
+	Instructions         string         `json:"instructions"`
+	MinRequiredApprovers int            `json:"minRequiredApprovers"`
+	CreatedOn            time.Time      `json:"createdOn"`
+	Steps                []ApprovalStep `json:"steps"`
+	Pipeline             struct {
+		ID    json.Number `json:"id"`
+		Name  string      `json:"name"`
+		Owner struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"owner"`
+	} `json:"pipeline"`
+	// What the signed in user may do, e.g. "view, update" when they may approve as a member of an approver group
+	Permissions string `json:"permissions"`
+}
+
+// ApprovalStep is the decision of a single approver
+type ApprovalStep struct {
+	AssignedApprover struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"displayName"`
+		UniqueName  string `json:"uniqueName"`
+		// Groups are containers, any of their members may approve
+		IsContainer bool `json:"isContainer"`
+	} `json:"assignedApprover"`
+	Status  string `json:"status"`
+	Comment string `json:"comment"`
+}
+
+// Get the ID of the run waiting on the approval
+func (a *Approval) GetRunID() int {
+	return a.Pipeline.Owner.ID
+}
+
+// Get the names of the approvers who have not decided yet
+func (a *Approval) GetPendingApprovers() []string {
+	var approvers []string
+	for _, step := range a.Steps {
+		if step.Status == "pending" || step.Status == "undefined" || step.Status == "" {
+			approvers = append(approvers, step.AssignedApprover.DisplayName)
+		}
+	}
+	return approvers
+}
+
+// Determines if the user (by display name or unique name) is one of the pending approvers, or may approve
+// as a member of a pending approver group, as told by the permissions of the approval
+func (a *Approval) IsAssignedTo(user *UserProfile) bool {
+	if user == nil {
+		return false
+	}
+	canUpdate := slices.Contains(strings.Split(strings.ReplaceAll(a.Permissions, " ", ""), ","), "update")
+	for _, step := range a.Steps {
+		if step.Status != "pending" && step.Status != "undefined" && step.Status != "" {
+			continue
+		}
+		approver := step.AssignedApprover
+		if (approver.IsContainer && canUpdate) ||
+			approver.DisplayName == user.DisplayName ||
+			strings.EqualFold(approver.UniqueName, user.Mail) ||
+			(user.Username != "" && strings.EqualFold(approver.UniqueName, user.Username)) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPendingApprovals retrieves the approvals of the project that are waiting for a decision
+func (c *Client) GetPendingApprovals() ([]Approval, error) {
+	output, err := c.invoke(invokeRequest{
+		Area:     "pipelines",
+		Resource: "approvals",
+		QueryParameters: map[string]string{
+			"state":   "pending",
+			"$expand": "steps,permissions",
+		},
+		APIVersion: "7.1-preview.1",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pending approvals: %v", err)
+	}
+
+	var approvals struct {
+		Value []Approval `json:"value"`
+	}
+	if err := json.Unmarshal(output, &approvals); err != nil {
+		return nil, fmt.Errorf("error parsing pending approvals: %v", err)
+	}
+	slices.SortFunc(approvals.Value, func(a, b Approval) int {
+		return a.CreatedOn.Compare(b.CreatedOn)
+	})
+	return approvals.Value, nil
+}
+
+// UpdateApproval approves or rejects the approval with a comment
+func (c *Client) UpdateApproval(approvalID string, approve bool, comment string) error {
+	status := "rejected"
+	if approve {
+		status = "approved"
+	}
+	_, err := c.invoke(invokeRequest{
+		Area:       "pipelines",
+		Resource:   "approvals",
+		HTTPMethod: "PATCH",
+		Body: []map[string]string{{
+			"approvalId": approvalID,
+			"status":     status,
+			"comment":    comment,
+		}},
+		APIVersion: "7.1-preview.1",
+	})
+	if err != nil {
+		return fmt.Errorf("error updating approval %s: %v", approvalID, err)
+	}
+	return nil
+}
+
+// TestRun is a run of tests published by a pipeline run
+type TestRun struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	State              string `json:"state"`
+	TotalTests         int    `json:"totalTests"`
+	PassedTests        int    `json:"passedTests"`
+	UnanalyzedTests    int    `json:"unanalyzedTests"`
+	NotApplicableTests int    `json:"notApplicableTests"`
+	RunStatistics      []struct {
+		Outcome string `json:"outcome"`
+		Count   int    `json:"count"`
+	} `json:"runStatistics"`
+}
+
+// Get the number of passed, failed and skipped tests of the run.
+// Falls back to the run totals when the statistics per outcome are missing.
+func (r *TestRun) GetCounts() (passed int, failed int, skipped int) {
+	if len(r.RunStatistics) == 0 {
+		return r.PassedTests, r.UnanalyzedTests, r.NotApplicableTests
+	}
+	for _, statistic := range r.RunStatistics {
+		switch statistic.Outcome {
+		case "Passed":
+			passed += statistic.Count
+		case "Failed", "Aborted", "Timeout", "Error":
+			failed += statistic.Count
+		default:
+			skipped += statistic.Count
+		}
+	}
+	return passed, failed, skipped
+}
+
+// TestResult is the outcome of a single test
+type TestResult struct {
+	ID                int     `json:"id"`
+	TestCaseTitle     string  `json:"testCaseTitle"`
+	AutomatedTestName string  `json:"automatedTestName"`
+	TestStorage       string  `json:"automatedTestStorage"`
+	Outcome           string  `json:"outcome"`
+	ErrorMessage      string  `json:"errorMessage"`
+	StackTrace        string  `json:"stackTrace"`
+	DurationInMs      float64 `json:"durationInMs"`
+	CustomFields      []struct {
+		FieldName string `json:"fieldName"`
+		Value     any    `json:"value"`
+	} `json:"customFields"`
+}
+
+// Determines if Azure DevOps flaky test detection marked the result as flaky
+func (r *TestResult) IsFlaky() bool {
+	for _, field := range r.CustomFields {
+		if field.FieldName == "IsTestResultFlaky" {
+			return fmt.Sprint(field.Value) == "true"
+		}
+	}
+	return false
+}
+
+// Get the duration of the test
+func (r *TestResult) Duration() time.Duration {
+	return time.Duration(r.DurationInMs * float64(time.Millisecond))
+}
+
+// GetTestRuns retrieves the test runs published by the pipeline run
+func (c *Client) GetTestRuns(runID int) ([]TestRun, error) {
+	output, err := c.invoke(invokeRequest{
+		Area:     "test",
+		Resource: "runs",
+		QueryParameters: map[string]string{
+			"buildUri":          fmt.Sprintf("vstfs:///Build/Build/%d", runID),
+			"includeRunDetails": "true",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching test runs: %v", err)
+	}
+
+	var testRuns struct {
+		Value []TestRun `json:"value"`
+	}
+	if err := json.Unmarshal(output, &testRuns); err != nil {
+		return nil, fmt.Errorf("error parsing test runs: %v", err)
+	}
+	return testRuns.Value, nil
+}
+
+// GetFailedTestResults retrieves the failed tests of a test run, including their error and stack trace
+func (c *Client) GetFailedTestResults(testRunID int) ([]TestResult, error) {
+	output, err := c.invoke(invokeRequest{
+		Area:     "test",
+		Resource: "results",
+		RouteParameters: map[string]string{
+			"runId": strconv.Itoa(testRunID),
+		},
+		QueryParameters: map[string]string{
+			"outcomes": "Failed,Aborted,Timeout,Error",
+			"$top":     "1000",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching test results: %v", err)
+	}
+
+	var results struct {
+		Value []TestResult `json:"value"`
+	}
+	if err := json.Unmarshal(output, &results); err != nil {
+		return nil, fmt.Errorf("error parsing test results: %v", err)
+	}
+	return results.Value, nil
+}
+
+// GetPipelineDefinitionsWithLatestRun retrieves the pipeline definitions with their latest run
+func (c *Client) GetPipelineDefinitionsWithLatestRun() ([]Pipeline, error) {
+	output, err := c.invoke(invokeRequest{
+		Area:     "build",
+		Resource: "definitions",
+		QueryParameters: map[string]string{
+			"includeLatestBuilds": "true",
+		},
+		Query: jmespathPipelineDefinitionsWithLatestRunQuery,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pipeline definitions: %v", err)
+	}
+
+	var pipelines []Pipeline
+	if err := json.Unmarshal(output, &pipelines); err != nil {
+		return nil, fmt.Errorf("error parsing pipelines: %v", err)
+	}
+	return pipelines, nil
+}
+
+// SetPipelineQueueStatus pauses, disables or enables the queueing of new runs of the pipeline.
+// The definition has to be updated as a whole, so it is read first then written back.
+func (c *Client) SetPipelineQueueStatus(pipelineID int, status string) error {
+	routeParameters := map[string]string{
+		"definitionId": strconv.Itoa(pipelineID),
+	}
+	output, err := c.invoke(invokeRequest{
+		Area:            "build",
+		Resource:        "definitions",
+		RouteParameters: routeParameters,
+	})
+	if err != nil {
+		return fmt.Errorf("error fetching pipeline definition: %v", err)
+	}
+
+	var definition map[string]any
+	if err := json.Unmarshal(output, &definition); err != nil {
+		return fmt.Errorf("error parsing pipeline definition: %v", err)
+	}
+	definition["queueStatus"] = status
+
+	_, err = c.invoke(invokeRequest{
+		Area:            "build",
+		Resource:        "definitions",
+		RouteParameters: routeParameters,
+		HTTPMethod:      "PUT",
+		Body:            definition,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating pipeline queue status: %v", err)
+	}
+	return nil
+}
+
+// PipelineFolder is a folder of pipeline definitions, as organized by their path
+type PipelineFolder struct {
+	Name      string
+	Path      string
+	Folders   []*PipelineFolder
+	Pipelines []Pipeline
+}
+
+// BuildPipelineFolderTree organizes the pipelines by the folders of their path, like \Team\Service.
+// Folders and pipelines are sorted by name.
+func BuildPipelineFolderTree(pipelines []Pipeline) *PipelineFolder {
+	root := &PipelineFolder{Name: "\\", Path: "\\"}
+	for _, pipeline := range pipelines {
+		folder := root
+		for _, name := range strings.Split(strings.Trim(pipeline.Path, "\\"), "\\") {
+			if name == "" {
+				continue
+			}
+			index := slices.IndexFunc(folder.Folders, func(f *PipelineFolder) bool { return f.Name == name })
+			if index < 0 {
+				folder.Folders = append(folder.Folders, &PipelineFolder{
+					Name: name,
+					Path: strings.TrimSuffix(folder.Path, "\\") + "\\" + name,
+				})
+				index = len(folder.Folders) - 1
+			}
+			folder = folder.Folders[index]
+		}
+		folder.Pipelines = append(folder.Pipelines, pipeline)
+	}
+
+	var sortFolder func(folder *PipelineFolder)
+	sortFolder = func(folder *PipelineFolder) {
+		slices.SortFunc(folder.Folders, func(a, b *PipelineFolder) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+		slices.SortFunc(folder.Pipelines, func(a, b Pipeline) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+		for _, child := range folder.Folders {
+			sortFolder(child)
+		}
+	}
+	sortFolder(root)
+	return root
+}
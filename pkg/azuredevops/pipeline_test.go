@@ -0,0 +1,399 @@
+package azuredevops
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParsePipelineYAML(t *testing.T) {
+	content := `
+parameters:
+  - name: environment
+    displayName: Target environment
+    type: string
+    default: staging
+    values:
+      - staging
+      - prod
+  - name: runTests
+    type: boolean
+    default: true
+  - name: retries
+    type: number
+    default: 3
+    values: [1, 3, 5]
+  - name: extraSteps
+    type: stepList
+    default: []
+
+stages:
+  - stage: Build
+  - stage: Test
+  - template: deploy.yml
+`
+	parsed, err := ParsePipelineYAML(content)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(parsed.Parameters) != 4 {
+		t.Fatalf("Expected 4 parameters, got %d", len(parsed.Parameters))
+	}
+	environment := parsed.Parameters[0]
+	if environment.Label() != "Target environment" || environment.DefaultString() != "staging" || len(environment.Values) != 2 {
+		t.Errorf("Unexpected environment parameter: %+v", environment)
+	}
+	if parsed.Parameters[1].DefaultString() != "true" {
+		t.Errorf("Expected runTests default 'true', got '%s'", parsed.Parameters[1].DefaultString())
+	}
+	if len(parsed.Parameters[2].Values) != 3 || parsed.Parameters[2].Values[1] != "3" {
+		t.Errorf("Expected numeric values to be read as strings, got %v", parsed.Parameters[2].Values)
+	}
+	if parsed.Parameters[3].IsQueueable() {
+		t.Error("Expected stepList parameter to not be queueable")
+	}
+
+	if len(parsed.Stages) != 2 || parsed.Stages[0] != "Build" || parsed.Stages[1] != "Test" {
+		t.Errorf("Expected stages [Build Test], got %v", parsed.Stages)
+	}
+}
+
+func TestParsePipelineYAML_LegacyParameters(t *testing.T) {
+	parsed, err := ParsePipelineYAML("parameters:\n  debug: false\n  configuration: Release\n")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(parsed.Parameters) != 2 || parsed.Parameters[0].Name != "configuration" || parsed.Parameters[0].DefaultString() != "Release" {
+		t.Errorf("Unexpected legacy parameters: %+v", parsed.Parameters)
+	}
+}
+
+func TestPipelineLogNames(t *testing.T) {
+	records := []TimelineRecord{
+		{ID: "stage", Type: "Stage", Name: "Build"},
+		{ID: "phase", ParentID: "stage", Type: "Phase", Name: "Compile"},
+		{ID: "job", ParentID: "phase", Type: "Job", Name: "Compile", Log: &TimelineLog{ID: 3}},
+		{ID: "task", ParentID: "job", Type: "Task", Name: "Run tests", Log: &TimelineLog{ID: 7}},
+		{ID: "checkpoint", ParentID: "stage", Type: "Checkpoint", Name: "Checkpoint"},
+	}
+
+	names := PipelineLogNames(records)
+
+	if len(names) != 2 {
+		t.Fatalf("Expected 2 log names, got %d", len(names))
+	}
+	if names[3] != "Build / Compile" {
+		t.Errorf("Expected job log name 'Build / Compile', got '%s'", names[3])
+	}
+	if names[7] != "Build / Compile / Run tests" {
+		t.Errorf("Expected task log name 'Build / Compile / Run tests', got '%s'", names[7])
+	}
+}
+
+func TestBuildTimelineTree(t *testing.T) {
+	records := []TimelineRecord{
+		{ID: "task2", ParentID: "job", Type: "Task", Name: "Test", Order: 2},
+		{ID: "deploy", Type: "Stage", Name: "Deploy", Order: 2},
+		{ID: "build", Type: "Stage", Name: "Build", Order: 1},
+		{ID: "phase", ParentID: "build", Type: "Phase", Name: "Compile", Order: 1},
+		{ID: "job", ParentID: "phase", Type: "Job", Name: "Compile", Order: 1},
+		{ID: "task1", ParentID: "job", Type: "Task", Name: "Checkout", Order: 1},
+	}
+
+	roots := BuildTimelineTree(records)
+
+	if len(roots) != 2 || roots[0].Record.Name != "Build" || roots[1].Record.Name != "Deploy" {
+		t.Fatalf("Expected stages [Build Deploy], got %+v", roots)
+	}
+	if len(roots[0].Children) != 1 || roots[0].Children[0].Record.Type != "Job" {
+		t.Fatalf("Expected the job to be attached to the stage, got %+v", roots[0].Children)
+	}
+	tasks := roots[0].Children[0].Children
+	if len(tasks) != 2 || tasks[0].Record.Name != "Checkout" || tasks[1].Record.Name != "Test" {
+		t.Errorf("Expected tasks [Checkout Test], got %+v", tasks)
+	}
+}
+
+func TestPipelineArtifact_GetContainerLocation(t *testing.T) {
+	artifact := PipelineArtifact{Name: "drop", Type: "Container", Data: "#/12345/drop/reports"}
+	containerID, itemPath, err := artifact.GetContainerLocation()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if containerID != "12345" || itemPath != "drop/reports" {
+		t.Errorf("Expected container 12345 and path drop/reports, got %s and %s", containerID, itemPath)
+	}
+
+	pipelineArtifact := PipelineArtifact{Name: "drop", Type: "PipelineArtifact", Data: "ABCDEF"}
+	if _, _, err := pipelineArtifact.GetContainerLocation(); err == nil {
+		t.Error("Expected error for a pipeline artifact, got nil")
+	}
+}
+
+func TestPendingCheckpoints(t *testing.T) {
+	records := []TimelineRecord{
+		{ID: "stage", Type: "Stage", Name: "Deploy", State: "pending"},
+		{ID: "checkpoint", ParentID: "stage", Type: "Checkpoint", Name: "Checkpoint", State: "inProgress"},
+		{ID: "approval", ParentID: "checkpoint", Type: "Checkpoint.Approval", Name: "Checkpoint.Approval", State: "inProgress"},
+		{ID: "hours", ParentID: "checkpoint", Type: "Checkpoint.TaskCheck", Name: "Business hours", State: "inProgress"},
+		{ID: "lock", ParentID: "checkpoint", Type: "Checkpoint.ExclusiveLock", Name: "Exclusive lock", State: "completed"},
+	}
+
+	checks := PendingCheckpoints(records)
+
+	if len(checks) != 2 || checks[0] != "Approval" || checks[1] != "Business hours" {
+		t.Errorf("Expected checks [Approval Business hours], got %v", checks)
+	}
+}
+
+func TestApproval_IsAssignedTo(t *testing.T) {
+	var approval Approval
+	err := json.Unmarshal([]byte(`{
+		"id": "abc",
+		"status": "pending",
+		"steps": [
+			{"assignedApprover": {"displayName": "Jane Doe", "uniqueName": "jane@example.com"}, "status": "pending"},
+			{"assignedApprover": {"displayName": "John Doe", "uniqueName": "john@example.com"}, "status": "approved"}
+		],
+		"pipeline": {"id": "12", "name": "deploy", "owner": {"id": 345, "name": "20250101.1"}}
+	}`), &approval)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if approval.GetRunID() != 345 {
+		t.Errorf("Expected run ID 345, got %d", approval.GetRunID())
+	}
+	if !approval.IsAssignedTo(&UserProfile{DisplayName: "Someone", Mail: "JANE@example.com"}) {
+		t.Error("Expected approval to be assigned to jane@example.com")
+	}
+	if approval.IsAssignedTo(&UserProfile{DisplayName: "John Doe", Mail: "john@example.com"}) {
+		t.Error("Expected approval to not be assigned to John who already approved")
+	}
+	if approvers := approval.GetPendingApprovers(); len(approvers) != 1 || approvers[0] != "Jane Doe" {
+		t.Errorf("Expected pending approvers [Jane Doe], got %v", approvers)
+	}
+
+	// Approvals assigned to a group are the user's when Azure DevOps lets them approve
+	var groupApproval Approval
+	err = json.Unmarshal([]byte(`{
+		"id": "def",
+		"status": "pending",
+		"permissions": "view, update",
+		"steps": [
+			{"assignedApprover": {"displayName": "[project]\\Release Approvers", "isContainer": true}, "status": "pending"}
+		]
+	}`), &groupApproval)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	user := &UserProfile{DisplayName: "John Doe", Mail: "john@example.com"}
+	if !groupApproval.IsAssignedTo(user) {
+		t.Error("Expected the group approval to be assigned to a member of the group")
+	}
+	groupApproval.Permissions = "view"
+	if groupApproval.IsAssignedTo(user) {
+		t.Error("Expected the group approval to not be assigned to a user who may not approve")
+	}
+}
+
+func TestTestRun_GetCounts(t *testing.T) {
+	var testRun TestRun
+	err := json.Unmarshal([]byte(`{
+		"id": 1,
+		"totalTests": 12,
+		"passedTests": 8,
+		"runStatistics": [
+			{"state": "Completed", "outcome": "Passed", "count": 8},
+			{"state": "Completed", "outcome": "Failed", "count": 3},
+			{"state": "Completed", "outcome": "NotExecuted", "count": 1}
+		]
+	}`), &testRun)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	passed, failed, skipped := testRun.GetCounts()
+	if passed != 8 || failed != 3 || skipped != 1 {
+		t.Errorf("Expected 8 passed, 3 failed and 1 skipped, got %d, %d and %d", passed, failed, skipped)
+	}
+}
+
+func TestTestResult_IsFlaky(t *testing.T) {
+	var results []TestResult
+	err := json.Unmarshal([]byte(`[
+		{"id": 1, "outcome": "Failed", "customFields": [{"fieldName": "IsTestResultFlaky", "value": true}]},
+		{"id": 2, "outcome": "Failed", "customFields": [{"fieldName": "IsTestResultFlaky", "value": "false"}]},
+		{"id": 3, "outcome": "Failed"}
+	]`), &results)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !results[0].IsFlaky() || results[1].IsFlaky() || results[2].IsFlaky() {
+		t.Errorf("Expected only the first result to be flaky, got %v, %v, %v", results[0].IsFlaky(), results[1].IsFlaky(), results[2].IsFlaky())
+	}
+}
+
+func TestBuildPipelineFolderTree(t *testing.T) {
+	pipelines := []Pipeline{
+		{ID: 1, Name: "web", Path: "\\Team\\Frontend"},
+		{ID: 2, Name: "api", Path: "\\Team"},
+		{ID: 3, Name: "infra", Path: "\\"},
+		{ID: 4, Name: "admin", Path: "\\Team\\Frontend"},
+	}
+
+	root := BuildPipelineFolderTree(pipelines)
+
+	if len(root.Pipelines) != 1 || root.Pipelines[0].Name != "infra" {
+		t.Errorf("Expected [infra] at the root, got %+v", root.Pipelines)
+	}
+	if len(root.Folders) != 1 || root.Folders[0].Name != "Team" {
+		t.Fatalf("Expected a single Team folder, got %+v", root.Folders)
+	}
+	team := root.Folders[0]
+	if len(team.Pipelines) != 1 || team.Pipelines[0].Name != "api" {
+		t.Errorf("Expected [api] in Team, got %+v", team.Pipelines)
+	}
+	if len(team.Folders) != 1 || team.Folders[0].Path != "\\Team\\Frontend" {
+		t.Fatalf("Expected a Frontend folder in Team, got %+v", team.Folders)
+	}
+	frontend := team.Folders[0].Pipelines
+	if len(frontend) != 2 || frontend[0].Name != "admin" || frontend[1].Name != "web" {
+		t.Errorf("Expected [admin web] in Frontend, got %+v", frontend)
+	}
+}
+
+func TestClient_GetPipelineRunsFiltered(t *testing.T) {
+	origExecCommand := execCommand
+	defer func() { execCommand = origExecCommand }()
+
+	var calledArgs []string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		calledArgs = args
+		return exec.Command("echo", "[]")
+	}
+
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	_, err := client.GetPipelineRunsFiltered(PipelineRunsFilter{
+		PipelineID:   7,
+		Branch:       "refs/heads/main",
+		Result:       "failed",
+		RequestedFor: "jane@example.com",
+		Top:          100,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	args := strings.Join(calledArgs, " ")
+	for _, expected := range []string{"--top 100", "--pipeline-ids 7", "--branch refs/heads/main", "--result failed", "--requested-for jane@example.com"} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in the arguments, got %s", expected, args)
+		}
+	}
+	if slices.Contains(calledArgs, "--reason") || slices.Contains(calledArgs, "--status") {
+		t.Errorf("Expected empty filters to be left out, got %s", args)
+	}
+
+	if _, err := client.GetPipelineRunsFiltered(PipelineRunsFilter{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(strings.Join(calledArgs, " "), "--top 40") {
+		t.Errorf("Expected the default top of 40, got %v", calledArgs)
+	}
+
+	if _, err := client.GetPipelineRunsFiltered(PipelineRunsFilter{Reason: "nope"}); err == nil {
+		t.Error("Expected an error for an invalid reason, got nil")
+	}
+}
+
+// mockInvokes records the arguments and the body of the az devops invoke calls, answering them in turn
+func mockInvokes(t *testing.T, outputs ...string) (calls *[]string, bodies *[]map[string]interface{}) {
+	origExecCommand := execCommand
+	t.Cleanup(func() { execCommand = origExecCommand })
+	calls, bodies = &[]string{}, &[]map[string]interface{}{}
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		var body map[string]interface{}
+		if i := slices.Index(args, "--in-file"); i >= 0 {
+			content, _ := os.ReadFile(args[i+1])
+			json.Unmarshal(content, &body)
+		}
+		output := "{}"
+		if len(*calls) < len(outputs) {
+			output = outputs[len(*calls)]
+		}
+		*calls = append(*calls, strings.Join(args, " "))
+		*bodies = append(*bodies, body)
+		return exec.Command("echo", output)
+	}
+	return calls, bodies
+}
+
+func TestClient_CancelPipelineRun(t *testing.T) {
+	calls, bodies := mockInvokes(t)
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	if err := client.CancelPipelineRun(42); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, expected := range []string{"--area build --resource builds", "--http-method PATCH", "buildId=42", "project=testproject"} {
+		if !strings.Contains((*calls)[0], expected) {
+			t.Errorf("Expected %q in the arguments, got %s", expected, (*calls)[0])
+		}
+	}
+	if (*bodies)[0]["status"] != "cancelling" {
+		t.Errorf("Expected the run to be cancelled, got %+v", (*bodies)[0])
+	}
+}
+
+func TestClient_RetryPipelineRun(t *testing.T) {
+	calls, bodies := mockInvokes(t)
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	if err := client.RetryPipelineRun(42); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, expected := range []string{"--area build --resource builds", "--http-method PATCH", "buildId=42", "--query-parameters retry=true"} {
+		if !strings.Contains((*calls)[0], expected) {
+			t.Errorf("Expected %q in the arguments, got %s", expected, (*calls)[0])
+		}
+	}
+	if body := (*bodies)[0]; body == nil || len(body) != 0 {
+		t.Errorf("Expected an empty body, got %+v", body)
+	}
+}
+
+func TestClient_RerunPipelineRun(t *testing.T) {
+	calls, bodies := mockInvokes(t,
+		`{"templateParameters": {"environment": "prod"}, "parameters": "{\"verbose\":\"true\"}"}`,
+		`{"id": 43, "definitionId": 7, "sourceBranch": "refs/heads/main"}`,
+	)
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	run := &PipelineRun{ID: 42, DefinitionID: 7, SourceBranch: "refs/heads/main", SourceVersion: "abc123"}
+	queued, err := client.RerunPipelineRun(run)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if queued.ID != 43 {
+		t.Errorf("Expected the queued run, got %+v", queued)
+	}
+	if len(*calls) != 2 || !strings.Contains((*calls)[0], "buildId=42") || strings.Contains((*calls)[0], "--http-method") {
+		t.Fatalf("Expected the parameters of the run to be fetched first, got %v", *calls)
+	}
+	if !strings.Contains((*calls)[1], "--http-method POST") || strings.Contains((*calls)[1], "buildId") {
+		t.Errorf("Expected a new build to be queued, got %s", (*calls)[1])
+	}
+	body := (*bodies)[1]
+	definition, _ := body["definition"].(map[string]interface{})
+	templateParameters, _ := body["templateParameters"].(map[string]interface{})
+	if definition["id"] != float64(7) || body["sourceBranch"] != "refs/heads/main" || body["sourceVersion"] != "abc123" {
+		t.Errorf("Expected the same pipeline, branch and commit, got %+v", body)
+	}
+	if templateParameters["environment"] != "prod" || body["parameters"] != `{"verbose":"true"}` {
+		t.Errorf("Expected the parameters and variables of the run, got %+v", body)
+	}
+}
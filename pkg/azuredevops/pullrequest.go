@@ -1,7 +1,10 @@
 package azuredevops
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -105,7 +108,10 @@ func (pr *PullRequestDetails) GetVotesInfo() []VoteInfo {
 
 // Retrieve more details from the Pull Request itself
 func (pr *PullRequestDetails) GetMorePRDetails() (*PullRequestDetails, error) {
-	_shallowPR, _ := _fetchPRDetails(strconv.Itoa(pr.ID))
+	_shallowPR, err := _fetchPRDetails(strconv.Itoa(pr.ID))
+	if err != nil {
+		return nil, err
+	}
 
 	pr.IsDetailFetched = true
 	pr.Description = _shallowPR.Description
@@ -113,3 +119,136 @@ func (pr *PullRequestDetails) GetMorePRDetails() (*PullRequestDetails, error) {
 	pr.WorkItemRefs = _shallowPR.WorkItemRefs
 	return pr, nil
 }
+
+// Get the ref of the merge commit Azure DevOps maintains for the PR
+func (pr *PullRequestDetails) GetMergeRefName() string {
+	return fmt.Sprintf("refs/pull/%d/merge", pr.ID)
+}
+
+// RepositoryIdentity reduces an Azure Repos web or git remote URL to "organization/project/repository".
+// HTTPS (dev.azure.com and the legacy visualstudio.com hosts) and SSH remotes are supported.
+// Returns an empty string if the URL is not recognized as an Azure Repos URL.
+func RepositoryIdentity(remoteURL string) string {
+	u := strings.TrimSpace(remoteURL)
+	u = strings.TrimSuffix(u, "/")
+	u = strings.TrimSuffix(u, ".git")
+	if unescaped, err := url.PathUnescape(u); err == nil {
+		u = unescaped
+	}
+
+	var organization string
+	var segments []string
+	switch {
+	case strings.Contains(u, ":v3/"):
+		// git@ssh.dev.azure.com:v3/org/project/repo
+		// org@vs-ssh.visualstudio.com:v3/org/project/repo
+		segments = strings.Split(strings.SplitN(u, ":v3/", 2)[1], "/")
+		if len(segments) != 3 {
+			return ""
+		}
+		return strings.ToLower(strings.Join(segments, "/"))
+	case strings.Contains(u, "dev.azure.com/"):
+		// https://[org@]dev.azure.com/org/project/_git/repo
+		segments = strings.Split(strings.SplitN(u, "dev.azure.com/", 2)[1], "/")
+		organization = segments[0]
+		segments = segments[1:]
+	case strings.Contains(u, ".visualstudio.com/"):
+		// https://org.visualstudio.com/[DefaultCollection/]project/_git/repo
+		host, path, _ := strings.Cut(u, ".visualstudio.com/")
+		host = host[strings.LastIndexAny(host, "/@")+1:]
+		organization = host
+		segments = strings.Split(path, "/")
+		if len(segments) > 0 && strings.EqualFold(segments[0], "DefaultCollection") {
+			segments = segments[1:]
+		}
+	default:
+		return ""
+	}
+
+	// Remaining segments are either project/_git/repo or _git/repo when the repository is named after the project
+	gitIdx := slices.Index(segments, "_git")
+	if organization == "" || gitIdx < 0 || gitIdx+1 >= len(segments) {
+		return ""
+	}
+	repository := segments[gitIdx+1]
+	project := repository
+	if gitIdx > 0 {
+		project = segments[gitIdx-1]
+	}
+	return strings.ToLower(organization + "/" + project + "/" + repository)
+}
+
+// PullRequestThread is a comment thread of a pull request
+type PullRequestThread struct {
+	ID        int  `json:"id"`
+	IsDeleted bool `json:"isDeleted"`
+	Comments  []struct {
+		ID          int    `json:"id"`
+		CommentType string `json:"commentType"`
+		IsDeleted   bool   `json:"isDeleted"`
+		Author      struct {
+			DisplayName string `json:"displayName"`
+		} `json:"author"`
+	} `json:"comments"`
+}
+
+// CountPullRequestComments counts the comments written by people in the threads,
+// leaving out the system comments such as votes and pushes
+func CountPullRequestComments(threads []PullRequestThread) int {
+	count := 0
+	for _, thread := range threads {
+		if thread.IsDeleted {
+			continue
+		}
+		for _, comment := range thread.Comments {
+			if comment.CommentType == "text" && !comment.IsDeleted {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// GetPullRequestThreads retrieves the comment threads of the pull request
+func (c *Client) GetPullRequestThreads(pr *PullRequestDetails) ([]PullRequestThread, error) {
+	output, err := c.invoke(invokeRequest{
+		Area:     "git",
+		Resource: "pullRequestThreads",
+		RouteParameters: map[string]string{
+			"repositoryId":  pr.Repository,
+			"pullRequestId": strconv.Itoa(pr.ID),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching threads of PR %d: %v", pr.ID, err)
+	}
+
+	var threads struct {
+		Value []PullRequestThread `json:"value"`
+	}
+	if err := json.Unmarshal(output, &threads); err != nil {
+		return nil, fmt.Errorf("error parsing threads of PR %d: %v", pr.ID, err)
+	}
+	return threads.Value, nil
+}
+
+// AddPullRequestComment starts a thread on the pull request with the comment
+func (c *Client) AddPullRequestComment(pr *PullRequestDetails, text string) error {
+	_, err := c.invoke(invokeRequest{
+		Area:       "git",
+		Resource:   "pullRequestThreads",
+		HTTPMethod: "POST",
+		RouteParameters: map[string]string{
+			"repositoryId":  pr.Repository,
+			"pullRequestId": strconv.Itoa(pr.ID),
+		},
+		Body: map[string]interface{}{
+			"comments": []map[string]interface{}{{"parentCommentId": 0, "content": text, "commentType": 1}},
+			"status":   1,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error commenting on PR %d: %v", pr.ID, err)
+	}
+	return nil
+}
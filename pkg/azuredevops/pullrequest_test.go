@@ -0,0 +1,90 @@
+package azuredevops
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRepositoryIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{"web URL", "https://dev.azure.com/MyOrg/My%20Project/_git/my-repo", "myorg/my project/my-repo"},
+		{"https remote with user", "https://myorg@dev.azure.com/myorg/project/_git/repo", "myorg/project/repo"},
+		{"ssh remote", "git@ssh.dev.azure.com:v3/myorg/project/repo", "myorg/project/repo"},
+		{"legacy ssh remote", "myorg@vs-ssh.visualstudio.com:v3/myorg/project/repo", "myorg/project/repo"},
+		{"legacy host", "https://myorg.visualstudio.com/DefaultCollection/project/_git/repo.git", "myorg/project/repo"},
+		{"repository named after project", "https://dev.azure.com/myorg/_git/project", "myorg/project/project"},
+		{"not azure", "git@github.com:aldnav/lazyaz.git", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RepositoryIdentity(tt.url); got != tt.expected {
+				t.Errorf("RepositoryIdentity(%q) = %q, expected %q", tt.url, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCountPullRequestComments(t *testing.T) {
+	var threads []PullRequestThread
+	err := json.Unmarshal([]byte(`[
+		{"id": 1, "comments": [
+			{"id": 1, "commentType": "text", "author": {"displayName": "Jane"}},
+			{"id": 2, "commentType": "text", "author": {"displayName": "John"}},
+			{"id": 3, "commentType": "text", "isDeleted": true}
+		]},
+		{"id": 2, "comments": [{"id": 1, "commentType": "system"}]},
+		{"id": 3, "isDeleted": true, "comments": [{"id": 1, "commentType": "text"}]}
+	]`), &threads)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := CountPullRequestComments(threads); got != 2 {
+		t.Errorf("CountPullRequestComments() = %d, expected 2", got)
+	}
+	if got := CountPullRequestComments(nil); got != 0 {
+		t.Errorf("CountPullRequestComments(nil) = %d, expected 0", got)
+	}
+}
+
+func TestClient_AddPullRequestComment(t *testing.T) {
+	origExecCommand := execCommand
+	defer func() { execCommand = origExecCommand }()
+
+	var args []string
+	var body map[string]interface{}
+	execCommand = func(command string, cmdArgs ...string) *exec.Cmd {
+		args = cmdArgs
+		for i, arg := range cmdArgs {
+			if arg == "--in-file" {
+				content, _ := os.ReadFile(cmdArgs[i+1])
+				json.Unmarshal(content, &body)
+			}
+		}
+		return exec.Command("echo", "{}")
+	}
+
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	pr := &PullRequestDetails{ID: 12, Repository: "repo"}
+	if err := client.AddPullRequestComment(pr, "Looks good"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	joined := strings.Join(args, " ")
+	for _, expected := range []string{"--resource pullRequestThreads", "--http-method POST", "pullRequestId=12", "repositoryId=repo"} {
+		if !strings.Contains(joined, expected) {
+			t.Errorf("Expected %q in the arguments, got %s", expected, joined)
+		}
+	}
+	comments, _ := body["comments"].([]interface{})
+	if len(comments) != 1 || comments[0].(map[string]interface{})["content"] != "Looks good" {
+		t.Errorf("Unexpected body %+v", body)
+	}
+}
@@ -2,12 +2,15 @@ package azuredevops
 
 const (
 	// Work Items
-	workItemQueryMeSincePastMonth        = `SELECT * FROM workitems WHERE [System.AssignedTo] = @me AND [System.CreatedDate] >= @Today - 90 ORDER BY [System.CreatedDate] DESC`
-	workItemQueryWasEverMeSincePastMonth = `SELECT * FROM workitems WHERE EVER [System.AssignedTo] = @me AND [System.CreatedDate] >= @Today - 90 ORDER BY [System.CreatedDate] DESC`
-	workItemsQueryAll                    = `SELECT * FROM workitems WHERE [System.CreatedDate] >= @Today - 90 ORDER BY [System.CreatedDate] DESC`
+	workItemQueryMeSincePastMonth = `SELECT * FROM workitems WHERE [System.AssignedTo] = @me AND [System.CreatedDate] >= @Today - 90 ORDER BY [System.CreatedDate] DESC`
+
+	// Pages of work items, %s being the condition on the creation date
+	workItemPageQueryMe        = `SELECT * FROM workitems WHERE [System.AssignedTo] = @me AND %s ORDER BY [System.CreatedDate] DESC`
+	workItemPageQueryWasEverMe = `SELECT * FROM workitems WHERE EVER [System.AssignedTo] = @me AND %s ORDER BY [System.CreatedDate] DESC`
+	workItemsPageQueryAll      = `SELECT * FROM workitems WHERE %s ORDER BY [System.CreatedDate] DESC`
 )
 
-const jmespathWorkItemQuery = `[].{` +
+const jmespathWorkItemFieldsQuery = `{` +
 	`Id: fields."System.Id", ` +
 	`"Work Item Type": fields."System.WorkItemType", ` +
 	`"Title": fields."System.Title", ` +
@@ -22,7 +25,8 @@ const jmespathWorkItemQuery = `[].{` +
 	`"ChangedBy": fields."System.ChangedBy".displayName, ` +
 	`"Description": fields."System.Description"` +
 	`}`
-const jmespathWorkItemDetailsQuery = `{` +
+const jmespathWorkItemQuery = `[].` + jmespathWorkItemFieldsQuery
+const jmespathWorkItemDetailsFields = `` +
 	`"Repro Steps": fields."Microsoft.VSTS.TCM.ReproSteps", ` +
 	`"System.AreaPath": fields."System.AreaPath", ` +
 	`"Acceptance Criteria": fields."Microsoft.VSTS.Common.AcceptanceCriteria", ` +
@@ -33,8 +37,11 @@ const jmespathWorkItemDetailsQuery = `{` +
 	`"PR refs": relations[?attributes.name=='Pull Request'].url, ` +
 	`"Priority": fields."Microsoft.VSTS.Common.Priority", ` +
 	`"Severity": fields."Microsoft.VSTS.Common.Severity", ` +
-	`"Attachments": relations[?rel=='AttachedFile']` +
-	`}`
+	`"Attachments": relations[?rel=='AttachedFile']`
+const jmespathWorkItemDetailsQuery = `{` + jmespathWorkItemDetailsFields + `}`
+
+// Details of the work items of a batch, along with their ID
+const jmespathWorkItemsBatchDetailsQuery = `value[].{"Id": id, ` + jmespathWorkItemDetailsFields + `}`
 const jmespathPRDetailsQuery = `{` +
 	`"Title": title, ` +
 	`"Status": status, ` +
@@ -62,7 +69,11 @@ const jmespathPRDetailsQuery = `{` +
 const jmespathPRListsQuery = `[].` + jmespathPRDetailsQuery
 const jmespathUserProfileQuery = `{"id": id, "displayName": displayName, "mail": mail, "givenName": givenName, "surname": surname}`
 const jmespathPipelineDefinitionsQuery = `[].{id:id, name:name, path:path, status:queueStatus, defaultQueue:queue.name, project:project.name, author:authoredBy.displayName, authorUniqueName:authoredBy.uniqueName, pipelineType:type}`
-const jmespathPipelineRunsQuery = `[].{id:id, buildNumber:buildNumber, definitionId: definition.id, definitionName: definition.name, definitionPath: definition.path, finishTime: finishTime, keepForever:keepForever, priority:priority, queue:queue.name, queueTime:queueTime, reason:reason, repositoryId:repository.id, repositoryName:repository.name,repositoryType:repository.type, requestedBy:requestedBy.displayName, requestedByUniqueName:requestedBy.uniqueName, requestedFor:requestedFor.displayName, requestedForUniqueName:requestedFor.uniqueName, result:result, sourceBranch:sourceBranch, sourceVersion:sourceVersion, startTime:startTime, status:status, logsUrl:logs.url, logsType:logs.type, retainedByRelease: retainedByRelease, deleted:deleted, deletedByd:deletedBy, deletedDate:deletedDate, deletedReason:deletedReason, projectId:project.id, projectUrl:project.url }`
+const jmespathPipelineRunQuery = `{id:id, buildNumber:buildNumber, definitionId: definition.id, definitionName: definition.name, definitionPath: definition.path, finishTime: finishTime, keepForever:keepForever, priority:priority, queue:queue.name, queueTime:queueTime, reason:reason, repositoryId:repository.id, repositoryName:repository.name,repositoryType:repository.type, requestedBy:requestedBy.displayName, requestedByUniqueName:requestedBy.uniqueName, requestedFor:requestedFor.displayName, requestedForUniqueName:requestedFor.uniqueName, result:result, sourceBranch:sourceBranch, sourceVersion:sourceVersion, startTime:startTime, status:status, logsUrl:logs.url, logsType:logs.type, retainedByRelease: retainedByRelease, deleted:deleted, deletedByd:deletedBy, deletedDate:deletedDate, deletedReason:deletedReason, projectId:project.id, projectUrl:project.url }`
+const jmespathPipelineRunsQuery = `[].` + jmespathPipelineRunQuery
+const jmespathPipelineArtifactsQuery = `[].{id:id, name:name, type:resource.type, data:resource.data, downloadUrl:resource.downloadUrl, size:to_number(resource.properties.artifactsize)}`
+const jmespathPipelineDefinitionsWithLatestRunQuery = `value[].{id:id, name:name, path:path, status:queueStatus, defaultQueue:queue.name, project:project.name, author:authoredBy.displayName, authorUniqueName:authoredBy.uniqueName, pipelineType:type, latestRunId:latestBuild.id, latestRunBuildNumber:latestBuild.buildNumber, latestRunStatus:latestBuild.status, latestRunResult:latestBuild.result, latestRunFinishTime:latestBuild.finishTime}`
+const jmespathPipelineDefinitionQuery = `{id:id, name:name, path:path, status:queueStatus, defaultQueue:queue.name, project:project.name, author:authoredBy.displayName, authorUniqueName:authoredBy.uniqueName, pipelineType:type, defaultBranch:repository.defaultBranch, repositoryId:repository.id, repositoryName:repository.name, repositoryType:repository.type, yamlFilename:process.yamlFilename}`
 
 // References:
 // - https://learn.microsoft.com/en-us/azure/devops/boards/queries/query-operators-variables?view=azure-devops
@@ -0,0 +1,101 @@
+package azuredevops
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Kinds of the items referenced by ID or URL
+const (
+	ItemWorkItem    = "workitem"
+	ItemPullRequest = "pullrequest"
+	ItemPipelineRun = "pipelinerun"
+)
+
+// ItemRef references a work item, PR or pipeline run
+type ItemRef struct {
+	Kind string
+	ID   int
+}
+
+// Names accepted for the kinds of items, e.g. in `lazyaz show wi 1234`
+var itemKindNames = map[string][]string{
+	ItemWorkItem:    {"wi", "workitem", "workitems", "work-item"},
+	ItemPullRequest: {"pr", "prs", "pullrequest", "pull-request"},
+	ItemPipelineRun: {"run", "runs", "build", "pipelinerun", "pipeline-run"},
+}
+
+// ParseItemKind parses the name of a kind of item, such as "wi", "pr" or "run"
+func ParseItemKind(name string) (string, error) {
+	for kind, names := range itemKindNames {
+		if slices.Contains(names, strings.ToLower(name)) {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("unknown kind of item %q, expected wi, pr or run", name)
+}
+
+// ParseItemURL parses the URL of a work item, PR or pipeline run as shown in the web portal, e.g.
+// https://dev.azure.com/org/project/_workitems/edit/1234,
+// https://dev.azure.com/org/project/_git/repo/pullrequest/567 or
+// https://dev.azure.com/org/project/_build/results?buildId=8910
+func ParseItemURL(rawURL string) (ItemRef, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return ItemRef{}, fmt.Errorf("invalid URL: %s", rawURL)
+	}
+	parseID := func(kind string, value string) (ItemRef, error) {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			return ItemRef{}, fmt.Errorf("invalid ID %q in URL: %s", value, rawURL)
+		}
+		return ItemRef{Kind: kind, ID: id}, nil
+	}
+
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	for i, segment := range segments {
+		if i+1 >= len(segments) {
+			break
+		}
+		switch {
+		case segment == "_workitems" && segments[i+1] == "edit" && i+2 < len(segments):
+			return parseID(ItemWorkItem, segments[i+2])
+		case segment == "pullrequest":
+			return parseID(ItemPullRequest, segments[i+1])
+		}
+	}
+	query := parsed.Query()
+	if slices.Contains(segments, "_build") && query.Has("buildId") {
+		return parseID(ItemPipelineRun, query.Get("buildId"))
+	}
+	// Work items opened from boards and backlogs
+	if query.Has("workitem") {
+		return parseID(ItemWorkItem, query.Get("workitem"))
+	}
+	return ItemRef{}, fmt.Errorf("not a work item, pull request or pipeline run URL: %s", rawURL)
+}
+
+// ParseItemRef parses the URL of an item, or the ID of an item of the kind when not a URL
+func ParseItemRef(kind string, value string) (ItemRef, error) {
+	if strings.Contains(value, "://") {
+		ref, err := ParseItemURL(value)
+		if err != nil {
+			return ItemRef{}, err
+		}
+		if kind != "" && ref.Kind != kind {
+			return ItemRef{}, fmt.Errorf("the URL is of a %s, not a %s", ref.Kind, kind)
+		}
+		return ref, nil
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(value, "#"))
+	if err != nil || id <= 0 {
+		return ItemRef{}, fmt.Errorf("invalid ID: %s", value)
+	}
+	if kind == "" {
+		return ItemRef{}, fmt.Errorf("the kind of item of %s is needed, e.g. wi %s", value, value)
+	}
+	return ItemRef{Kind: kind, ID: id}, nil
+}
@@ -0,0 +1,51 @@
+package azuredevops
+
+import "testing"
+
+func TestParseItemURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected ItemRef
+	}{
+		{"https://dev.azure.com/org/project/_workitems/edit/1234", ItemRef{ItemWorkItem, 1234}},
+		{"https://org.visualstudio.com/project/_workitems/edit/1234/", ItemRef{ItemWorkItem, 1234}},
+		{"https://dev.azure.com/org/project/_boards/board/t/Team/Stories?workitem=42", ItemRef{ItemWorkItem, 42}},
+		{"https://dev.azure.com/org/project/_git/repo/pullrequest/567?_a=files", ItemRef{ItemPullRequest, 567}},
+		{"https://dev.azure.com/org/project/_build/results?buildId=8910&view=logs", ItemRef{ItemPipelineRun, 8910}},
+	}
+	for _, test := range tests {
+		ref, err := ParseItemURL(test.url)
+		if err != nil {
+			t.Errorf("Expected no error for %s, got %v", test.url, err)
+			continue
+		}
+		if ref != test.expected {
+			t.Errorf("Expected %+v for %s, got %+v", test.expected, test.url, ref)
+		}
+	}
+
+	for _, url := range []string{
+		"https://dev.azure.com/org/project/_git/repo",
+		"https://dev.azure.com/org/project/_workitems/edit/abc",
+		"not a url",
+	} {
+		if _, err := ParseItemURL(url); err == nil {
+			t.Errorf("Expected an error for %s, got nil", url)
+		}
+	}
+}
+
+func TestParseItemRef(t *testing.T) {
+	if ref, err := ParseItemRef(ItemPullRequest, "#567"); err != nil || ref != (ItemRef{ItemPullRequest, 567}) {
+		t.Errorf("Unexpected reference %+v, %v", ref, err)
+	}
+	if _, err := ParseItemRef("", "567"); err == nil {
+		t.Error("Expected an error for an ID without a kind, got nil")
+	}
+	if _, err := ParseItemRef(ItemWorkItem, "https://dev.azure.com/org/project/_git/repo/pullrequest/567"); err == nil {
+		t.Error("Expected an error for a URL of another kind, got nil")
+	}
+	if kind, err := ParseItemKind("WI"); err != nil || kind != ItemWorkItem {
+		t.Errorf("Expected a work item kind, got %q, %v", kind, err)
+	}
+}
@@ -3,6 +3,7 @@ package azuredevops
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -54,10 +55,99 @@ type WorkItemDetails struct {
 	Attachments        []Attachment `json:"Attachments"`
 }
 
+// GetWorkItem retrieves a single work item by ID
+func (c *Client) GetWorkItem(id int) (*WorkItem, error) {
+	output, err := requests.get(CacheWorkItem, strconv.Itoa(id), func() ([]byte, error) {
+		return runAzCommand("boards", "work-item", "show", "--id", strconv.Itoa(id), "--query", jmespathWorkItemFieldsQuery, "--output", "json")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching work item %d: %v", id, err)
+	}
+
+	var workItem WorkItem
+	if err := json.Unmarshal(output, &workItem); err != nil {
+		return nil, fmt.Errorf("error parsing work item %d: %v", id, err)
+	}
+	return &workItem, nil
+}
+
+// UpdateWorkItem sets the fields of the work item, e.g. {"System.State": "Active"}, and returns the updated work item
+func (c *Client) UpdateWorkItem(id int, fields map[string]string) (*WorkItem, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields to update on work item %d", id)
+	}
+	cmdParams := []string{"boards", "work-item", "update", "--id", strconv.Itoa(id), "--query", jmespathWorkItemFieldsQuery, "--output", "json", "--fields"}
+	cmdParams = append(cmdParams, sortedKeyValues(fields)...)
+	output, err := runAzCommand(cmdParams...)
+	if err != nil {
+		return nil, fmt.Errorf("error updating work item %d: %v", id, err)
+	}
+	c.Invalidate(CacheWorkItem, id)
+	c.Invalidate(CacheWorkItemDetails, id)
+
+	var workItem WorkItem
+	if err := json.Unmarshal(output, &workItem); err != nil {
+		return nil, fmt.Errorf("error parsing work item %d: %v", id, err)
+	}
+	return &workItem, nil
+}
+
+// AddWorkItemComment adds the comment to the discussion of the work item
+func (c *Client) AddWorkItemComment(id int, text string) error {
+	if _, err := runAzCommand("boards", "work-item", "update", "--id", strconv.Itoa(id), "--discussion", text, "--output", "none"); err != nil {
+		return fmt.Errorf("error commenting on work item %d: %v", id, err)
+	}
+	c.Invalidate(CacheWorkItemDetails, id)
+	return nil
+}
+
+// Number of work items a batch request takes at most
+const workItemsBatchSize = 200
+
+// GetWorkItemsDetails retrieves the details of the work items in batches, keyed by ID.
+// The details are kept so GetMoreWorkItemDetails does not fetch them again.
+// The batch API does not take a list of fields along with the relations, so the fields are selected from the response.
+func (c *Client) GetWorkItemsDetails(ids []int) (map[int]*WorkItemDetails, error) {
+	details := make(map[int]*WorkItemDetails, len(ids))
+	for batch := range slices.Chunk(ids, workItemsBatchSize) {
+		output, err := c.invoke(invokeRequest{
+			Area:       "wit",
+			Resource:   "workitemsbatch",
+			HTTPMethod: "POST",
+			Body: map[string]interface{}{
+				"ids":         batch,
+				"$expand":     "relations",
+				"errorPolicy": "omit",
+			},
+			Query: jmespathWorkItemsBatchDetailsQuery,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching work items details: %v", err)
+		}
+
+		var items []struct {
+			ID int `json:"Id"`
+			WorkItemDetails
+		}
+		if err := json.Unmarshal(output, &items); err != nil {
+			return nil, fmt.Errorf("error parsing work items details: %v", err)
+		}
+		for _, item := range items {
+			if cached, err := json.Marshal(item.WorkItemDetails); err == nil {
+				requests.set(CacheWorkItemDetails, strconv.Itoa(item.ID), cached)
+			}
+			details[item.ID] = &item.WorkItemDetails
+		}
+	}
+	return details, nil
+}
+
 // GetMoreWorkItemDetails retrieves the details of a specific work item
 // Given a WorkItem, it will use the ID to fetch more details
 func (wit *WorkItem) GetMoreWorkItemDetails() (*WorkItem, error) {
-	output, err := runAzCommand("boards", "work-item", "show", "--id", strconv.Itoa(wit.ID), "--query", jmespathWorkItemDetailsQuery, "--output", "json")
+	output, err := requests.get(CacheWorkItemDetails, strconv.Itoa(wit.ID), func() ([]byte, error) {
+		return runAzCommand("boards", "work-item", "show", "--id", strconv.Itoa(wit.ID), "--query", jmespathWorkItemDetailsQuery, "--output", "json")
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error fetching work item details: %v", err)
 	}
@@ -92,13 +182,9 @@ func (wit *WorkItem) GetPRDetails(c *Client) ([]PullRequestDetails, error) {
 	if len(wit.PRDetails) > 0 {
 		return wit.PRDetails, nil
 	}
-	prs := []PullRequestDetails{}
-	for _, prRef := range wit.GetPRs() {
-		pr, err := c.GetPRDetails(prRef)
-		if err != nil {
-			return nil, fmt.Errorf("error fetching PR details: %v", err)
-		}
-		prs = append(prs, *pr)
+	prs, err := c.GetPRsDetails(wit.GetPRs())
+	if err != nil {
+		return nil, err
 	}
 	wit.PRDetails = prs
 	return prs, nil
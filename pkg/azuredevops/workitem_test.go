@@ -0,0 +1,139 @@
+package azuredevops
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestClient_GetWorkItemsDetails(t *testing.T) {
+	origExecCommand := execCommand
+	origRequests := requests
+	defer func() {
+		execCommand = origExecCommand
+		requests = origRequests
+	}()
+	requests = newRequestCache()
+
+	var calls [][]string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		calls = append(calls, args)
+		return exec.Command("echo", `[
+			{"Id": 1, "Priority": 2, "Board Column": "Doing", "PR refs": ["vstfs:///Git/PullRequestId/p%2Fr%2F7"]},
+			{"Id": 2, "Priority": 1, "Severity": "2 - High"}
+		]`)
+	}
+
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	details, err := client.GetWorkItemsDetails([]int{1, 2})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("Expected a single batch request, got %d", len(calls))
+	}
+	args := strings.Join(calls[0], " ")
+	for _, expected := range []string{"--resource workitemsbatch", "--http-method POST", "--in-file"} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in the arguments, got %s", expected, args)
+		}
+	}
+	if len(details) != 2 || details[1].BoardColumn != "Doing" || details[2].Severity != "2 - High" {
+		t.Errorf("Unexpected details %+v", details)
+	}
+
+	// The details of the batch are reused
+	workItem := WorkItem{ID: 1}
+	if _, err := workItem.GetMoreWorkItemDetails(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("Expected the batched details to be reused, got %d requests", len(calls))
+	}
+	if workItem.Details == nil || workItem.Details.Priority != 2 || len(workItem.Details.PRRefs) != 1 {
+		t.Errorf("Unexpected details %+v", workItem.Details)
+	}
+}
+
+func TestClient_GetPRsDetails(t *testing.T) {
+	origExecCommand := execCommand
+	origRequests := requests
+	defer func() {
+		execCommand = origExecCommand
+		requests = origRequests
+	}()
+	requests = newRequestCache()
+
+	var mu sync.Mutex
+	var fetched []string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		id := args[slices.Index(args, "--id")+1]
+		mu.Lock()
+		fetched = append(fetched, id)
+		mu.Unlock()
+		if id == "13" {
+			return exec.Command("false")
+		}
+		return exec.Command("echo", fmt.Sprintf(`{"ID": %s, "Title": "PR %s"}`, id, id))
+	}
+
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	ids := []string{"5", "3", "8", "1", "9", "2"}
+	prs, err := client.GetPRsDetails(ids)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for i, pr := range prs {
+		if fmt.Sprint(pr.ID) != ids[i] {
+			t.Errorf("Expected PR %s at %d, got %d", ids[i], i, pr.ID)
+		}
+	}
+	if len(fetched) != len(ids) {
+		t.Errorf("Expected %d requests, got %d", len(ids), len(fetched))
+	}
+
+	if _, err := client.GetPRsDetails([]string{"5", "13"}); err == nil {
+		t.Error("Expected an error when a PR cannot be fetched, got nil")
+	}
+	if prs, err := client.GetPRsDetails(nil); err != nil || len(prs) != 0 {
+		t.Errorf("Expected no PRs and no error, got %v and %v", prs, err)
+	}
+}
+
+func TestClient_UpdateWorkItem(t *testing.T) {
+	origExecCommand := execCommand
+	defer func() { execCommand = origExecCommand }()
+
+	var calls [][]string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		calls = append(calls, args)
+		return exec.Command("echo", `{"Id": 7, "State": "Active", "Title": "Fix the build"}`)
+	}
+
+	client := NewClient(&Config{Organization: "testorg", Project: "testproject"})
+	workItem, err := client.UpdateWorkItem(7, map[string]string{"System.Title": "Fix the build", "System.State": "Active"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if workItem.ID != 7 || workItem.State != "Active" {
+		t.Errorf("Unexpected work item %+v", workItem)
+	}
+	args := strings.Join(calls[0], " ")
+	if !strings.Contains(args, "work-item update --org testorg --id 7") || !strings.HasSuffix(args, "--fields System.State=Active System.Title=Fix the build") {
+		t.Errorf("Unexpected arguments %s", args)
+	}
+
+	if _, err := client.UpdateWorkItem(7, nil); err == nil {
+		t.Error("Expected an error without fields")
+	}
+
+	if err := client.AddWorkItemComment(7, "Done"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if args := strings.Join(calls[len(calls)-1], " "); !strings.Contains(args, "--id 7 --discussion Done") {
+		t.Errorf("Unexpected arguments %s", args)
+	}
+}